@@ -0,0 +1,172 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+// bank adds puzzles to a puzzle bank or lists the puzzles of a bank that match a query.
+func bank(args []string) error {
+	var (
+		input      inputs
+		file       string
+		add        bool
+		levels     string
+		clues      string
+		strategies inputs
+		format     string
+		q          generator.BankQuery
+	)
+
+	fs := newFlagSet("bank", "[puzzle ...]", "Add puzzles to a puzzle bank with -add, or list the puzzles in it that match -level, -clues, and -strategy. A bank holds one puzzle of each canonical form: puzzles that differ only by transposition, by permutations of bands, stacks, rows within a band, or columns within a stack, or by relabelling the digits are stored once.")
+	fs.StringVar(&file, "f", "sudoku.bank", "bank `file`, which -add creates if it does not exist")
+	fs.BoolVar(&add, "add", false, "solve and add the input puzzles to the bank, skipping duplicates and puzzles without a single solution")
+	fs.Var(&input, "i", inputUsage)
+	fs.StringVar(&levels, "level", "", "list puzzles at any of these comma-separated `levels`")
+	fs.StringVar(&clues, "clues", "", "list puzzles with a clue count in `range`, such as 24, 22-25, 22-, or -25")
+	fs.Var(&strategies, "strategy", "list puzzles that need `strategy` (may be repeated to require several)")
+	fs.IntVar(&q.Limit, "n", 0, "list at most `count` puzzles; 0 lists them all")
+	fs.StringVar(&format, "format", "", "list one machine-readable record per puzzle in `format` (json, jsonl, or csv)")
+	fs.Parse(args)
+
+	if add {
+		b, err := generator.OpenBank(file)
+		if err != nil {
+			return err
+		}
+		defer b.Close()
+
+		return bankAdd(b, input, fs.Args())
+	}
+
+	if fs.NArg() > 0 || len(input) > 0 {
+		fs.Usage()
+		return fmt.Errorf("puzzles are only read with -add")
+	}
+
+	b, err := generator.ReadBank(file)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	if levels != "" {
+		for _, s := range strings.Split(levels, ",") {
+			l, err := generator.ParseLevel(strings.TrimSpace(s))
+			if err != nil {
+				return err
+			}
+			q.Levels = append(q.Levels, l)
+		}
+	}
+	if q.MinClues, q.MaxClues, err = parseRange(clues); err != nil {
+		return err
+	}
+	q.Strategies = strategies
+
+	out, err := newRecordWriter(format, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range b.Query(q) {
+		if out != nil {
+			if err := out.write(gameRecord(g)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fmt.Printf("%s %s (%d) %s\n", g.Puzzle.Encode(), g.Level, g.Clues, strings.Join(g.Strategies, ", "))
+	}
+
+	if out != nil {
+		return out.close()
+	}
+
+	return nil
+}
+
+// bankAdd solves the input puzzles and adds them to b, reporting the puzzles that are skipped and a count of those added.
+func bankAdd(b *generator.Bank, input inputs, args []string) error {
+	added, duplicates := 0, 0
+	if err := eachPuzzle(input, args, func(line string) error {
+		g, err := solveGame(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s; skipping\n", line, err)
+			return nil
+		}
+
+		ok, err := b.Add(g)
+		if err != nil {
+			return err
+		}
+		if ok {
+			added++
+		} else {
+			duplicates++
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("added %d, skipped %d duplicates, %d in bank\n", added, duplicates, b.Len())
+	return nil
+}
+
+// parseRange parses an inclusive range of clue counts such as 24, 22-25, 22-, or -25; 0 stands for an open end.
+func parseRange(s string) (min, max uint, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+
+	lo, hi := s, s
+	if i := strings.Index(s, "-"); i >= 0 {
+		lo, hi = s[:i], s[i+1:]
+	}
+
+	parse := func(s string) (uint, error) {
+		if s == "" {
+			return 0, nil
+		}
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("bad clue range %q", s)
+		}
+		return uint(n), nil
+	}
+
+	if min, err = parse(lo); err != nil {
+		return
+	}
+	max, err = parse(hi)
+	return
+}
@@ -0,0 +1,214 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+// errStopped stops reading puzzles once a batch has failed.
+var errStopped = errors.New("batch stopped")
+
+type (
+	// outcome is the result of processing one puzzle in a batch: a record for the summary and any text destined for stdout and stderr.
+	outcome struct {
+		rec            *record
+		stdout, stderr bytes.Buffer
+	}
+
+	// summary tallies the outcomes of a batch by level and strategy.
+	summary struct {
+		all, solved, errors int
+		levels              map[generator.Level]int
+		unsolved            map[int]int // Counts of unsolved puzzles with 0, 1, and 2 or more solutions.
+		strategies          map[string]int
+	}
+
+	batchJob struct {
+		index int
+		line  string
+	}
+
+	batchResult struct {
+		index int
+		*outcome
+	}
+)
+
+// batchWindow is the number of puzzles per worker that may be read ahead of the oldest puzzle not yet emitted, which bounds the results held back to keep the output in order.
+const batchWindow = 4
+
+// batch calls work for every puzzle from eachPuzzle using the given number of goroutines and passes the outcomes to emit in input order. It stops reading puzzles after the first error from emit.
+func batch(files inputs, args []string, workers int, work func(line string) *outcome, emit func(o *outcome) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan batchJob, workers)
+	results := make(chan batchResult, workers)
+	window := make(chan struct{}, batchWindow*workers) // Holds a token for each puzzle read but not yet emitted.
+	done := make(chan struct{})
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+
+		index := 0
+		readErr = eachPuzzle(files, args, func(line string) error {
+			select {
+			case window <- struct{}{}:
+			case <-done:
+				return errStopped
+			}
+
+			jobs <- batchJob{index, line}
+			index++
+			return nil
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- batchResult{j.index, work(j.line)}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Results arrive in any order; hold them until all earlier ones have been emitted.
+	var err error
+	pending := make(map[int]*outcome)
+	next := 0
+	for r := range results {
+		pending[r.index] = r.outcome
+		for {
+			o, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-window
+
+			if err == nil {
+				if err = emit(o); err != nil {
+					close(done)
+				}
+			}
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return readErr
+}
+
+func newSummary() *summary {
+	return &summary{
+		levels:     make(map[generator.Level]int),
+		unsolved:   make(map[int]int),
+		strategies: make(map[string]int),
+	}
+}
+
+func (s *summary) add(r *record) {
+	s.all++
+	switch {
+	case r.Error != "":
+		s.errors++
+		return
+	case r.Solved:
+		s.solved++
+		s.levels[r.Level]++
+	case r.Solutions > 2:
+		s.unsolved[2]++
+	default:
+		s.unsolved[r.Solutions]++
+	}
+
+	for _, n := range r.Strategies {
+		s.strategies[n]++
+	}
+}
+
+func (s *summary) print(w io.Writer) {
+	fmt.Fprintf(w, "solved %d of %d\n", s.solved, s.all)
+
+	if len(s.levels) > 0 {
+		fmt.Fprintln(w, "levels:")
+		for l := generator.Easy; l <= generator.Extreme; l++ {
+			if n, ok := s.levels[l]; ok {
+				fmt.Fprintf(w, "\t%-20s %d\n", l, n)
+			}
+		}
+	}
+
+	if len(s.unsolved) > 0 || s.errors > 0 {
+		fmt.Fprintln(w, "not solved:")
+		for n, label := range []string{"no solution", "single solution", "multiple solutions"} {
+			if c, ok := s.unsolved[n]; ok {
+				fmt.Fprintf(w, "\t%-20s %d\n", label, c)
+			}
+		}
+		if s.errors > 0 {
+			fmt.Fprintf(w, "\t%-20s %d\n", "errors", s.errors)
+		}
+	}
+
+	if len(s.strategies) > 0 {
+		names := make([]string, 0, len(s.strategies))
+		for n := range s.strategies {
+			names = append(names, n)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if s.strategies[names[i]] != s.strategies[names[j]] {
+				return s.strategies[names[i]] > s.strategies[names[j]]
+			}
+			return names[i] < names[j]
+		})
+
+		fmt.Fprintln(w, "strategies:")
+		for _, n := range names {
+			fmt.Fprintf(w, "\t%-20s %d\n", n, s.strategies[n])
+		}
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package main
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchOrder(t *testing.T) {
+	var args []string
+	for i := 0; i < 50; i++ {
+		args = append(args, strconv.Itoa(i))
+	}
+
+	var got []string
+	err := batch(nil, args, 4, func(line string) *outcome {
+		n, _ := strconv.Atoi(line)
+		time.Sleep(time.Duration(n%7) * time.Millisecond)
+		o := &outcome{}
+		o.stdout.WriteString(line)
+		return o
+	}, func(o *outcome) error {
+		got = append(got, o.stdout.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, args, got)
+}
+
+func TestBatchStop(t *testing.T) {
+	args := make([]string, 1000)
+	for i := range args {
+		args[i] = strconv.Itoa(i)
+	}
+
+	var worked int32
+	errEmit := errors.New("emit failed")
+	err := batch(nil, args, 2, func(line string) *outcome {
+		atomic.AddInt32(&worked, 1)
+		return &outcome{}
+	}, func(o *outcome) error {
+		return errEmit
+	})
+	assert.Equal(t, errEmit, err)
+	assert.LessOrEqual(t, int(worked), 1+batchWindow*2+2) // The failed puzzle, the window, and the jobs already queued.
+}
@@ -0,0 +1,158 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+var conversions = []string{"base64", "dots", "grid", "json", "text", "values", "zeros"}
+
+// convert reads puzzles in any of the supported encodings and writes them in the encoding given by -to.
+func convert(args []string) error {
+	var (
+		input               inputs
+		to                  string
+		canonical, disguise bool
+	)
+
+	fs := newFlagSet("convert", "[puzzle ...]", "Convert puzzles between encodings. Input lines may be 81-character puzzles (using '.' or '0' for blanks), "+
+		"the text form of a grid (givens followed by ':' and candidates), a JSON grid or game, or a base64 binary grid.")
+	fs.Var(&input, "i", inputUsage)
+	fs.StringVar(&to, "to", "dots", "output `encoding`: "+strings.Join(conversions, ", "))
+	fs.BoolVar(&canonical, "canonical", false, "write the canonical form of each puzzle, which is the same for puzzles that are transformations of each other")
+	fs.BoolVar(&disguise, "disguise", false, "write a random transformation of each puzzle (rows, columns, and digits shuffled) that has the same difficulty")
+	generator.ColorFlag(fs)
+	fs.Parse(args)
+
+	found := false
+	for _, c := range conversions {
+		found = found || c == to
+	}
+	if !found {
+		return fmt.Errorf("unknown encoding %q (expected one of %s)", to, strings.Join(conversions, ", "))
+	}
+	if canonical && disguise {
+		return fmt.Errorf("-canonical and -disguise cannot be used together")
+	}
+
+	return eachPuzzle(input, fs.Args(), func(line string) error {
+		grid, err := parseAny(line)
+		if err != nil {
+			return fmt.Errorf("%s: %w", line, err)
+		}
+		switch {
+		case canonical:
+			t, err := grid.CanonicalTransform()
+			if err != nil {
+				return fmt.Errorf("%s: %w", line, err)
+			}
+			if grid, err = t.Apply(grid); err != nil {
+				return fmt.Errorf("%s: %w", line, err)
+			}
+		case disguise:
+			if grid, err = generator.RandomTransform(nil).Apply(grid); err != nil {
+				return fmt.Errorf("%s: %w", line, err)
+			}
+		}
+
+		var s string
+		switch to {
+		case "base64":
+			data, err := grid.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			s = base64.StdEncoding.EncodeToString(data)
+		case "dots":
+			s = strings.ReplaceAll(grid.Encode(), "0", ".")
+		case "grid":
+			grid.Display()
+			return nil
+		case "json":
+			data, err := json.Marshal(grid)
+			if err != nil {
+				return err
+			}
+			s = string(data)
+		case "text":
+			data, err := grid.MarshalText()
+			if err != nil {
+				return err
+			}
+			s = string(data)
+		case "values":
+			s = grid.Values()
+		case "zeros":
+			s = grid.Encode()
+		}
+
+		fmt.Println(s)
+		return nil
+	})
+}
+
+// parseAny decodes a puzzle from a JSON grid or game, the text form of a grid, or a base64 binary grid.
+func parseAny(line string) (*generator.Grid, error) {
+	if strings.HasPrefix(line, "{") {
+		var probe map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &probe); err != nil {
+			return nil, err
+		}
+
+		if _, ok := probe["puzzle"]; ok {
+			var g generator.Game
+			if err := json.Unmarshal([]byte(line), &g); err != nil {
+				return nil, err
+			}
+			return g.Puzzle, nil
+		}
+
+		var g generator.Grid
+		if err := json.Unmarshal([]byte(line), &g); err != nil {
+			return nil, err
+		}
+		return &g, nil
+	}
+
+	var g generator.Grid
+	err := g.UnmarshalText([]byte(line))
+	if err == nil {
+		return &g, nil
+	}
+
+	if data, berr := base64.StdEncoding.DecodeString(line); berr == nil {
+		if berr = g.UnmarshalBinary(data); berr == nil {
+			return &g, nil
+		}
+	}
+
+	return nil, err
+}
@@ -0,0 +1,197 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+// generate creates puzzles at the levels given by -0, -1, -2, and -3 using one worker per CPU.
+func generate(args []string) error {
+	var (
+		counts     [4]int
+		format     string
+		htmlOutput bool
+		layout     layoutFlags
+		files      htmlFlags
+		pdf        pdfFlags
+		bankFile   string
+	)
+
+	fs := newFlagSet("generate", "", "Generate puzzles at the requested levels.")
+	fs.IntVar(&counts[generator.Easy], "0", 0, "`count` of easy games to generate")
+	fs.IntVar(&counts[generator.Standard], "1", 0, "`count` of standard games to generate")
+	fs.IntVar(&counts[generator.Hard], "2", 0, "`count` of hard games to generate")
+	fs.IntVar(&counts[generator.Expert], "3", 0, "`count` of expert games to generate")
+	fs.StringVar(&format, "format", "", "emit one machine-readable record per puzzle in `format` (json, jsonl, or csv) instead of text")
+	fs.BoolVar(&htmlOutput, "html", false, "display HTML output on the default browser (see -o, -svg, -png and -jpeg to write files instead)")
+	fs.StringVar(&bankFile, "bank", "", "add the puzzles to the puzzle bank in `file` (see the bank command), skipping those it already holds")
+	files.register(fs)
+	pdf.register(fs)
+	layout.register(fs, true)
+	generator.AttemptsFlag(fs)
+	generator.ColorFlag(fs)
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if err := files.check(); err != nil {
+		return err
+	}
+
+	out, err := newRecordWriter(format, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if layout.size != 9 || layout.extra() {
+		if err := layoutOutput(&files, &pdf); err != nil {
+			return err
+		}
+		if bankFile != "" {
+			return errors.New("-bank supports only standard 9 x 9 puzzles")
+		}
+
+		games, err := generateVariants(&layout, counts, out)
+		if err != nil {
+			return err
+		}
+
+		return writeGames(games, htmlOutput, &files, &pdf, out)
+	}
+
+	var b *generator.Bank
+	if bankFile != "" {
+		if b, err = generator.OpenBank(bankFile); err != nil {
+			return err
+		}
+		defer b.Close()
+	}
+
+	numberOfWorkers := runtime.NumCPU()
+	numberOfTasks := 0
+	for _, c := range counts {
+		numberOfTasks += c
+	}
+
+	tasks := make(chan generator.Level, numberOfTasks)
+	results := make(chan *generator.Game, numberOfTasks)
+
+	for w := 0; w < numberOfWorkers; w++ {
+		go generator.Worker(tasks, results)
+	}
+
+	for l, c := range counts {
+		for t := 0; t < c; t++ {
+			tasks <- generator.Level(l)
+		}
+	}
+
+	close(tasks)
+
+	games := make([]*generator.Game, 0, numberOfTasks)
+	duplicates := 0
+
+	for t := 0; t < numberOfTasks; t++ {
+		g := <-results
+		if g == nil {
+			failed()
+			continue
+		}
+
+		games = append(games, g)
+		if b != nil {
+			added, err := b.Add(g)
+			if err != nil {
+				return err
+			}
+			if !added {
+				duplicates++
+			}
+		}
+		if out != nil {
+			if err := out.write(gameRecord(g)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fmt.Printf("%s (%d) %s\n", g.Level, g.Clues, strings.Join(g.Strategies, ", "))
+		fmt.Printf("%s\n", g.Puzzle.Encode())
+		g.Puzzle.Display()
+		g.Solution.Display()
+	}
+
+	if b != nil {
+		fmt.Fprintf(os.Stderr, "bank: added %d, skipped %d duplicates, %d in bank\n", len(games)-duplicates, duplicates, b.Len())
+	}
+	if len(games) == 0 && numberOfTasks > 0 {
+		return errNoGames
+	}
+
+	return writeGames(games, htmlOutput, &files, &pdf, out)
+}
+
+// errNoGames is returned by generate when every requested puzzle used up its attempts.
+var errNoGames = errors.New("no puzzles were generated within the attempt limit (see -a)")
+
+// failed reports on standard error that a worker used up its attempts without generating a puzzle at the requested level.
+func failed() {
+	fmt.Fprintln(os.Stderr, "gave up on a puzzle after the maximum attempts (see -a)")
+}
+
+// writeGames writes generated games, sorted by level, as the HTML, image and PDF files asked for by the flags, and closes out if it is not nil.
+func writeGames(games []*generator.Game, htmlOutput bool, files *htmlFlags, pdf *pdfFlags, out recordWriter) error {
+	if htmlOutput || files.set() || pdf.file != "" {
+		sort.Slice(games, func(i, j int) bool {
+			return games[i].Level < games[j].Level
+		})
+	}
+	if htmlOutput || files.set() {
+		if err := files.output(games); err != nil {
+			return err
+		}
+	}
+	if pdf.file != "" {
+		if err := pdf.write(games); err != nil {
+			return err
+		}
+	}
+
+	if out != nil {
+		return out.close()
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"dogdaze.org/sudoku/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateGivesUp(t *testing.T) {
+	defer generator.AttemptsFlag(flag.NewFlagSet("attempts", flag.ContinueOnError)) // Restores the default limit.
+
+	// A 4 x 4 puzzle never needs the expert strategies, so a single attempt cannot succeed.
+	assert.Equal(t, errNoGames, generate([]string{"-size", "4", "-a", "1", "-3", "1"}))
+	assert.Equal(t, errNoGames, generate([]string{"-a", "0", "-0", "1"}))
+}
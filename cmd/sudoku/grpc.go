@@ -0,0 +1,404 @@
+//go:build go1.24
+// +build go1.24
+
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+type (
+	// grpcError is an error with a gRPC status code.
+	grpcError struct {
+		code    int
+		message string
+	}
+
+	// grpcMethod decodes the request message of a unary method, calls it, and encodes its response message.
+	grpcMethod func(ctx context.Context, request []byte) (protoMessage, error)
+)
+
+// gRPC status codes.
+const (
+	grpcOK                = 0
+	grpcCanceled          = 1
+	grpcInvalidArgument   = 3
+	grpcDeadlineExceeded  = 4
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+	grpcInternal          = 13
+)
+
+// maxMessage limits the size of a request message.
+const maxMessage = 1 << 16
+
+func (e *grpcError) Error() string {
+	return e.message
+}
+
+// serveGRPC serves the service of api/sudoku.proto on addr. It speaks gRPC over unencrypted HTTP/2 with only the standard library, so it supports neither TLS nor compressed messages, which a client can be told not to use.
+func (s *server) serveGRPC(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sudoku.v1.Sudoku/Generate", s.grpcGenerate)
+	mux.HandleFunc("/sudoku.v1.Sudoku/Solve", s.grpcUnary(s.grpcSolve))
+	mux.HandleFunc("/sudoku.v1.Sudoku/Rate", s.grpcUnary(s.grpcRate))
+	mux.HandleFunc("/sudoku.v1.Sudoku/Hint", s.grpcUnary(s.grpcHint))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		grpcStart(w)
+		grpcFinish(w, &grpcError{grpcUnimplemented, "unknown method " + r.URL.Path})
+	})
+
+	hs := &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		Protocols:    new(http.Protocols),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 5 * time.Minute, // Generate streams its games over one call, and expert puzzles can take a while.
+		IdleTimeout:  2 * time.Minute,
+	}
+	hs.Protocols.SetUnencryptedHTTP2(true)
+
+	return hs.ListenAndServe()
+}
+
+// grpcUnary adapts a unary method to a handler, reading its request message and writing its response message and status.
+func (s *server) grpcUnary(method grpcMethod) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel, request, err := grpcRequest(r)
+		defer cancel()
+
+		grpcStart(w)
+		if err == nil {
+			var response protoMessage
+			if response, err = method(ctx, request); err == nil {
+				err = grpcWrite(w, response)
+			}
+		}
+		grpcFinish(w, err)
+	}
+}
+
+// grpcRequest reads the single message of a request and returns it with a context that ends at the deadline set by the client, if any.
+func grpcRequest(r *http.Request) (context.Context, context.CancelFunc, []byte, error) {
+	ctx, cancel := r.Context(), context.CancelFunc(func() {})
+	if t := r.Header.Get("Grpc-Timeout"); t != "" {
+		if d, ok := grpcTimeout(t); ok {
+			ctx, cancel = context.WithTimeout(ctx, d)
+		}
+	}
+
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		return ctx, cancel, nil, &grpcError{grpcInvalidArgument, "expected a POST of application/grpc"}
+	}
+
+	var header [5]byte
+	if _, err := io.ReadFull(r.Body, header[:]); err != nil {
+		return ctx, cancel, nil, &grpcError{grpcInvalidArgument, "missing request message"}
+	}
+	if header[0] != 0 {
+		return ctx, cancel, nil, &grpcError{grpcUnimplemented, "compressed messages are not supported"}
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxMessage {
+		return ctx, cancel, nil, &grpcError{grpcResourceExhausted, fmt.Sprintf("request message larger than %d bytes", maxMessage)}
+	}
+
+	request := make([]byte, length)
+	if _, err := io.ReadFull(r.Body, request); err != nil {
+		return ctx, cancel, nil, &grpcError{grpcInvalidArgument, "truncated request message"}
+	}
+
+	return ctx, cancel, request, nil
+}
+
+// grpcTimeout parses the value of a grpc-timeout header, such as 100m for 100 milliseconds.
+func grpcTimeout(t string) (time.Duration, bool) {
+	units := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second, 'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond}
+	if len(t) < 2 {
+		return 0, false
+	}
+
+	unit, ok := units[t[len(t)-1]]
+	n, err := strconv.ParseInt(t[:len(t)-1], 10, 64)
+	if !ok || err != nil || n < 0 {
+		return 0, false
+	}
+
+	return time.Duration(n) * unit, true
+}
+
+// grpcStart sends the headers of a response, which declare the status trailers.
+func grpcStart(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Add("Trailer", "Grpc-Status")
+	w.Header().Add("Trailer", "Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+}
+
+// grpcWrite sends a response message, framed with its length, and flushes it so that streamed messages arrive as they are made.
+func grpcWrite(w http.ResponseWriter, m protoMessage) error {
+	frame := make([]byte, 5, 5+len(m))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(m)))
+	if _, err := w.Write(append(frame, m...)); err != nil {
+		return err
+	}
+	w.(http.Flusher).Flush()
+
+	return nil
+}
+
+// grpcFinish sends the status of a call, derived from err, in the trailers.
+func grpcFinish(w http.ResponseWriter, err error) {
+	code, message := grpcOK, ""
+	if err != nil {
+		code, message = grpcStatus(err)
+	}
+
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", grpcEscape(message))
+}
+
+// grpcStatus maps an error of the API to a gRPC status code and message.
+func grpcStatus(err error) (int, string) {
+	var (
+		ge  *grpcError
+		bad badRequest
+	)
+	switch {
+	case errors.As(err, &ge):
+		return ge.code, ge.message
+	case errors.As(err, &bad):
+		return grpcInvalidArgument, err.Error()
+	case errors.Is(err, errBusy):
+		return grpcResourceExhausted, err.Error()
+	case errors.Is(err, context.Canceled):
+		return grpcCanceled, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return grpcDeadlineExceeded, err.Error()
+	case errors.Is(err, errProto):
+		return grpcInvalidArgument, err.Error()
+	}
+
+	return grpcInternal, err.Error()
+}
+
+// grpcEscape percent-encodes a status message as the gRPC protocol requires.
+func grpcEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// grpcGenerate streams the games of a GenerateRequest, generating each on a worker of the pool.
+func (s *server) grpcGenerate(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel, request, err := grpcRequest(r)
+	defer cancel()
+
+	grpcStart(w)
+	defer func() { grpcFinish(w, err) }()
+	if err != nil {
+		return
+	}
+
+	var req apiRequest
+	if err = protoFields(request, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 1:
+			req.Level = protoLevel(v)
+		case 2:
+			req.Count = int(int32(v))
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	level, count, err := s.generateRequest(&req)
+	if err != nil {
+		return
+	}
+
+	for i := 0; i < count; i++ {
+		var game *generator.Game
+		if err = s.pool.run(ctx, func() error {
+			if game = generateGame(level); game == nil {
+				return fmt.Errorf("could not generate a %s puzzle", level)
+			}
+			return nil
+		}); err != nil {
+			return
+		}
+
+		var m protoMessage
+		m.string(1, game.Puzzle.Encode())
+		m.string(2, game.Solution.Values())
+		m.uint(3, uint64(game.Level)+1)
+		m.uint(4, uint64(game.Clues))
+		m.strings(5, game.Strategies)
+		m.int(6, game.Seed)
+		if err = grpcWrite(w, m); err != nil {
+			return
+		}
+	}
+}
+
+// grpcPuzzle decodes a request whose first field is a puzzle and whose second, if any, holds the values entered so far.
+func grpcPuzzle(request []byte) (*apiRequest, error) {
+	var req apiRequest
+	err := protoFields(request, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 1:
+			req.Puzzle = string(data)
+		case 2:
+			req.Values = string(data)
+		}
+		return nil
+	})
+
+	return &req, err
+}
+
+// grpcCall decodes a request and runs the endpoint of the JSON API that answers it on the pool.
+func (s *server) grpcCall(ctx context.Context, request []byte, endpoint func(req *apiRequest) (interface{}, error)) (interface{}, error) {
+	req, err := grpcPuzzle(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var res interface{}
+	err = s.pool.run(ctx, func() (err error) {
+		res, err = endpoint(req)
+		return
+	})
+
+	return res, err
+}
+
+func (s *server) grpcSolve(ctx context.Context, request []byte) (protoMessage, error) {
+	res, err := s.grpcCall(ctx, request, s.solve)
+	if err != nil {
+		return nil, err
+	}
+
+	r := res.(*record)
+	m := recordMessage(r)
+	m.string(7, r.Solution)
+	m.string(8, r.Repaired)
+
+	return m, nil
+}
+
+func (s *server) grpcRate(ctx context.Context, request []byte) (protoMessage, error) {
+	res, err := s.grpcCall(ctx, request, s.rate)
+	if err != nil {
+		return nil, err
+	}
+
+	return recordMessage(res.(*record)), nil
+}
+
+// recordMessage encodes the fields that a SolveResponse and a RateResponse share.
+func recordMessage(r *record) protoMessage {
+	var m protoMessage
+	m.string(1, r.Encoded)
+	m.uint(2, uint64(r.Level)+1)
+	m.uint(3, uint64(r.Clues))
+	m.strings(4, r.Strategies)
+	m.bool(5, r.Solved)
+	m.uint(6, uint64(r.Solutions))
+
+	return m
+}
+
+func (s *server) grpcHint(ctx context.Context, request []byte) (protoMessage, error) {
+	res, err := s.grpcCall(ctx, request, s.hint)
+	if err != nil {
+		return nil, err
+	}
+
+	h := res.(*hintResponse)
+	cell := func(row, col, digit int) []byte {
+		var c protoMessage
+		c.uint(1, uint64(row))
+		c.uint(2, uint64(col))
+		c.uint(3, uint64(digit))
+		return c
+	}
+
+	var m protoMessage
+	for _, p := range h.Wrong {
+		m.bytes(1, cell(p.Row, p.Col, 0))
+	}
+	m.bool(2, h.Solved)
+	m.string(3, h.Strategy)
+	if h.Level != nil {
+		m.uint(4, uint64(*h.Level)+1)
+	}
+	m.strings(5, h.Notes)
+	for _, p := range h.Pattern {
+		m.bytes(6, cell(p.Row, p.Col, 0))
+	}
+	for _, p := range h.Removed {
+		m.bytes(7, cell(p.Row, p.Col, p.Digit))
+	}
+	for _, l := range h.Links {
+		var link protoMessage
+		link.bytes(1, cell(l.From.Row, l.From.Col, 0))
+		link.bytes(2, cell(l.To.Row, l.To.Col, 0))
+		link.uint(3, uint64(l.Digit))
+		link.bool(4, l.Strong)
+		m.bytes(8, link)
+	}
+	m.string(9, h.SVG)
+
+	return m, nil
+}
+
+// protoLevel converts a Level of the service, which starts at LEVEL_EASY = 1, to the name of a generator.Level.
+func protoLevel(v uint64) string {
+	if v == 0 || v > uint64(generator.Extreme)+1 {
+		return strconv.FormatUint(v, 10) // Rejected by ParseLevel.
+	}
+
+	return generator.Level(v - 1).String()
+}
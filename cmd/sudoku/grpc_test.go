@@ -0,0 +1,216 @@
+//go:build go1.24
+// +build go1.24
+
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dogdaze.org/sudoku/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+// grpcPost calls a gRPC handler with a request message and returns its response messages and status trailers.
+func grpcPost(handler http.HandlerFunc, m protoMessage) ([][]byte, *http.Response) {
+	frame := make([]byte, 5, 5+len(m))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(m)))
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(append(frame, m...)))
+	r.Header.Set("Content-Type", "application/grpc")
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	var messages [][]byte
+	for b := w.Body.Bytes(); len(b) >= 5; {
+		length := binary.BigEndian.Uint32(b[1:5])
+		messages = append(messages, b[5:5+length])
+		b = b[5+length:]
+	}
+
+	return messages, w.Result()
+}
+
+// protoMap decodes a message into the values of each field, as integers for varint fields and as bytes for length-delimited ones.
+func protoMap(t *testing.T, b []byte) map[int][]protoField {
+	fields, err := decodeProto(b)
+	assert.NoError(t, err)
+	m := map[int][]protoField{}
+	for _, f := range fields {
+		m[f.field] = append(m[f.field], f)
+	}
+
+	return m
+}
+
+func TestRecordMessage(t *testing.T) {
+	m := protoMap(t, recordMessage(&record{
+		Encoded:    servePuzzle,
+		Level:      generator.Hard,
+		Clues:      26,
+		Strategies: []string{"Naked Single", "X-Wing"},
+		Solved:     true,
+		Solutions:  1,
+	}))
+	assert.Equal(t, []protoField{{1, 0, servePuzzle}}, m[1])
+	assert.Equal(t, []protoField{{2, 3, ""}}, m[2]) // LEVEL_HARD.
+	assert.Equal(t, []protoField{{3, 26, ""}}, m[3])
+	assert.Equal(t, []protoField{{4, 0, "Naked Single"}, {4, 0, "X-Wing"}}, m[4])
+	assert.Equal(t, []protoField{{5, 1, ""}}, m[5])
+	assert.Equal(t, []protoField{{6, 1, ""}}, m[6])
+	assert.Len(t, m, 6)
+
+	m = protoMap(t, recordMessage(&record{Encoded: servePuzzle, Level: generator.Easy}))
+	assert.Equal(t, []protoField{{2, 1, ""}}, m[2]) // LEVEL_EASY, not left out as the default.
+}
+
+func TestProtoLevel(t *testing.T) {
+	for v := generator.Easy; v <= generator.Extreme; v++ {
+		assert.Equal(t, v.String(), protoLevel(uint64(v)+1))
+	}
+	for _, v := range []uint64{0, uint64(generator.Extreme) + 2, 1 << 40} {
+		_, err := generator.ParseLevel(protoLevel(v))
+		assert.Error(t, err, v)
+	}
+}
+
+func TestGRPCGenerate(t *testing.T) {
+	s := newTestServer()
+
+	var req protoMessage
+	req.uint(1, 1) // LEVEL_EASY.
+	req.uint(2, 2)
+	messages, res := grpcPost(s.grpcGenerate, req)
+	assert.Equal(t, "0", res.Trailer.Get("Grpc-Status"))
+	assert.Len(t, messages, 2)
+	for _, b := range messages {
+		m := protoMap(t, b)
+		assert.Len(t, m[1][0].data, 81)
+		assert.Len(t, m[2][0].data, 81)
+		assert.Equal(t, uint64(1), m[3][0].v)
+		assert.NotEmpty(t, m[5])
+	}
+
+	req = nil
+	req.uint(1, 1)
+	req.uint(2, 4) // More than maxGames.
+	messages, res = grpcPost(s.grpcGenerate, req)
+	assert.Equal(t, "3", res.Trailer.Get("Grpc-Status"))
+	assert.Empty(t, messages)
+}
+
+func TestGRPCSolve(t *testing.T) {
+	s := newTestServer()
+	want, err := s.solve(&apiRequest{Puzzle: servePuzzle})
+	assert.NoError(t, err)
+	r := want.(*record)
+
+	var req protoMessage
+	req.string(1, servePuzzle)
+	messages, res := grpcPost(s.grpcUnary(s.grpcSolve), req)
+	assert.Equal(t, "0", res.Trailer.Get("Grpc-Status"))
+	assert.Len(t, messages, 1)
+	m := protoMap(t, messages[0])
+	assert.Equal(t, servePuzzle, m[1][0].data)
+	assert.Equal(t, uint64(r.Level)+1, m[2][0].v)
+	assert.Equal(t, uint64(r.Clues), m[3][0].v)
+	assert.Len(t, m[4], len(r.Strategies))
+	assert.Equal(t, uint64(1), m[6][0].v)
+	assert.Equal(t, serveSolution, m[7][0].data)
+	assert.Empty(t, m[8])
+
+	messages, res = grpcPost(s.grpcUnary(s.grpcRate), req)
+	assert.Equal(t, "0", res.Trailer.Get("Grpc-Status"))
+	m = protoMap(t, messages[0])
+	assert.Equal(t, uint64(r.Level)+1, m[2][0].v)
+	assert.Empty(t, m[7])
+
+	req = nil
+	req.string(1, "123")
+	messages, res = grpcPost(s.grpcUnary(s.grpcSolve), req)
+	assert.Equal(t, "3", res.Trailer.Get("Grpc-Status"))
+	assert.NotEmpty(t, res.Trailer.Get("Grpc-Message"))
+	assert.Empty(t, messages)
+
+	messages, res = grpcPost(s.grpcUnary(s.grpcSolve), protoMessage{0x0a, 0x05})
+	assert.Equal(t, "3", res.Trailer.Get("Grpc-Status"))
+	assert.Equal(t, errProto.Error(), res.Trailer.Get("Grpc-Message"))
+}
+
+func TestGRPCHint(t *testing.T) {
+	s := newTestServer()
+	want, err := s.hint(&apiRequest{Puzzle: servePuzzle})
+	assert.NoError(t, err)
+	h := want.(*hintResponse)
+
+	var req protoMessage
+	req.string(1, servePuzzle)
+	messages, res := grpcPost(s.grpcUnary(s.grpcHint), req)
+	assert.Equal(t, "0", res.Trailer.Get("Grpc-Status"))
+	m := protoMap(t, messages[0])
+	assert.Empty(t, m[1])
+	assert.Empty(t, m[2])
+	assert.Equal(t, h.Strategy, m[3][0].data)
+	assert.Equal(t, uint64(*h.Level)+1, m[4][0].v)
+	assert.Len(t, m[5], len(h.Notes))
+	assert.Len(t, m[6], len(h.Pattern))
+	assert.Len(t, m[7], len(h.Removed))
+	assert.Len(t, m[8], len(h.Links))
+	assert.Equal(t, h.SVG, m[9][0].data)
+	for i, p := range h.Removed {
+		cell := protoMap(t, []byte(m[7][i].data))
+		var row, col, digit uint64
+		if len(cell[1]) > 0 {
+			row = cell[1][0].v
+		}
+		if len(cell[2]) > 0 {
+			col = cell[2][0].v
+		}
+		if len(cell[3]) > 0 {
+			digit = cell[3][0].v
+		}
+		assert.Equal(t, []uint64{uint64(p.Row), uint64(p.Col), uint64(p.Digit)}, []uint64{row, col, digit})
+	}
+
+	values := []byte(servePuzzle)
+	values[0] = '1' // The solution has a 6 there.
+	req = nil
+	req.string(1, servePuzzle)
+	req.string(2, string(values))
+	messages, res = grpcPost(s.grpcUnary(s.grpcHint), req)
+	assert.Equal(t, "0", res.Trailer.Get("Grpc-Status"))
+	m = protoMap(t, messages[0])
+	assert.Equal(t, []protoField{{1, 0, ""}}, m[1]) // The cell at row 0, column 0, whose fields are all defaults.
+	assert.Len(t, m, 1)
+
+	req = nil
+	req.string(1, servePuzzle)
+	req.string(2, serveSolution)
+	messages, _ = grpcPost(s.grpcUnary(s.grpcHint), req)
+	assert.Equal(t, []protoField{{2, 1, ""}}, protoMap(t, messages[0])[2])
+}
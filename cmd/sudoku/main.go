@@ -28,65 +28,45 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
-	"html/template"
+	"io"
 	"os"
 	"path"
-	"runtime"
-	"sort"
 	"strings"
-
-	"dogdaze.org/sudoku/generator"
-	"github.com/grkuntzmd/qrcodegen"
-	"github.com/pkg/browser"
 )
 
 type (
 	inputs []string
 
-	puzzle struct {
-		Num int
-		generator.Level
-		Break   bool
-		Grid    template.HTML
-		QRCode  template.HTML
-		Encoded string
-	}
-
-	solution struct {
-		Num  int
-		Grid template.HTML
+	// command is a subcommand of the CLI. The run function receives the arguments following the subcommand name.
+	command struct {
+		name    string
+		summary string
+		run     func(args []string) error
 	}
 )
 
+const inputUsage = "`file` containing input patterns, or - for stdin (may be repeated); puzzles may also be given as arguments, and stdin is read if there are neither"
+
 var (
 	buildInfo  string
 	buildStamp string
 	gitHash    string
 	version    string
 
-	level0Count int
-	level1Count int
-	level2Count int
-	level3Count int
-	// level4Count int
-
-	input      inputs
-	bruteForce bool
-	htmlOutput bool
-	verbose    uint
+	commands []command
 )
 
 func init() {
-	flag.IntVar(&level0Count, "0", 0, "`count` of easy games to generate")
-	flag.IntVar(&level1Count, "1", 0, "`count` of standard games to generate")
-	flag.IntVar(&level2Count, "2", 0, "`count` of hard games to generate")
-	flag.IntVar(&level3Count, "3", 0, "`count` of expert games to generate")
-	// flag.IntVar(&level4Count, "4", 0, "`count` of extreme (nearly impossible) games to generate")
-
-	flag.Var(&input, "i", "`file` containing input patterns (may be repeated)")
-	flag.BoolVar(&bruteForce, "b", false, "use brute force search to solve")
-	flag.BoolVar(&htmlOutput, "h", false, "display HTML output on the default browser")
-	flag.UintVar(&verbose, "v", 0, "`verbosity` level; higher emits more messages")
+	commands = []command{
+		{"generate", "generate puzzles at the requested levels", generate},
+		{"solve", "solve puzzles, showing each grid", solve},
+		{"rate", "rate puzzles by the hardest strategy needed to solve them", rate},
+		{"validate", "check that puzzles are well formed and have a single solution", validate},
+		{"render", "display puzzles and their solutions as HTML in the default browser or a PDF booklet", render},
+		{"convert", "convert puzzles between encodings", convert},
+		{"bank", "add puzzles to a bank that skips duplicates, or query it", bank},
+		{"serve", "serve a JSON API over HTTP for generating, solving, rating, hinting at, and rendering puzzles", serve},
+	}
 
 	if buildInfo != "" {
 		parts := strings.Split(buildInfo, "|")
@@ -99,132 +79,44 @@ func init() {
 }
 
 func main() {
-	flag.CommandLine.Usage = usage
-	flag.Parse()
-
-	if len(input) > 0 && (level0Count > 0 || level1Count > 0 || level2Count > 0 || level3Count > 0 /* || level4Count > 0 */) {
+	if len(os.Args) < 2 {
 		usage()
 		os.Exit(1)
 	}
 
-	if len(input) > 0 { // Handle -i files.
-		for _, i := range input {
-			f, err := os.Open(i)
-			if err != nil {
-				fmt.Printf("cannot open %s for reading; skipping\n", i)
-				continue
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		if len(os.Args) > 2 {
+			if c := lookup(os.Args[2]); c != nil {
+				c.run([]string{"-h"})
 			}
-			defer f.Close()
-
-			all := 0
-			sol := 0
-			s := bufio.NewScanner(f)
-			for s.Scan() {
-				all++
-				line := s.Text()
-				fmt.Printf("Encoded: %s\n", line)
-
-				grid, err := generator.ParseEncoded(line)
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-					continue
-				}
-				grid.Display()
-
-				if !grid.Valid() {
-					fmt.Fprintln(os.Stderr, "grid is invalid")
-					continue
-				}
-
-				strategies := make(map[string]bool)
-				maxLevel, solved := grid.Reduce(true, &strategies, verbose)
-
-				var names []string
-				for n := range strategies {
-					names = append(names, n)
-				}
-				sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
-
-				grid.Display()
-				if solved {
-					sol++
-					fmt.Printf("level: %s, solved, (%s)\n", maxLevel, strings.Join(names, ", "))
-				} else {
-					fmt.Printf("level: %s, not solved (%s)\n", maxLevel, strings.Join(names, ", "))
-					if bruteForce {
-						solutions := make([]*generator.Grid, 0)
-						grid.Search(&solutions)
-						switch len(solutions) {
-						case 0:
-							fmt.Printf("still not solved after search, (%s)\n", strings.Join(names, ", "))
-						case 1:
-							fmt.Printf("single solution found, (%s)\n", strings.Join(names, ", "))
-							solutions[0].Display()
-						default:
-							fmt.Printf("multiple solutions found, (%s)\n", strings.Join(names, ", "))
-							for _, s := range solutions {
-								s.Display()
-							}
-						}
-					}
-				}
-			}
-			fmt.Printf("solved %d of %d\n", sol, all)
-		}
-	} else { // Generate puzzles of levels given in -0, -1, -2, -3, -4.
-		numberOfWorkers := runtime.NumCPU()
-		numberOfTasks := level0Count + level1Count + level2Count + level3Count // + level4Count
-
-		tasks := make(chan generator.Level, numberOfTasks)
-		results := make(chan *generator.Game, numberOfTasks)
-
-		for w := 0; w < numberOfWorkers; w++ {
-			go generator.Worker(tasks, results)
-		}
-
-		for t := 0; t < level0Count; t++ {
-			tasks <- generator.Easy
-		}
-
-		for t := 0; t < level1Count; t++ {
-			tasks <- generator.Standard
-		}
-
-		for t := 0; t < level2Count; t++ {
-			tasks <- generator.Hard
 		}
+		usage()
+		return
+	}
 
-		for t := 0; t < level3Count; t++ {
-			tasks <- generator.Expert
-		}
-
-		// for t := 0; t < level4Count; t++ {
-		// 	tasks <- generator.Extreme
-		// }
-
-		close(tasks)
-
-		games := make([]*generator.Game, 0, numberOfTasks)
-
-		for t := 0; t < numberOfTasks; t++ {
-			g := <-results
-			if g != nil {
-				fmt.Printf("%s (%d) %s\n", g.Level, g.Clues, strings.Join(g.Strategies, ", "))
-				fmt.Printf("%s\n", g.Puzzle.Encode())
-				g.Puzzle.Display()
-				g.Solution.Display()
-				games = append(games, g)
-			}
+	if err := dispatch(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if lookup(name) == nil {
+			fmt.Fprintln(os.Stderr)
+			usage()
 		}
+		os.Exit(1)
+	}
+}
 
-		if htmlOutput {
-			sort.Slice(games, func(i, j int) bool {
-				return games[i].Level < games[j].Level
-			})
+// dispatch runs the command named by args[0] with the remaining arguments. The error of the command is prefixed with its name.
+func dispatch(args []string) error {
+	c := lookup(args[0])
+	if c == nil {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
 
-			html(games)
-		}
+	if err := c.run(args[1:]); err != nil {
+		return fmt.Errorf("%s: %w", c.name, err)
 	}
+
+	return nil
 }
 
 func (i *inputs) Set(value string) error {
@@ -236,101 +128,83 @@ func (i *inputs) String() string {
 	return strings.Join(*i, ",")
 }
 
-func html(games []*generator.Game) {
-	puzzles := make([]puzzle, 0, len(games))
-	solutions := make([]solution, 0, len(games))
+// eachPuzzle calls f with every puzzle given as an argument and every line from the input files, where "-" names stdin, that is neither blank nor a comment starting with "#". If there are neither arguments nor input files, it reads stdin. A file that cannot be opened is reported and skipped. It stops at the first error returned by f.
+func eachPuzzle(files inputs, args []string, f func(line string) error) error {
+	for _, a := range args {
+		if err := f(strings.TrimSpace(a)); err != nil {
+			return err
+		}
+	}
+
+	if len(files) == 0 && len(args) == 0 {
+		files = inputs{"-"}
+	}
 
-	for i, g := range games {
-		segs := []*qrcodegen.QRSegment{
-			qrcodegen.MakeAlphanumeric("SUDOKU://"),
-			qrcodegen.MakeNumeric(g.Puzzle.Encode()),
+	for _, i := range files {
+		if err := eachLine(i, f); err != nil {
+			return err
 		}
-		qrCode, err := qrcodegen.EncodeSegments(segs, qrcodegen.Low)
+	}
+
+	return nil
+}
+
+func eachLine(name string, f func(line string) error) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
 		if err != nil {
-			panic(err)
+			fmt.Fprintf(os.Stderr, "cannot open %s for reading; skipping\n", name)
+			return nil
 		}
-		svg, err := qrCode.ToSVGString(4, false)
-		if err != nil {
-			panic(err)
+		defer file.Close()
+		r = file
+	}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
 
-		puzzles = append(puzzles, puzzle{i + 1, g.Level, i%2 == 1, template.HTML(g.Puzzle.SVG(0.8, false, false, nil)), template.HTML(svg), g.Puzzle.Encode()})
-		solutions = append(solutions, solution{i + 1, template.HTML(g.Solution.SVG(0.3, true, false, nil))})
+		if err := f(line); err != nil {
+			return err
+		}
 	}
 
-	t := template.Must(template.New("html").Parse(`
-		<!DOCTYPE html>
-		<html lang="en">
-		<head>
-			<meta charset="UTF-8">
-			<meta name="viewport" content="width=device-width, initial-scale=1.0">
-			<title>Sudoku</title>
-
-			<style>
-				.break { page-break-after: always; }
-				.puzzle {
-					display: grid;
-					grid-template: 40vh / 80% 20%;
-					column-gap: 10px;
-					justify-items: center;
-					align-items: stretch;
-				}
-				.small svg {
-					height: 100%;
-					width: 100%;
-				}
-				.small-font {
-					font-size: 0.8em;
-				}
-				.solutions {
-					display: flex;
-					flex-direction: row;
-					flex-wrap: wrap;
-					justify-content: space-between;
-					align-items: flex-start;
-				}
-			</style>
-		</head>
-		<body>
-			{{ range .Puzzles }}
-				<div {{ if .Break }}class="break"{{ end }} style="page-break-inside: avoid;">
-					<h2>{{ .Num }} {{ .Level }}</h2>
-					<div class="puzzle">
-						<div>{{ .Grid }}</div>
-						<div class="small">{{ .QRCode }}</div>
-					</div>
-					<p class="small-font">Encoded: {{ .Encoded }}</p>
-				</div>
-			{{ end }}
-			<p class="break"></p>
-			<div class="solutions">
-				{{ range .Solutions }}
-					<div style="page-break-inside: avoid;">
-						<h4>{{ .Num }}</h4>
-						<p>{{ .Grid }}</p>
-					</div>
-				{{ end }}
-			</div>
-		</body>
-		</html>
-	`))
+	return s.Err()
+}
 
-	var b strings.Builder
-	if err := t.Execute(&b, struct {
-		Puzzles   []puzzle
-		Solutions []solution
-	}{puzzles, solutions}); err != nil {
-		panic(err)
+func lookup(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
 	}
 
-	if err := browser.OpenReader(strings.NewReader(b.String())); err != nil {
-		panic(err)
+	return nil
+}
+
+// newFlagSet creates the flag set for a subcommand with a usage message that shows the arguments and a description.
+func newFlagSet(name, arguments, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	if arguments != "" {
+		arguments = " " + arguments
 	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [options]%s\n\n%s\n\n", path.Base(os.Args[0]), name, arguments, description)
+		fs.PrintDefaults()
+	}
+
+	return fs
 }
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", path.Base(os.Args[0]))
-	flag.PrintDefaults()
-	fmt.Fprintln(os.Stderr, "\nEither -i or level counts (-0, -1, -2, -3, -4) may be used, but not both.")
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [options]\n\nCommands:\n", path.Base(os.Args[0]))
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nUse \"%s help <command>\" or \"%s <command> -h\" for the options of a command.\n", path.Base(os.Args[0]), path.Base(os.Args[0]))
 	fmt.Fprintf(os.Stderr, "\nbuildStamp: %s, gitHash: %s, version: %s\n", buildStamp, gitHash, version)
 }
@@ -0,0 +1,123 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readPuzzles returns the lines that eachPuzzle passes on, with stdin replaced by a temporary file holding the given text.
+func readPuzzles(t *testing.T, stdin string, files inputs, args []string) []string {
+	f, err := ioutil.TempFile("", "stdin")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.WriteString(stdin)
+	assert.NoError(t, err)
+	_, err = f.Seek(0, 0)
+	assert.NoError(t, err)
+
+	defer func(stdin *os.File) { os.Stdin = stdin }(os.Stdin)
+	os.Stdin = f
+
+	var lines []string
+	assert.NoError(t, eachPuzzle(files, args, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}))
+
+	return lines
+}
+
+func TestEachPuzzle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "input")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "puzzles.txt")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("# A comment.\nfile 1\n\n  file 2  \n"), 0644))
+	missing := filepath.Join(dir, "missing.txt")
+	stdin := "stdin 1\n# Another comment.\nstdin 2\n"
+
+	for _, test := range []struct {
+		name  string
+		files inputs
+		args  []string
+		want  []string
+	}{
+		{"stdin", nil, nil, []string{"stdin 1", "stdin 2"}},
+		{"dash", inputs{"-"}, nil, []string{"stdin 1", "stdin 2"}},
+		{"file", inputs{file}, nil, []string{"file 1", "file 2"}},
+		{"arguments", nil, []string{" arg 1", "arg 2"}, []string{"arg 1", "arg 2"}},
+		{"arguments and files", inputs{file, "-"}, []string{"arg"}, []string{"arg", "file 1", "file 2", "stdin 1", "stdin 2"}},
+		{"missing file", inputs{missing, file}, nil, []string{"file 1", "file 2"}},
+	} {
+		assert.Equal(t, test.want, readPuzzles(t, stdin, test.files, test.args), test.name)
+	}
+}
+
+func TestEachPuzzleStops(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := eachPuzzle(nil, []string{"1", "2"}, func(line string) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDispatch(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"generate", "x"}, "generate: unexpected arguments: x"},
+		{[]string{"solve", "-format", "xml"}, `solve: unknown format "xml" (expected one of csv, json, jsonl)`},
+		{[]string{"rate", "-format", "xml"}, `rate: unknown format "xml" (expected one of csv, json, jsonl)`},
+		{[]string{"validate", "-q", "12"}, "validate: 1 of 1 puzzles failed"},
+		{[]string{"render", "12"}, "render: no puzzles to render"},
+		{[]string{"convert", "-to", "xml"}, `convert: unknown encoding "xml" (expected one of base64, dots, grid, json, text, values, zeros)`},
+		{[]string{"bank", "12"}, "bank: puzzles are only read with -add"},
+		{[]string{"serve", "x"}, "serve: unexpected arguments: x"},
+		{[]string{"bogus"}, `unknown command "bogus"`},
+		{[]string{"Solve"}, `unknown command "Solve"`},
+	} {
+		captureStdout(t, func() {
+			assert.EqualError(t, dispatch(test.args), test.err, test.args[0])
+		})
+	}
+
+	out := captureStdout(t, func() {
+		assert.NoError(t, dispatch([]string{"convert", "-to", "dots", outputPuzzle}))
+	})
+	assert.Equal(t, outputPuzzle+"\n", out)
+}
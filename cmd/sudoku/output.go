@@ -0,0 +1,176 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+type (
+	// record is the machine-readable summary of a single puzzle.
+	record struct {
+		Encoded    string          `json:"encoded"`
+		Level      generator.Level `json:"level"`
+		Clues      uint            `json:"clues"`
+		Strategies []string        `json:"strategies"`
+		Solved     bool            `json:"solved"`
+		Solutions  int             `json:"solutions"`
+		Solution   string          `json:"solution,omitempty"`
+		Repaired   string          `json:"repaired,omitempty"`
+		Cages      string          `json:"cages,omitempty"`
+		Error      string          `json:"error,omitempty"`
+	}
+
+	recordWriter interface {
+		write(r *record) error
+		close() error
+	}
+
+	csvWriter struct {
+		w      *csv.Writer
+		header bool
+	}
+
+	jsonWriter struct {
+		w     io.Writer
+		count int
+	}
+
+	jsonlWriter struct {
+		e *json.Encoder
+	}
+)
+
+var formats = []string{"csv", "json", "jsonl"}
+
+// newRecordWriter returns a writer for the named format, or nil if the format is empty (human-readable output).
+func newRecordWriter(format string, w io.Writer) (recordWriter, error) {
+	switch format {
+	case "":
+		return nil, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "jsonl":
+		return &jsonlWriter{json.NewEncoder(w)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q (expected one of %s)", format, strings.Join(formats, ", "))
+}
+
+// gameRecord builds a record for a generated game.
+func gameRecord(g *generator.Game) *record {
+	return &record{
+		Encoded:    g.Puzzle.Encode(),
+		Level:      g.Level,
+		Clues:      g.Clues,
+		Strategies: nonNil(g.Strategies),
+		Solved:     true,
+		Solutions:  1,
+		Solution:   g.Solution.Values(),
+	}
+}
+
+func (w *csvWriter) write(r *record) error {
+	if !w.header {
+		w.header = true
+		if err := w.w.Write([]string{"encoded", "level", "clues", "strategies", "solved", "solutions", "solution", "repaired", "cages", "error"}); err != nil {
+			return err
+		}
+	}
+
+	if err := w.w.Write([]string{
+		r.Encoded,
+		r.Level.String(),
+		strconv.FormatUint(uint64(r.Clues), 10),
+		strings.Join(r.Strategies, ";"),
+		strconv.FormatBool(r.Solved),
+		strconv.Itoa(r.Solutions),
+		r.Solution,
+		r.Repaired,
+		r.Cages,
+		r.Error,
+	}); err != nil {
+		return err
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvWriter) close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *jsonWriter) write(r *record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n  "
+	if w.count == 0 {
+		sep = "[\n  "
+	}
+	w.count++
+
+	_, err = fmt.Fprintf(w.w, "%s%s", sep, data)
+	return err
+}
+
+func (w *jsonWriter) close() error {
+	if w.count == 0 {
+		_, err := fmt.Fprintln(w.w, "[]")
+		return err
+	}
+
+	_, err := fmt.Fprintln(w.w, "\n]")
+	return err
+}
+
+func (w *jsonlWriter) write(r *record) error {
+	return w.e.Encode(r)
+}
+
+func (w *jsonlWriter) close() error {
+	return nil
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+
+	return s
+}
@@ -0,0 +1,146 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"dogdaze.org/sudoku/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+const outputPuzzle = "....2473.54.37.26.237.....47...3.84...3481....84.6...33......59.7..93..2..62..3.."
+
+var outputRecords = []*record{
+	{Encoded: "123", Level: generator.Hard, Clues: 25, Strategies: []string{"xWing", "nakedPair"}, Solved: true, Solutions: 1, Solution: "456"},
+	{Encoded: "789", Strategies: []string{}, Error: "invalid, with \"quotes\""},
+}
+
+// captureStdout returns what f writes to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	file, err := ioutil.TempFile("", "stdout")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	defer func(stdout *os.File) { os.Stdout = stdout }(os.Stdout)
+	os.Stdout = file
+	f()
+
+	data, err := ioutil.ReadFile(file.Name())
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestRecordWriters(t *testing.T) {
+	for _, test := range []struct {
+		format  string
+		records []*record
+		want    string
+	}{
+		{"csv", outputRecords, "encoded,level,clues,strategies,solved,solutions,solution,repaired,cages,error\n" +
+			"123,Hard,25,xWing;nakedPair,true,1,456,,,\n" +
+			"789,Easy,0,,false,0,,,,\"invalid, with \"\"quotes\"\"\"\n"},
+		{"csv", nil, ""},
+		{"json", outputRecords, "[\n" +
+			`  {"encoded":"123","level":"Hard","clues":25,"strategies":["xWing","nakedPair"],"solved":true,"solutions":1,"solution":"456"},` + "\n" +
+			`  {"encoded":"789","level":"Easy","clues":0,"strategies":[],"solved":false,"solutions":0,"error":"invalid, with \"quotes\""}` + "\n]\n"},
+		{"json", nil, "[]\n"},
+		{"jsonl", outputRecords, `{"encoded":"123","level":"Hard","clues":25,"strategies":["xWing","nakedPair"],"solved":true,"solutions":1,"solution":"456"}` + "\n" +
+			`{"encoded":"789","level":"Easy","clues":0,"strategies":[],"solved":false,"solutions":0,"error":"invalid, with \"quotes\""}` + "\n"},
+		{"jsonl", nil, ""},
+	} {
+		var b bytes.Buffer
+		w, err := newRecordWriter(test.format, &b)
+		if !assert.NoError(t, err, test.format) {
+			continue
+		}
+		for _, r := range test.records {
+			assert.NoError(t, w.write(r), test.format)
+		}
+		assert.NoError(t, w.close(), test.format)
+		assert.Equal(t, test.want, b.String(), test.format)
+	}
+
+	w, err := newRecordWriter("", &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Nil(t, w)
+}
+
+func TestFormatFlag(t *testing.T) {
+	for _, args := range [][]string{
+		{"generate", "-format", "xml", "-0", "1"},
+		{"solve", "-format", "xml", outputPuzzle},
+		{"rate", "-format", "JSON", outputPuzzle},
+	} {
+		c := lookup(args[0])
+		assert.EqualError(t, c.run(args[1:]), `unknown format "`+args[2]+`" (expected one of csv, json, jsonl)`, args[0])
+	}
+}
+
+func TestSolveRecords(t *testing.T) {
+	var want record
+	out := captureStdout(t, func() {
+		assert.NoError(t, solve([]string{"-format", "jsonl", outputPuzzle, "12"}))
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &want))
+		assert.Equal(t, strings.ReplaceAll(outputPuzzle, ".", "0"), want.Encoded)
+		assert.Equal(t, generator.Hard, want.Level)
+		assert.Equal(t, 1, want.Solutions)
+		assert.Len(t, want.Solution, 81)
+
+		var bad record
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &bad))
+		assert.Equal(t, "12", bad.Encoded)
+		assert.NotEmpty(t, bad.Error)
+	}
+
+	out = captureStdout(t, func() {
+		assert.NoError(t, rate([]string{"-format", "json", outputPuzzle}))
+	})
+	var records []record
+	assert.NoError(t, json.Unmarshal([]byte(out), &records))
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, want.Level, records[0].Level)
+		assert.Equal(t, want.Strategies, records[0].Strategies)
+	}
+
+	out = captureStdout(t, func() {
+		assert.NoError(t, solve([]string{"-format", "csv", outputPuzzle}))
+	})
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, []string{want.Encoded, "Hard", "36", strings.Join(want.Strategies, ";"), "false", "1", want.Solution, "", "", ""}, rows[1])
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+// pdfFlags holds the flags that write puzzles to a PDF booklet.
+type pdfFlags struct {
+	file      string
+	page      string
+	perPage   int
+	title     string
+	noAnswers bool
+	noNumbers bool
+}
+
+// register adds the PDF flags to a flag set.
+func (f *pdfFlags) register(fs *flag.FlagSet) {
+	var sizes []string
+	for s := range generator.PageSizes {
+		sizes = append(sizes, s)
+	}
+	sort.Strings(sizes)
+
+	fs.StringVar(&f.file, "pdf", "", "write the puzzles and their solutions to a print-ready PDF booklet in `file`")
+	fs.StringVar(&f.page, "page", "a4", "page `size` of the PDF booklet ("+strings.Join(sizes, ", ")+")")
+	fs.IntVar(&f.perPage, "per-page", 2, "`count` of puzzles on each page of the PDF booklet")
+	fs.StringVar(&f.title, "title", "Sudoku", "`title` printed at the top of each page of the PDF booklet")
+	fs.BoolVar(&f.noAnswers, "no-answers", false, "leave the answer key out of the PDF booklet")
+	fs.BoolVar(&f.noNumbers, "no-page-numbers", false, "leave the page numbers out of the PDF booklet")
+}
+
+// write writes games to the PDF booklet named by the -pdf flag.
+func (f *pdfFlags) write(games []*generator.Game) error {
+	size, ok := generator.PageSizes[strings.ToLower(f.page)]
+	if !ok {
+		return fmt.Errorf("unknown page size %q", f.page)
+	}
+	if f.perPage < 1 {
+		return fmt.Errorf("-per-page must be at least 1")
+	}
+
+	file, err := os.Create(f.file)
+	if err != nil {
+		return err
+	}
+
+	err = generator.WritePDF(file, games, generator.PDFOptions{
+		PageWidth:   size[0],
+		PageHeight:  size[1],
+		PerPage:     f.perPage,
+		Title:       f.title,
+		PageNumbers: !f.noNumbers,
+		AnswerKey:   !f.noAnswers,
+	})
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,144 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// protoMessage builds a protocol buffer message in the wire format. Singular fields that hold their default values are left out, as in proto3.
+type protoMessage []byte
+
+// Wire types of protocol buffer fields.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+var errProto = errors.New("malformed protocol buffer message")
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+
+	return append(b, byte(v))
+}
+
+func (m *protoMessage) tag(field, wire int) {
+	*m = appendVarint(*m, uint64(field<<3|wire))
+}
+
+// uint adds a varint field, such as a uint32, an enum or, through int, an int32 or int64, whose negative values are sign-extended to 64 bits as the wire format requires.
+func (m *protoMessage) uint(field int, v uint64) {
+	if v != 0 {
+		m.tag(field, protoVarint)
+		*m = appendVarint(*m, v)
+	}
+}
+
+func (m *protoMessage) int(field int, v int64) {
+	m.uint(field, uint64(v))
+}
+
+func (m *protoMessage) bool(field int, v bool) {
+	if v {
+		m.uint(field, 1)
+	}
+}
+
+func (m *protoMessage) string(field int, s string) {
+	if s != "" {
+		m.bytes(field, []byte(s))
+	}
+}
+
+// strings adds a repeated string field, including any empty strings.
+func (m *protoMessage) strings(field int, ss []string) {
+	for _, s := range ss {
+		m.bytes(field, []byte(s))
+	}
+}
+
+// bytes adds a length-delimited field, such as a string or an embedded message, even if it is empty.
+func (m *protoMessage) bytes(field int, b []byte) {
+	m.tag(field, protoBytes)
+	*m = appendVarint(*m, uint64(len(b)))
+	*m = append(*m, b...)
+}
+
+// protoFields calls f with each field of a protocol buffer message: its number, and its value as an integer for varint fields or as bytes for length-delimited ones. Fixed-width fields are skipped, since none of the messages of the service have them.
+func protoFields(b []byte, f func(field int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProto
+		}
+		b = b[n:]
+
+		field, wire := int(tag>>3), int(tag&7)
+		var (
+			v    uint64
+			data []byte
+		)
+		switch wire {
+		case protoVarint:
+			if v, n = binary.Uvarint(b); n <= 0 {
+				return errProto
+			}
+			b = b[n:]
+		case protoBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || length > uint64(len(b)-n) {
+				return errProto
+			}
+			data, b = b[n:n+int(length)], b[n+int(length):]
+		case protoFixed64, protoFixed32:
+			size := 8
+			if wire == protoFixed32 {
+				size = 4
+			}
+			if len(b) < size {
+				return errProto
+			}
+			b = b[size:]
+			continue
+		default:
+			return errProto
+		}
+
+		if field == 0 {
+			return errProto
+		}
+		if err := f(field, v, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// protoField is a field decoded by protoFields.
+type protoField struct {
+	field int
+	v     uint64
+	data  string
+}
+
+// decodeProto returns the fields of a message in the order they appear.
+func decodeProto(b []byte) ([]protoField, error) {
+	var fields []protoField
+	err := protoFields(b, func(field int, v uint64, data []byte) error {
+		fields = append(fields, protoField{field, v, string(data)})
+		return nil
+	})
+
+	return fields, err
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	var nested protoMessage
+	nested.uint(1, 8)
+	nested.string(2, "x")
+
+	var m protoMessage
+	m.uint(1, 300)
+	m.uint(2, 0) // Left out.
+	m.int(3, -1)
+	m.int(4, math.MaxInt64)
+	m.bool(5, true)
+	m.bool(6, false) // Left out.
+	m.string(7, "hello")
+	m.string(8, "") // Left out.
+	m.strings(9, []string{"a", "", "c"})
+	m.bytes(10, nested)
+	m.uint(536870911, 1) // The largest field number.
+
+	fields, err := decodeProto(m)
+	assert.NoError(t, err)
+	assert.Equal(t, []protoField{
+		{1, 300, ""},
+		{3, math.MaxUint64, ""},
+		{4, math.MaxInt64, ""},
+		{5, 1, ""},
+		{7, 0, "hello"},
+		{9, 0, "a"},
+		{9, 0, ""},
+		{9, 0, "c"},
+		{10, 0, string(nested)},
+		{536870911, 1, ""},
+	}, fields)
+
+	fields, err = decodeProto([]byte(fields[8].data))
+	assert.NoError(t, err)
+	assert.Equal(t, []protoField{{1, 8, ""}, {2, 0, "x"}}, fields)
+}
+
+func TestProtoWireFormat(t *testing.T) {
+	var m protoMessage
+	m.uint(1, 150)
+	m.string(2, "testing")
+	m.int(3, -2)
+	assert.Equal(t, []byte{
+		0x08, 0x96, 0x01,
+		0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g',
+		0x18, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01,
+	}, []byte(m))
+
+	var empty protoMessage
+	empty.bytes(4, nil)
+	assert.Equal(t, []byte{0x22, 0x00}, []byte(empty))
+}
+
+func TestProtoFieldsSkipFixed(t *testing.T) {
+	b := []byte{
+		0x09, 1, 2, 3, 4, 5, 6, 7, 8, // Field 1, fixed64.
+		0x15, 1, 2, 3, 4, // Field 2, fixed32.
+		0x18, 0x05, // Field 3, varint 5.
+	}
+	fields, err := decodeProto(b)
+	assert.NoError(t, err)
+	assert.Equal(t, []protoField{{3, 5, ""}}, fields)
+}
+
+func TestProtoFieldsMalformed(t *testing.T) {
+	for name, b := range map[string][]byte{
+		"truncated tag":      {0x80},
+		"truncated varint":   {0x08, 0x80},
+		"long varint":        {0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		"excessive length":   {0x12, 0x05, 'a', 'b'},
+		"huge length":        {0x12, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		"truncated fixed64":  {0x09, 1, 2, 3},
+		"truncated fixed32":  {0x15, 1},
+		"field 0":            {0x00, 0x01},
+		"start group":        {0x0b},
+		"end group":          {0x0c},
+		"unknown wire type":  {0x0e},
+		"truncated after ok": {0x08, 0x01, 0x12},
+	} {
+		_, err := decodeProto(b)
+		assert.Equal(t, errProto, err, name)
+	}
+
+	stop := errors.New("stop")
+	err := protoFields([]byte{0x08, 0x01, 0x08, 0x02}, func(field int, v uint64, data []byte) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+}
@@ -0,0 +1,403 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dogdaze.org/sudoku/generator"
+	"github.com/grkuntzmd/qrcodegen"
+	"github.com/pkg/browser"
+)
+
+type (
+	// htmlFlags holds the flags that write HTML, SVG, and PNG or JPEG images to files instead of opening the default browser, which a headless machine does not have.
+	htmlFlags struct {
+		file  string
+		dir   string
+		png   string
+		jpeg  string
+		steps string
+		play  string
+		dpi   float64
+		style generator.SVGOptions
+	}
+
+	puzzle struct {
+		Num int
+		generator.Level
+		Break   bool
+		Grid    template.HTML
+		QRCode  template.HTML
+		Encoded string
+	}
+
+	solution struct {
+		Num  int
+		Grid template.HTML
+	}
+)
+
+// render solves each input puzzle and displays the puzzles and their solutions as HTML in the default browser, or writes them to a PDF booklet.
+func render(args []string) error {
+	var (
+		input  inputs
+		files  htmlFlags
+		pdf    pdfFlags
+		layout layoutFlags
+	)
+
+	fs := newFlagSet("render", "[puzzle ...]", "Display puzzles and their solutions as HTML in the default browser, write them to files with -o, -svg, -png, or -jpeg, or write them to a PDF booklet with -pdf. Puzzles without a single solution are skipped.")
+	fs.Var(&input, "i", inputUsage)
+	files.register(fs)
+	pdf.register(fs)
+	layout.register(fs, false)
+	fs.Parse(args)
+
+	if err := files.check(); err != nil {
+		return err
+	}
+
+	var games []*generator.Game
+	if err := eachPuzzle(input, fs.Args(), func(line string) error {
+		var (
+			g   *generator.Game
+			err error
+		)
+		if layout.variant(line) {
+			g, err = solveLayoutGame(line, &layout)
+		} else {
+			g, err = solveGame(line)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s; skipping\n", line, err)
+			return nil
+		}
+
+		games = append(games, g)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if len(games) == 0 {
+		return errors.New("no puzzles to render")
+	}
+
+	for _, g := range games {
+		if !g.Puzzle.Standard() {
+			if err := layoutOutput(&files, &pdf); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	if pdf.file != "" {
+		return pdf.write(games)
+	}
+
+	return files.output(games)
+}
+
+// solveGame builds a game for an encoded standard puzzle (see newGame).
+func solveGame(line string) (*generator.Game, error) {
+	grid, err := generator.ParseEncoded(line)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGame(grid)
+}
+
+// newGame builds a game for a puzzle, rating it with the logical strategies and finding its solution by search.
+func newGame(grid *generator.Grid) (*generator.Game, error) {
+	if !grid.Valid() {
+		return nil, errors.New("grid is invalid")
+	}
+
+	cp := *grid
+	strategies := make(map[string]bool)
+	level, _ := cp.Reduce(true, &strategies, 0)
+
+	solutions := make([]*generator.Grid, 0, 2)
+	grid.Search(&solutions)
+	if len(solutions) != 1 {
+		return nil, errors.New(solutionCount(len(solutions), 2))
+	}
+
+	return &generator.Game{
+		Level:      level,
+		Clues:      grid.Clues(),
+		Strategies: strategyNames(strategies),
+		Puzzle:     grid,
+		Solution:   solutions[0],
+	}, nil
+}
+
+// register adds the HTML output flags to a flag set.
+func (f *htmlFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.file, "o", "", "write the HTML booklet to `file` instead of opening it in the default browser")
+	fs.StringVar(&f.dir, "svg", "", "write the SVG of each puzzle and solution to `dir` as puzzle-001.svg, solution-001.svg, and so on, instead of opening the default browser")
+	fs.StringVar(&f.png, "png", "", "write a PNG image of each puzzle and solution to `dir`, named like the SVG files")
+	fs.StringVar(&f.jpeg, "jpeg", "", "write a JPEG image of each puzzle and solution to `dir`, named like the SVG files")
+	fs.StringVar(&f.steps, "steps", "", "write a step-by-step walkthrough of solving each puzzle to `dir` as walkthrough-001.html, and so on, with a diagram of every step")
+	fs.StringVar(&f.play, "play", "", "write a page for playing each puzzle offline in a browser to `dir` as play-001.html, and so on, with pencil marks, undo, checking, and hints")
+	fs.BoolVar(&f.style.Dark, "dark", false, "draw HTML and SVG grids in light colours on a dark background")
+	fs.BoolVar(&f.style.Labels, "labels", false, "label the rows A - I and the columns 1 - 9 of HTML and SVG grids")
+	fs.BoolVar(&f.style.Classes, "classes", false, "style HTML and SVG grids with CSS classes (sudoku-given, sudoku-thick, and so on) instead of inline styles")
+	fs.StringVar(&f.style.Font, "font", "", "CSS font `family` of the digits in HTML and SVG grids (default sans-serif)")
+	fs.Float64Var(&f.dpi, "dpi", 96, fmt.Sprintf("resolution of PNG and JPEG images in `dots` per inch, at most %d (96 gives 500 x 500 pixels)", generator.MaxDPI))
+}
+
+// check returns an error if the flags cannot be used, before any puzzles are worked on.
+func (f *htmlFlags) check() error {
+	if !(f.dpi <= generator.MaxDPI) {
+		return fmt.Errorf("-dpi can be at most %d", generator.MaxDPI)
+	}
+
+	return nil
+}
+
+// set reports whether the flags name any files to write.
+func (f *htmlFlags) set() bool {
+	return f.file != "" || f.dir != "" || f.png != "" || f.jpeg != "" || f.steps != "" || f.play != ""
+}
+
+// output writes games to the files named by the flags or, if there are none, displays them as an HTML booklet in the default browser.
+func (f *htmlFlags) output(games []*generator.Game) error {
+	if !f.set() {
+		b, err := f.html(games)
+		if err != nil {
+			return err
+		}
+		return browser.OpenReader(strings.NewReader(b))
+	}
+
+	if f.file != "" {
+		b, err := f.html(games)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(f.file, []byte(b), 0644); err != nil {
+			return err
+		}
+	}
+
+	if f.dir != "" {
+		if err := writeGrids(f.dir, "svg", games, func(w io.Writer, g *generator.Grid) error {
+			_, err := io.WriteString(w, g.SVGWith(f.style, false, nil))
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	if f.png != "" {
+		if err := writeGrids(f.png, "png", games, func(w io.Writer, g *generator.Grid) error {
+			return g.WritePNG(w, f.dpi, false, nil)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if f.jpeg != "" {
+		if err := writeGrids(f.jpeg, "jpg", games, func(w io.Writer, g *generator.Grid) error {
+			return g.WriteJPEG(w, f.dpi, 90, false, nil)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if f.steps != "" {
+		if err := writePages(f.steps, "walkthrough", games, func(w io.Writer, i int, g *generator.Game) error {
+			return g.Puzzle.WriteWalkthrough(w, fmt.Sprintf("Puzzle %d (%s)", i+1, g.Level), f.style)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if f.play != "" {
+		if err := writePages(f.play, "play", games, func(w io.Writer, i int, g *generator.Game) error {
+			return g.WritePlayable(w, fmt.Sprintf("Puzzle %d (%s)", i+1, g.Level))
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePages creates dir if necessary and writes a page for each game to it using write, in files named name-001.html, name-002.html, and so on.
+func writePages(dir, name string, games []*generator.Game, write func(w io.Writer, i int, g *generator.Game) error) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for i, game := range games {
+		file, err := os.Create(filepath.Join(dir, fmt.Sprintf("%s-%03d.html", name, i+1)))
+		if err != nil {
+			return err
+		}
+		err = write(file, i, game)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeGrids creates dir if necessary and writes the puzzle and solution of each game to it using write, in files named puzzle-001.ext, solution-001.ext, and so on.
+func writeGrids(dir, ext string, games []*generator.Game, write func(w io.Writer, g *generator.Grid) error) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for i, game := range games {
+		for _, f := range []struct {
+			name string
+			grid *generator.Grid
+		}{{"puzzle", game.Puzzle}, {"solution", game.Solution}} {
+			file, err := os.Create(filepath.Join(dir, fmt.Sprintf("%s-%03d.%s", f.name, i+1, ext)))
+			if err != nil {
+				return err
+			}
+			err = write(file, f.grid)
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// html returns an HTML booklet of games in the style chosen by the flags: each puzzle with its level, a QR code and its encoding, followed by the solutions, printed upside down.
+func (f *htmlFlags) html(games []*generator.Game) (string, error) {
+	puzzleStyle, solutionStyle := f.style, f.style
+	puzzleStyle.Scale = 0.8
+	solutionStyle.Scale, solutionStyle.Invert = 0.3, true
+
+	puzzles := make([]puzzle, 0, len(games))
+	solutions := make([]solution, 0, len(games))
+
+	for i, g := range games {
+		// The encodings of puzzles larger than 9 x 9 use letters for the digits above 9, which need the alphanumeric mode of MakeSegments.
+		segs := append([]*qrcodegen.QRSegment{qrcodegen.MakeAlphanumeric("SUDOKU://")}, qrcodegen.MakeSegments(g.Puzzle.Encode())...)
+		qrCode, err := qrcodegen.EncodeSegments(segs, qrcodegen.Low)
+		if err != nil {
+			return "", err
+		}
+		svg, err := qrCode.ToSVGString(4, false)
+		if err != nil {
+			return "", err
+		}
+
+		puzzles = append(puzzles, puzzle{i + 1, g.Level, i%2 == 1, template.HTML(g.Puzzle.SVGWith(puzzleStyle, false, nil)), template.HTML(svg), g.Puzzle.Encode()})
+		solutions = append(solutions, solution{i + 1, template.HTML(g.Solution.SVGWith(solutionStyle, false, nil))})
+	}
+
+	t := template.Must(template.New("html").Parse(`
+		<!DOCTYPE html>
+		<html lang="en">
+		<head>
+			<meta charset="UTF-8">
+			<meta name="viewport" content="width=device-width, initial-scale=1.0">
+			<title>Sudoku</title>
+
+			<style>
+				.break { page-break-after: always; }
+				.puzzle {
+					display: grid;
+					grid-template: 40vh / 80% 20%;
+					column-gap: 10px;
+					justify-items: center;
+					align-items: stretch;
+				}
+				.small svg {
+					height: 100%;
+					width: 100%;
+				}
+				.small-font {
+					font-size: 0.8em;
+				}
+				.solutions {
+					display: flex;
+					flex-direction: row;
+					flex-wrap: wrap;
+					justify-content: space-between;
+					align-items: flex-start;
+				}
+			</style>
+		</head>
+		<body>
+			{{ range .Puzzles }}
+				<div {{ if .Break }}class="break"{{ end }} style="page-break-inside: avoid;">
+					<h2>{{ .Num }} {{ .Level }}</h2>
+					<div class="puzzle">
+						<div>{{ .Grid }}</div>
+						<div class="small">{{ .QRCode }}</div>
+					</div>
+					<p class="small-font">Encoded: {{ .Encoded }}</p>
+				</div>
+			{{ end }}
+			<p class="break"></p>
+			<div class="solutions">
+				{{ range .Solutions }}
+					<div style="page-break-inside: avoid;">
+						<h4>{{ .Num }}</h4>
+						<p>{{ .Grid }}</p>
+					</div>
+				{{ end }}
+			</div>
+		</body>
+		</html>
+	`))
+
+	var b strings.Builder
+	if err := t.Execute(&b, struct {
+		Puzzles   []puzzle
+		Solutions []solution
+	}{puzzles, solutions}); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
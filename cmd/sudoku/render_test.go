@@ -0,0 +1,98 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderJigsawSVG(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, render([]string{"-regions", "aaabcabbccdbcddd", "-svg", dir, "0030000000041000"}))
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "puzzle-001.svg"))
+	assert.NoError(t, err)
+	svg := string(b)
+	assert.Contains(t, svg, `<line x1="175" y1="25" x2="175" y2="75"`) // Between regions 1 and 2 in the first row.
+	assert.NotContains(t, svg, `<line x1="125" y1="25" x2="125" y2="75"`)
+
+	_, err = os.Stat(filepath.Join(dir, "solution-001.svg"))
+	assert.NoError(t, err)
+
+	err = render([]string{"-regions", "aaabcabbccdbcddd", "-png", dir, "0030000000041000"})
+	assert.EqualError(t, err, "-png, -jpeg, -steps, -play and -pdf support only standard 9 x 9 puzzles")
+}
+
+func TestRenderKillerSVG(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, render([]string{"-cages", "aabbacbbbcacbaaa:9,10,5,4,10,2", "-svg", dir, "0000200000003000"}))
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "puzzle-001.svg"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "stroke-dasharray")
+	assert.Contains(t, string(b), ">10</text>")
+}
+
+func TestRenderSamuraiSVG(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A Samurai puzzle is recognised by the length of its encoding.
+	const samurai = "206009100005102400090000000008750000000504000400000080804900003000000020900000000000201003005001900064000000007000000300000000000000030060000000000007500100000015000069000000000000700008160050721000040000000080000000007020000040000600012000000087000009000000005000000800000027000610700400900000800050908700280106000090002000000001040400001000000400008100000690107008050"
+	assert.NoError(t, render([]string{"-svg", dir, samurai}))
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "solution-001.svg"))
+	assert.NoError(t, err)
+	svg := string(b)
+	assert.Contains(t, svg, `<svg width="1100" height="1100"`)
+	assert.Equal(t, 369, strings.Count(svg, "</text>"))
+	assert.NotContains(t, svg, `<rect x="475" y="25" width="50" height="50"`) // Row 0, column 9 lies between the top grids.
+}
+
+func TestRenderDPI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.EqualError(t, render([]string{"-dpi", "100000", "-png", dir, servePuzzle}), "-dpi can be at most 600")
+	assert.EqualError(t, generate([]string{"-dpi", "601", "-png", dir, "-0", "1"}), "-dpi can be at most 600")
+
+	assert.NoError(t, render([]string{"-dpi", "48", "-png", dir, servePuzzle}))
+	_, err = os.Stat(filepath.Join(dir, "puzzle-001.png"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,456 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+type (
+	// pool runs jobs on a fixed number of goroutines, like the workers of generate, with a bounded queue of jobs waiting for them so that a burst of requests cannot start an unbounded amount of work.
+	pool struct {
+		jobs chan func()
+	}
+
+	// server answers the requests of the HTTP API.
+	server struct {
+		pool     *pool
+		limit    int
+		maxGames int
+	}
+
+	// apiRequest is the JSON body of a request. Each endpoint uses the fields that it needs.
+	apiRequest struct {
+		Level      string  `json:"level"`      // Level is the level of the puzzles to generate (easy, standard, hard, or expert).
+		Count      int     `json:"count"`      // Count is the number of puzzles to generate (default 1).
+		Puzzle     string  `json:"puzzle"`     // Puzzle is a standard 9 x 9 puzzle encoded as 81 digits, with '.' or '0' for empty cells.
+		Values     string  `json:"values"`     // Values are the 81 digits entered so far, with 0 for empty cells, to hint from instead of the puzzle.
+		Format     string  `json:"format"`     // Format is the format of a rendering: svg (the default), png, jpeg, html, play, or steps.
+		Candidates bool    `json:"candidates"` // Candidates shows the candidates of unsolved cells in SVG and raster renderings.
+		Solution   bool    `json:"solution"`   // Solution renders the solution instead of the puzzle.
+		Dark       bool    `json:"dark"`       // Dark draws light digits on a dark background.
+		Labels     bool    `json:"labels"`     // Labels labels the rows and columns.
+		DPI        float64 `json:"dpi"`        // DPI is the resolution of PNG and JPEG renderings (default 96).
+	}
+
+	// hintResponse describes the next step towards solving a puzzle, or the entered digits that are wrong.
+	hintResponse struct {
+		Wrong    []generator.Position  `json:"wrong,omitempty"`
+		Solved   bool                  `json:"solved"`
+		Strategy string                `json:"strategy,omitempty"`
+		Level    *generator.Level      `json:"level,omitempty"`
+		Notes    []string              `json:"notes,omitempty"`
+		Pattern  []generator.Position  `json:"pattern,omitempty"`
+		Removed  []generator.Placement `json:"removed,omitempty"`
+		Links    []generator.Link      `json:"links,omitempty"`
+		SVG      string                `json:"svg,omitempty"`
+	}
+)
+
+var errBusy = errors.New("the server is busy; try again later")
+
+// maxSolutions caps the -n flag of serve, since counting every solution of a puzzle with few givens, as -n 0 does for the CLI, could take any client's request almost forever.
+const maxSolutions = 1000
+
+// serve runs the HTTP API, which generates, solves, rates, hints at, and renders puzzles for other programs.
+func serve(args []string) error {
+	var (
+		addr    string
+		grpc    string
+		workers int
+		queue   int
+		s       server
+	)
+
+	fs := newFlagSet("serve", "", "Serve a JSON API over HTTP for generating, solving, rating, hinting at, and rendering puzzles. Each endpoint takes a JSON object in the body of a POST request: /generate (level, count), /solve (puzzle), /rate (puzzle), /hint (puzzle, values), and /render (puzzle, format, candidates, solution, dark, labels, dpi).")
+	fs.StringVar(&addr, "addr", "localhost:8080", "`address` to listen on; the default accepts only local connections")
+	fs.StringVar(&grpc, "grpc", "", "also serve the gRPC service of api/sudoku.proto on `address`, such as localhost:9090")
+	fs.IntVar(&workers, "j", runtime.NumCPU(), "number of requests to work on in `parallel`")
+	fs.IntVar(&queue, "queue", 64, "number of requests that may wait for a worker before the server answers 503")
+	fs.IntVar(&s.limit, "n", 2, fmt.Sprintf("stop counting the solutions of an unsolved puzzle at `count` (1 - %d)", maxSolutions))
+	fs.IntVar(&s.maxGames, "max", 10, "maximum `count` of puzzles generated by one request")
+	generator.AttemptsFlag(fs)
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if s.limit < 1 || s.limit > maxSolutions {
+		return fmt.Errorf("-n must be between 1 and %d", maxSolutions)
+	}
+
+	s.pool = newPool(workers, queue)
+
+	hs := &http.Server{
+		Addr:         addr,
+		Handler:      s.routes(),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 5 * time.Minute, // Generating expert puzzles can take a while.
+		IdleTimeout:  2 * time.Minute,
+	}
+	errs := make(chan error, 2)
+	go func() {
+		log.Printf("serving on http://%s", addr)
+		errs <- hs.ListenAndServe()
+	}()
+	if grpc != "" {
+		go func() {
+			log.Printf("serving gRPC on %s", grpc)
+			errs <- s.serveGRPC(grpc)
+		}()
+	}
+
+	return <-errs
+}
+
+// newPool starts workers goroutines that run the jobs of the pool, of which at most queue may wait.
+func newPool(workers, queue int) *pool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	p := &pool{make(chan func(), queue)}
+	for w := 0; w < workers; w++ {
+		go func() {
+			for job := range p.jobs {
+				job()
+			}
+		}()
+	}
+
+	return p
+}
+
+// run runs f on a worker of the pool and returns its error. It returns errBusy at once if the queue is full, and the error of ctx if the request is abandoned first; f is skipped if it has not started by then.
+func (p *pool) run(ctx context.Context, f func() error) error {
+	done := make(chan error, 1)
+	job := func() {
+		if ctx.Err() != nil {
+			done <- ctx.Err()
+			return
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("internal error: %v", r)
+			}
+		}()
+		done <- f()
+	}
+
+	select {
+	case p.jobs <- job:
+	default:
+		return errBusy
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// routes returns the handler of the API.
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generate", s.handle(s.generate))
+	mux.HandleFunc("/solve", s.handle(s.solve))
+	mux.HandleFunc("/rate", s.handle(s.rate))
+	mux.HandleFunc("/hint", s.handle(s.hint))
+	mux.HandleFunc("/render", s.handleRender)
+
+	return mux
+}
+
+// handle adapts an endpoint that turns a request into a JSON response into a handler, running it on the pool and reporting its errors as JSON.
+func (s *server) handle(endpoint func(req *apiRequest) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeRequest(w, r)
+		if !ok {
+			return
+		}
+
+		var res interface{}
+		if err := s.pool.run(r.Context(), func() (err error) {
+			res, err = endpoint(req)
+			return
+		}); err != nil {
+			fail(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}
+}
+
+// decodeRequest reads the JSON body of a POST request, answering the request with an error if it cannot.
+func decodeRequest(w http.ResponseWriter, r *http.Request) (*apiRequest, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "use POST with a JSON body")
+		return nil, false
+	}
+
+	var req apiRequest
+	d := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "bad request: "+err.Error())
+		return nil, false
+	}
+	if _, err := d.Token(); err != io.EOF {
+		writeError(w, http.StatusBadRequest, "bad request: the body must hold a single JSON object")
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// badRequest is an error in the content of a request, as opposed to a failure of the server.
+type badRequest string
+
+func (e badRequest) Error() string {
+	return string(e)
+}
+
+// fail answers a request with the status that suits err.
+func fail(w http.ResponseWriter, err error) {
+	var bad badRequest
+	switch {
+	case errors.As(err, &bad):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errBusy):
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusServiceUnavailable, "request abandoned: "+err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// writeError answers a request with a JSON object holding an error message.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{message})
+}
+
+// generate generates puzzles at a level, one after another on a single worker of the pool.
+func (s *server) generate(req *apiRequest) (interface{}, error) {
+	level, count, err := s.generateRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	games := make([]*record, 0, count)
+	for i := 0; i < count; i++ {
+		if g := generateGame(level); g != nil {
+			games = append(games, gameRecord(g))
+		}
+	}
+	if len(games) == 0 {
+		return nil, fmt.Errorf("could not generate a %s puzzle", level)
+	}
+
+	return games, nil
+}
+
+// generateRequest checks the level and count of a request to generate puzzles.
+func (s *server) generateRequest(req *apiRequest) (generator.Level, int, error) {
+	level, err := generator.ParseLevel(req.Level)
+	if err != nil {
+		return level, 0, badRequest(err.Error())
+	}
+	if level > generator.Expert {
+		return level, 0, badRequest("puzzles can be generated at the easy, standard, hard, and expert levels")
+	}
+
+	count := req.Count
+	if count <= 0 {
+		count = 1
+	}
+	if count > s.maxGames {
+		return level, 0, badRequest(fmt.Sprintf("at most %d puzzles can be generated at once", s.maxGames))
+	}
+
+	return level, count, nil
+}
+
+// generateGame generates a puzzle at level in the same way as the workers of the generate command, returning nil if it gives up.
+func generateGame(level generator.Level) *generator.Game {
+	tasks := make(chan generator.Level, 1)
+	results := make(chan *generator.Game, 1)
+	tasks <- level
+	close(tasks)
+	generator.Worker(tasks, results)
+
+	return <-results
+}
+
+// solve solves a puzzle with the logical strategies, falling back to a search, and suggests repair givens for a puzzle with more than one solution.
+func (s *server) solve(req *apiRequest) (interface{}, error) {
+	r := solveRecord(req.Puzzle, s.limit, true)
+	if r.Error != "" {
+		return nil, badRequest(r.Error)
+	}
+
+	return r, nil
+}
+
+// rate reports the level and strategies needed to solve a puzzle, without its solution.
+func (s *server) rate(req *apiRequest) (interface{}, error) {
+	r := solveRecord(req.Puzzle, s.limit, false)
+	if r.Error != "" {
+		return nil, badRequest(r.Error)
+	}
+	r.Solution = ""
+
+	return r, nil
+}
+
+// hint describes the next step that the logical strategies take from the digits entered so far, or lists the entered digits that do not match the solution.
+func (s *server) hint(req *apiRequest) (interface{}, error) {
+	game, err := solveGame(req.Puzzle)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	grid := game.Puzzle
+	if req.Values != "" {
+		if grid, err = generator.ParseEncoded(req.Values); err != nil {
+			return nil, badRequest("values: " + err.Error())
+		}
+	}
+
+	res := &hintResponse{}
+	values, solution := grid.Values(), game.Solution.Values()
+	for i := range values {
+		if values[i] != '0' && values[i] != solution[i] {
+			res.Wrong = append(res.Wrong, generator.Position{Row: i / 9, Col: i % 9})
+		}
+	}
+	if len(res.Wrong) > 0 || values == solution {
+		res.Solved = values == solution
+		return res, nil
+	}
+
+	steps, _ := grid.Steps()
+	if len(steps) == 0 {
+		return nil, errors.New("no strategy applies; the puzzle needs a search from here")
+	}
+
+	step := &steps[0]
+	res.Strategy, res.Level, res.Notes = step.Strategy, &step.Level, step.Notes
+	res.Pattern, res.Removed, res.Links = step.Pattern, step.Removed(), step.Links
+	res.SVG = step.SVG(generator.SVGOptions{Dark: req.Dark, Labels: req.Labels})
+
+	return res, nil
+}
+
+// handleRender renders a puzzle in the requested format, answering with the document itself rather than JSON.
+func (s *server) handleRender(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		b           bytes.Buffer
+		contentType string
+	)
+	if err := s.pool.run(r.Context(), func() (err error) {
+		contentType, err = renderOne(&b, req)
+		return
+	}); err != nil {
+		fail(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	b.WriteTo(w)
+}
+
+// renderOne writes one puzzle to w in the format of the request and returns its content type.
+func renderOne(w io.Writer, req *apiRequest) (string, error) {
+	game, err := solveGame(req.Puzzle)
+	if err != nil {
+		return "", badRequest(err.Error())
+	}
+
+	style := generator.SVGOptions{Dark: req.Dark, Labels: req.Labels}
+	grid := game.Puzzle
+	if req.Solution {
+		grid = game.Solution
+	}
+	dpi := req.DPI
+	if dpi <= 0 {
+		dpi = 96
+	}
+	if !(dpi <= generator.MaxDPI) {
+		return "", badRequest(fmt.Sprintf("dpi can be at most %d", generator.MaxDPI))
+	}
+
+	switch strings.ToLower(req.Format) {
+	case "", "svg":
+		_, err = io.WriteString(w, grid.SVGWith(style, req.Candidates, nil))
+		return "image/svg+xml", err
+	case "png":
+		return "image/png", grid.WritePNG(w, dpi, req.Candidates, nil)
+	case "jpeg", "jpg":
+		return "image/jpeg", grid.WriteJPEG(w, dpi, 90, req.Candidates, nil)
+	case "html":
+		f := htmlFlags{style: style}
+		page, err := f.html([]*generator.Game{game})
+		if err != nil {
+			return "", err
+		}
+		_, err = io.WriteString(w, page)
+		return "text/html; charset=utf-8", err
+	case "play":
+		return "text/html; charset=utf-8", game.WritePlayable(w, fmt.Sprintf("Sudoku (%s)", game.Level))
+	case "steps":
+		return "text/html; charset=utf-8", game.Puzzle.WriteWalkthrough(w, fmt.Sprintf("Sudoku (%s)", game.Level), style)
+	}
+
+	return "", badRequest(fmt.Sprintf("unknown format %q; use svg, png, jpeg, html, play, or steps", req.Format))
+}
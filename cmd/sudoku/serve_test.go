@@ -0,0 +1,249 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dogdaze.org/sudoku/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	servePuzzle   = "050700080103090000000000009200000300000380406001002070000040000035070900706000230"
+	serveSolution = "659724183173698542824135769268457391597381426341962875912843657435276918786519234"
+)
+
+// post sends a POST request with a JSON body to the handler of s.
+func post(s *server, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return w
+}
+
+// errorOf returns the message of a JSON error response.
+func errorOf(w *httptest.ResponseRecorder) string {
+	var res struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res.Error
+}
+
+func newTestServer() *server {
+	return &server{pool: newPool(2, 4), limit: 2, maxGames: 3}
+}
+
+func TestServeGenerate(t *testing.T) {
+	s := newTestServer()
+
+	w := post(s, "/generate", `{"level": "easy", "count": 2}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var games []record
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &games))
+	assert.Len(t, games, 2)
+	for _, g := range games {
+		assert.Equal(t, generator.Easy, g.Level)
+		assert.Len(t, g.Solution, 81)
+	}
+
+	w = post(s, "/generate", `{"level": "bogus"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `unknown level "bogus"`, errorOf(w))
+
+	w = post(s, "/generate", `{"level": "extreme"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post(s, "/generate", `{"level": "easy", "count": 4}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "at most 3 puzzles can be generated at once", errorOf(w))
+}
+
+func TestServeSolve(t *testing.T) {
+	s := newTestServer()
+
+	w := post(s, "/solve", `{"puzzle": "`+servePuzzle+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var r record
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+	assert.True(t, r.Solved)
+	assert.Equal(t, 1, r.Solutions)
+	assert.Equal(t, serveSolution, r.Solution)
+
+	// Without two of its givens the puzzle has several solutions, which are counted up to the limit, and a repair is suggested.
+	w = post(s, "/solve", `{"puzzle": "`+strings.Repeat("0", 27)+servePuzzle[27:]+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	r = record{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+	assert.Equal(t, 2, r.Solutions)
+	assert.Empty(t, r.Solution)
+	assert.NotEmpty(t, r.Repaired)
+
+	w = post(s, "/solve", `{"puzzle": "123"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotEmpty(t, errorOf(w))
+
+	w = post(s, "/solve", `{"puzzle": "`+servePuzzle+`", "colour": "blue"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, errorOf(w), "unknown field")
+
+	w = post(s, "/solve", `{"puzzle": `)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	for _, trailing := range []string{`{}`, `{"puzzle": "123"}`, `x`, `]`} {
+		w = post(s, "/solve", `{"puzzle": "`+servePuzzle+`"} `+trailing)
+		assert.Equal(t, http.StatusBadRequest, w.Code, trailing)
+		assert.Equal(t, "bad request: the body must hold a single JSON object", errorOf(w), trailing)
+	}
+	w = post(s, "/solve", `{"puzzle": "`+servePuzzle+`"}`+"\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = post(s, "/solve", `{"puzzle": "`+strings.Repeat("1", 1<<16)+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	s.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/solve", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, http.MethodPost, w.Header().Get("Allow"))
+}
+
+func TestServeRate(t *testing.T) {
+	s := newTestServer()
+
+	w := post(s, "/rate", `{"puzzle": "`+servePuzzle+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var r record
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+	assert.Equal(t, generator.Easy, r.Level)
+	assert.NotEmpty(t, r.Strategies)
+	assert.Empty(t, r.Solution)
+
+	w = post(s, "/rate", `{"puzzle": "`+strings.Repeat("1", 81)+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "grid is invalid", errorOf(w))
+}
+
+func TestServeHint(t *testing.T) {
+	s := newTestServer()
+
+	w := post(s, "/hint", `{"puzzle": "`+servePuzzle+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var h hintResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &h))
+	assert.False(t, h.Solved)
+	assert.NotEmpty(t, h.Strategy)
+	assert.Contains(t, h.SVG, "<svg")
+
+	// The first cell is empty in the puzzle and holds 6 in the solution.
+	w = post(s, "/hint", `{"puzzle": "`+servePuzzle+`", "values": "1`+servePuzzle[1:]+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	h = hintResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &h))
+	assert.Equal(t, []generator.Position{{Row: 0, Col: 0}}, h.Wrong)
+	assert.Empty(t, h.Strategy)
+
+	w = post(s, "/hint", `{"puzzle": "`+servePuzzle+`", "values": "`+serveSolution+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	h = hintResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &h))
+	assert.True(t, h.Solved)
+
+	w = post(s, "/hint", `{"puzzle": "`+servePuzzle+`", "values": "12"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.True(t, strings.HasPrefix(errorOf(w), "values: "))
+
+	w = post(s, "/hint", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServeRender(t *testing.T) {
+	s := newTestServer()
+
+	w := post(s, "/render", `{"puzzle": "`+servePuzzle+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `<svg width="500" height="500"`)
+
+	w = post(s, "/render", `{"puzzle": "`+servePuzzle+`", "format": "png", "dpi": 48}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "\x89PNG"))
+
+	for _, format := range []string{"html", "play", "steps"} {
+		w = post(s, "/render", `{"puzzle": "`+servePuzzle+`", "format": "`+format+`"}`)
+		assert.Equal(t, http.StatusOK, w.Code, format)
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"), format)
+	}
+
+	w = post(s, "/render", `{"puzzle": "`+servePuzzle+`", "format": "gif"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	w = post(s, "/render", `{"puzzle": "`+servePuzzle+`", "format": "png", "dpi": 601}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "dpi can be at most 600", errorOf(w))
+
+	w = post(s, "/render", `{"puzzle": "bad"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServeBusy(t *testing.T) {
+	s := &server{pool: newPool(1, 1), limit: 2, maxGames: 1}
+
+	// Occupy the only worker and then the only place in the queue.
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	go s.pool.run(context.Background(), func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	go s.pool.run(context.Background(), func() error {
+		return nil
+	})
+	for len(s.pool.jobs) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, path := range []string{"/generate", "/solve", "/rate", "/hint", "/render"} {
+		w := post(s, path, `{"level": "easy", "puzzle": "`+servePuzzle+`"}`)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, path)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"), path)
+		assert.Equal(t, errBusy.Error(), errorOf(w), path)
+	}
+}
+
+func TestServeLimit(t *testing.T) {
+	assert.EqualError(t, serve([]string{"-n", "0"}), "-n must be between 1 and 1000")
+	assert.EqualError(t, serve([]string{"-n", "1001"}), "-n must be between 1 and 1000")
+}
@@ -0,0 +1,407 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+// solve reduces each input puzzle using logical strategies, displaying the grid before and after, and optionally falls back to a brute-force search.
+func solve(args []string) error {
+	var (
+		input      inputs
+		layout     layoutFlags
+		bruteForce bool
+		format     string
+		limit      int
+		verbose    uint
+		workers    int
+	)
+
+	fs := newFlagSet("solve", "[puzzle ...]", "Solve puzzles using logical strategies, displaying each grid before and after.")
+	fs.Var(&input, "i", inputUsage)
+	fs.BoolVar(&bruteForce, "b", false, "use brute force search to solve")
+	fs.IntVar(&limit, "n", 2, "stop the brute force search after `count` solutions; 0 finds them all")
+	fs.StringVar(&format, "format", "", "emit one machine-readable record per puzzle in `format` (json, jsonl, or csv) instead of text")
+	fs.UintVar(&verbose, "v", 0, "`verbosity` level; higher emits more messages (forces -j 1, and is ignored with -format so the messages cannot corrupt the records)")
+	fs.IntVar(&workers, "j", runtime.NumCPU(), "number of puzzles to solve in `parallel`")
+	layout.register(fs, false)
+	generator.ColorFlag(fs)
+	fs.Parse(args)
+
+	out, err := newRecordWriter(format, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	// Verbose messages are written directly to stdout by the strategies, so they can only be kept in order by solving one puzzle at a time, and they are not written at all between records.
+	if out != nil {
+		verbose = 0
+	}
+	if verbose > 0 {
+		workers = 1
+	}
+
+	sum := newSummary()
+	if err := batch(input, fs.Args(), workers, func(line string) *outcome {
+		if layout.variant(line) {
+			if out != nil {
+				return &outcome{rec: solveVariantRecord(line, &layout, limit)}
+			}
+
+			return solveVariantText(line, &layout, bruteForce, verbose, limit)
+		}
+
+		if out != nil {
+			return &outcome{rec: solveRecord(line, limit, bruteForce)}
+		}
+
+		return solveText(line, bruteForce, verbose, limit)
+	}, func(o *outcome) error {
+		sum.add(o.rec)
+		return emit(out, o)
+	}); err != nil {
+		return err
+	}
+
+	if out != nil {
+		if err := out.close(); err != nil {
+			return err
+		}
+		sum.print(os.Stderr)
+		return nil
+	}
+
+	sum.print(os.Stdout)
+	return nil
+}
+
+// solveText solves a puzzle, capturing the text that solve displays for it.
+func solveText(line string, bruteForce bool, verbose uint, limit int) *outcome {
+	o := &outcome{rec: &record{Encoded: line}}
+	w := &o.stdout
+
+	fmt.Fprintf(w, "Encoded: %s\n", line)
+
+	grid, err := generator.ParseEncoded(line)
+	if err != nil {
+		o.rec.Error = err.Error()
+		fmt.Fprintln(&o.stderr, err)
+		return o
+	}
+	grid.DisplayTo(w)
+
+	if !grid.Valid() {
+		o.rec.Error = "grid is invalid"
+		fmt.Fprintln(&o.stderr, o.rec.Error)
+		return o
+	}
+
+	if verbose > 0 { // Flush what we have so it precedes the messages from the strategies.
+		emit(nil, o)
+	}
+
+	orig := *grid
+	strategies := make(map[string]bool)
+	maxLevel, solved := grid.Reduce(true, &strategies, verbose)
+	names := strategyNames(strategies)
+	o.rec.Level, o.rec.Solved, o.rec.Strategies = maxLevel, solved, names
+
+	grid.DisplayTo(w)
+	if solved {
+		o.rec.Solutions = 1
+		fmt.Fprintf(w, "level: %s, solved, (%s)\n", maxLevel, strings.Join(names, ", "))
+		return o
+	}
+
+	fmt.Fprintf(w, "level: %s, not solved (%s)\n", maxLevel, strings.Join(names, ", "))
+	if bruteForce {
+		var solutions []*generator.Grid
+		grid.Solutions(limit, func(s *generator.Grid) bool {
+			solutions = append(solutions, s)
+			return true
+		})
+		o.rec.Solutions = len(solutions)
+		if len(solutions) == 0 {
+			fmt.Fprintf(w, "still not solved after search, (%s)\n", strings.Join(names, ", "))
+		} else {
+			fmt.Fprintf(w, "%s found, (%s)\n", solutionCount(len(solutions), limit), strings.Join(names, ", "))
+			for _, s := range solutions {
+				s.DisplayTo(w)
+			}
+		}
+
+		if len(solutions) > 1 {
+			if a, err := orig.Ambiguity(limit); err == nil {
+				explain(w, a)
+			}
+		}
+	}
+
+	return o
+}
+
+// rate reports the level and strategies needed to solve each input puzzle, one line per puzzle.
+func rate(args []string) error {
+	var (
+		input   inputs
+		layout  layoutFlags
+		format  string
+		limit   int
+		workers int
+	)
+
+	fs := newFlagSet("rate", "[puzzle ...]", "Rate puzzles by the hardest strategy needed to solve them, followed by a summary of the levels and strategies.")
+	fs.Var(&input, "i", inputUsage)
+	fs.StringVar(&format, "format", "", "emit one machine-readable record per puzzle in `format` (json, jsonl, or csv) instead of text")
+	fs.IntVar(&limit, "n", 2, "stop counting the solutions of an unsolved puzzle at `count`; 0 counts them all")
+	fs.IntVar(&workers, "j", runtime.NumCPU(), "number of puzzles to rate in `parallel`")
+	layout.register(fs, false)
+	fs.Parse(args)
+
+	out, err := newRecordWriter(format, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	sum := newSummary()
+	if err := batch(input, fs.Args(), workers, func(line string) *outcome {
+		o := &outcome{}
+		if layout.variant(line) {
+			o.rec = solveVariantRecord(line, &layout, limit)
+		} else {
+			o.rec = solveRecord(line, limit, false)
+		}
+		if out != nil {
+			return o
+		}
+
+		r := o.rec
+		switch {
+		case r.Error != "":
+			fmt.Fprintf(&o.stdout, "%s error: %s\n", r.Encoded, r.Error)
+		case r.Solved:
+			fmt.Fprintf(&o.stdout, "%s %s (%s)\n", r.Encoded, r.Level, strings.Join(r.Strategies, ", "))
+		default:
+			fmt.Fprintf(&o.stdout, "%s %s not solved, %s (%s)\n", r.Encoded, r.Level, solutionCount(r.Solutions, limit), strings.Join(r.Strategies, ", "))
+		}
+
+		return o
+	}, func(o *outcome) error {
+		sum.add(o.rec)
+		return emit(out, o)
+	}); err != nil {
+		return err
+	}
+
+	if out != nil {
+		if err := out.close(); err != nil {
+			return err
+		}
+		sum.print(os.Stderr)
+		return nil
+	}
+
+	sum.print(os.Stdout)
+	return nil
+}
+
+// validate checks that each input puzzle is well formed and has exactly one solution. It returns an error if any puzzle fails.
+func validate(args []string) error {
+	var (
+		input   inputs
+		layout  layoutFlags
+		minimal bool
+		quiet   bool
+		workers int
+	)
+
+	fs := newFlagSet("validate", "[puzzle ...]", "Check that puzzles are well formed, have no conflicting givens, and have exactly one solution.")
+	fs.Var(&input, "i", inputUsage)
+	fs.BoolVar(&minimal, "m", false, "also require 9 x 9 puzzles to be minimal (no given can be removed)")
+	fs.BoolVar(&quiet, "q", false, "report only the puzzles that fail")
+	fs.IntVar(&workers, "j", runtime.NumCPU(), "number of puzzles to check in `parallel`")
+	layout.register(fs, false)
+	fs.Parse(args)
+
+	all := 0
+	bad := 0
+	if err := batch(input, fs.Args(), workers, func(line string) *outcome {
+		o := &outcome{rec: &record{Encoded: line}}
+
+		if layout.variant(line) {
+			o.rec.Error = validateVariant(line, &layout)
+		} else if grid, err := generator.ParseEncoded(line); err != nil {
+			o.rec.Error = err.Error()
+		} else if !grid.Valid() {
+			o.rec.Error = "conflicting givens"
+		} else {
+			if n := grid.CountSolutions(2); n != 1 {
+				o.rec.Error = solutionCount(n, 2)
+			} else if minimal {
+				if ok, err := grid.Minimal(); err != nil {
+					o.rec.Error = err.Error()
+				} else if !ok {
+					o.rec.Error = "not minimal"
+				}
+			}
+		}
+
+		if o.rec.Error != "" {
+			fmt.Fprintf(&o.stdout, "%s %s\n", line, o.rec.Error)
+		} else if !quiet {
+			fmt.Fprintf(&o.stdout, "%s ok\n", line)
+		}
+
+		return o
+	}, func(o *outcome) error {
+		all++
+		if o.rec.Error != "" {
+			bad++
+		}
+		return emit(nil, o)
+	}); err != nil {
+		return err
+	}
+
+	if bad > 0 {
+		return fmt.Errorf("%d of %d puzzles failed", bad, all)
+	}
+
+	return nil
+}
+
+// emit writes an outcome's record to out (if not nil) and its captured text to stdout and stderr, clearing the text.
+func emit(out recordWriter, o *outcome) error {
+	if out != nil {
+		if err := out.write(o.rec); err != nil {
+			return err
+		}
+	}
+
+	if _, err := o.stdout.WriteTo(os.Stdout); err != nil {
+		return err
+	}
+	_, err := o.stderr.WriteTo(os.Stderr)
+	return err
+}
+
+// explain describes the deadly patterns that make a puzzle ambiguous and the givens that would repair it.
+func explain(w io.Writer, a *generator.Ambiguity) {
+	cells := make([]string, len(a.Undetermined))
+	for i, p := range a.Undetermined {
+		cells[i] = p.String()
+	}
+	fmt.Fprintf(w, "cells that differ between solutions: %s\n", strings.Join(cells, ", "))
+
+	fmt.Fprintln(w, "deadly patterns (digit in one solution/digit in another):")
+	for _, p := range a.Patterns {
+		fmt.Fprintf(w, "\t%s\n", p)
+	}
+
+	givens := make([]string, len(a.Givens))
+	for i, p := range a.Givens {
+		givens[i] = p.String()
+	}
+	fmt.Fprintf(w, "add %d given(s) to make the puzzle unique: %s\n", len(a.Givens), strings.Join(givens, ", "))
+	fmt.Fprintf(w, "repaired: %s\n", a.Repaired.Encode())
+}
+
+// solveRecord parses, rates, and (if necessary) searches an encoded puzzle for up to limit solutions (all if limit is 0) without emitting any text. If repair is true and the puzzle has more than one solution, the record includes the puzzle with the fewest extra givens that make it unique.
+func solveRecord(line string, limit int, repair bool) *record {
+	r := &record{Encoded: line, Strategies: []string{}}
+
+	grid, err := generator.ParseEncoded(line)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Encoded = grid.Encode()
+	r.Clues = grid.Clues()
+
+	if !grid.Valid() {
+		r.Error = "grid is invalid"
+		return r
+	}
+
+	orig := *grid
+	strategies := make(map[string]bool)
+	r.Level, r.Solved = grid.Reduce(true, &strategies, 0)
+	r.Strategies = strategyNames(strategies)
+
+	if r.Solved {
+		r.Solutions = 1
+		r.Solution = grid.Values()
+		return r
+	}
+
+	// Search the original puzzle rather than the reduced grid so that the count reflects the puzzle itself.
+	r.Solutions = orig.Solutions(limit, func(s *generator.Grid) bool {
+		r.Solution = s.Values()
+		return true
+	})
+	if r.Solutions != 1 {
+		r.Solution = ""
+	}
+
+	if repair && r.Solutions > 1 {
+		if a, err := orig.Ambiguity(limit); err == nil {
+			r.Repaired = a.Repaired.Encode()
+		}
+	}
+
+	return r
+}
+
+// solutionCount describes a number of solutions found by a search that stopped at limit (if positive).
+func solutionCount(n, limit int) string {
+	switch {
+	case n == 0:
+		return "no solution"
+	case n == 1:
+		return "single solution"
+	case limit > 0 && n >= limit:
+		return fmt.Sprintf("at least %d solutions", n)
+	default:
+		return fmt.Sprintf("%d solutions", n)
+	}
+}
+
+func strategyNames(strategies map[string]bool) []string {
+	names := make([]string, 0, len(strategies))
+	for n := range strategies {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
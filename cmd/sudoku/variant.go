@@ -0,0 +1,376 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"strings"
+
+	"dogdaze.org/sudoku/generator"
+)
+
+// layoutFlags holds the flags that choose the layout of variant puzzles.
+type layoutFlags struct {
+	size     int
+	diagonal bool
+	regions  string
+	cages    string
+	killer   bool
+	rules    string
+	multi    string
+}
+
+// register adds the layout flags to a flag set. The -size flag is only added for generate, since the size of a puzzle that is read is known from its length.
+func (f *layoutFlags) register(fs *flag.FlagSet, generating bool) {
+	f.size = 9
+	if generating {
+		fs.IntVar(&f.size, "size", 9, "generate `size` x size puzzles (4, 6, 8, 9, 12, or 16)")
+	}
+	fs.StringVar(&f.multi, "layout", "", "use the multi-grid `layout` samurai or butterfly (puzzles of 369 characters are read as Samurai without it)")
+	fs.BoolVar(&f.diagonal, "x", false, "add the two main diagonals as extra units (X-Sudoku)")
+	fs.StringVar(&f.rules, "rules", "", "add the comma-separated extra `rules`: windoku, antiking, antiknight and nonconsecutive")
+	if generating {
+		fs.StringVar(&f.regions, "regions", "", "use the jigsaw regions in `map` (one character per cell; equal characters share a region), or random regions if map is \"random\"")
+		fs.BoolVar(&f.killer, "killer", false, "cover each puzzle with random killer cages")
+	} else {
+		fs.StringVar(&f.regions, "regions", "", "use the jigsaw regions in `map` (one character per cell; equal characters share a region)")
+		fs.StringVar(&f.cages, "cages", "", "use the killer cages in `spec`: a map with one character per cell ('.' for none; connected equal characters form a cage), ':', and the sums of the cages in order of their first cells separated by commas")
+	}
+}
+
+// extra reports whether the flags add any rules to the standard layout.
+func (f *layoutFlags) extra() bool {
+	return f.diagonal || f.regions != "" || f.cages != "" || f.killer || f.rules != "" || f.multi != ""
+}
+
+// variant reports whether an encoded puzzle needs a layout because it has extra rules or is not 9 x 9.
+func (f *layoutFlags) variant(line string) bool {
+	return f.extra() || len(line) != 81
+}
+
+// layout builds the layout for puzzles encoded in n characters, or of the -size flag if n is 0.
+func (f *layoutFlags) layout(n int) (*generator.Layout, error) {
+	var (
+		l   *generator.Layout
+		err error
+	)
+	switch {
+	case f.multi == "samurai":
+		l = generator.NewSamurai()
+	case f.multi == "butterfly":
+		l = generator.NewButterfly()
+	case f.multi != "":
+		err = fmt.Errorf("unknown layout %q", f.multi)
+	case n == 0:
+		l, err = generator.NewLayout(f.size)
+	default:
+		l, err = generator.LayoutOf(n)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if f.multi != "" && f.size != 9 {
+		return nil, fmt.Errorf("the %s layout is made of 9 x 9 grids", l)
+	}
+
+	if f.diagonal {
+		if len(l.Grids()) > 1 {
+			return nil, fmt.Errorf("the %s layout has no main diagonals", l)
+		}
+		l.AddDiagonals()
+	}
+
+	if f.rules != "" {
+		for _, r := range strings.Split(f.rules, ",") {
+			switch strings.TrimSpace(r) {
+			case "windoku":
+				err = l.AddWindows()
+			case "antiking":
+				l.AddAntiKing()
+			case "antiknight":
+				l.AddAntiKnight()
+			case "nonconsecutive":
+				l.AddNonConsecutive()
+			default:
+				err = fmt.Errorf("unknown rule %q", r)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if f.regions == "random" {
+		if f.regions, err = generator.RandomRegions(l.Size, rand.Int63()); err != nil {
+			return nil, err
+		}
+	}
+	if f.regions != "" {
+		if err := l.SetRegions(f.regions); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.cages != "" {
+		if err := l.SetCages(f.cages); err != nil {
+			return nil, err
+		}
+	}
+	if f.killer {
+		l.SetRandomCages()
+	}
+
+	return l, nil
+}
+
+// parse parses an encoded variant puzzle using the layout chosen by the flags.
+func (f *layoutFlags) parse(line string) (*generator.Grid, error) {
+	l, err := f.layout(len(line))
+	if err != nil {
+		return nil, err
+	}
+
+	return generator.ParseLayout(l, line)
+}
+
+// layoutOutput returns an error if the flags ask for output that only standard 9 x 9 puzzles support: HTML booklets and SVG files can be drawn for any layout, but images, walkthroughs, playable pages and PDF booklets cannot.
+func layoutOutput(files *htmlFlags, pdf *pdfFlags) error {
+	if files.png != "" || files.jpeg != "" || files.steps != "" || files.play != "" || pdf.file != "" {
+		return errors.New("-png, -jpeg, -steps, -play and -pdf support only standard 9 x 9 puzzles")
+	}
+
+	return nil
+}
+
+// solveLayoutGame builds a game for an encoded variant puzzle using the layout chosen by the flags (see newGame).
+func solveLayoutGame(line string, lf *layoutFlags) (*generator.Game, error) {
+	grid, err := lf.parse(line)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGame(grid)
+}
+
+// generateVariants creates puzzles on the layout chosen by the flags at the requested levels using one worker per CPU, writing them to out or, if out is nil, as text, and returns the games.
+func generateVariants(lf *layoutFlags, counts [4]int, out recordWriter) ([]*generator.Game, error) {
+	l, err := lf.layout(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if lf.regions != "" {
+		if out != nil {
+			fmt.Fprintf(os.Stderr, "regions: %s\n", l.Regions())
+		} else {
+			fmt.Printf("regions: %s\n", l.Regions())
+		}
+	}
+
+	numberOfWorkers := runtime.NumCPU()
+	numberOfTasks := 0
+	for _, c := range counts {
+		numberOfTasks += c
+	}
+
+	tasks := make(chan generator.Level, numberOfTasks)
+	results := make(chan *generator.Game, numberOfTasks)
+
+	for w := 0; w < numberOfWorkers; w++ {
+		go generator.LayoutWorker(l, tasks, results)
+	}
+
+	for lv, c := range counts {
+		for t := 0; t < c; t++ {
+			tasks <- generator.Level(lv)
+		}
+	}
+
+	close(tasks)
+
+	games := make([]*generator.Game, 0, numberOfTasks)
+	for t := 0; t < numberOfTasks; t++ {
+		g := <-results
+		if g == nil {
+			failed()
+			continue
+		}
+
+		games = append(games, g)
+		if out != nil {
+			if err := out.write(variantGameRecord(g)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fmt.Printf("%s %s (%d) %s\n", l, g.Level, g.Clues, strings.Join(g.Strategies, ", "))
+		fmt.Printf("%s\n", g.Puzzle.Encode())
+		if cages := g.Puzzle.Layout().EncodeCages(); cages != "" {
+			fmt.Printf("cages: %s\n", cages)
+		}
+		g.Puzzle.Display()
+		g.Solution.Display()
+	}
+	if len(games) == 0 && numberOfTasks > 0 {
+		return nil, errNoGames
+	}
+
+	return games, nil
+}
+
+// variantGameRecord builds a record for a generated variant game.
+func variantGameRecord(g *generator.Game) *record {
+	return &record{
+		Encoded:    g.Puzzle.Encode(),
+		Level:      g.Level,
+		Clues:      g.Clues,
+		Strategies: nonNil(g.Strategies),
+		Solved:     true,
+		Solutions:  1,
+		Solution:   g.Solution.Values(),
+		Cages:      g.Puzzle.Layout().EncodeCages(),
+	}
+}
+
+// solveVariantText solves a variant puzzle, capturing the text that solve displays for it.
+func solveVariantText(line string, lf *layoutFlags, bruteForce bool, verbose uint, limit int) *outcome {
+	o := &outcome{rec: &record{Encoded: line}}
+	w := &o.stdout
+
+	fmt.Fprintf(w, "Encoded: %s\n", line)
+
+	v, err := lf.parse(line)
+	if err != nil {
+		o.rec.Error = err.Error()
+		fmt.Fprintln(&o.stderr, err)
+		return o
+	}
+	v.DisplayTo(w)
+
+	if !v.Valid() {
+		o.rec.Error = "grid is invalid"
+		fmt.Fprintln(&o.stderr, o.rec.Error)
+		return o
+	}
+
+	if verbose > 0 { // Flush what we have so it precedes the messages from the strategies.
+		emit(nil, o)
+	}
+
+	orig := *v
+	strategies := make(map[string]bool)
+	maxLevel, solved := v.Reduce(true, &strategies, verbose)
+	names := strategyNames(strategies)
+	o.rec.Level, o.rec.Solved, o.rec.Strategies = maxLevel, solved, names
+
+	v.DisplayTo(w)
+	if solved {
+		o.rec.Solutions = 1
+		fmt.Fprintf(w, "level: %s, solved, (%s)\n", maxLevel, strings.Join(names, ", "))
+		return o
+	}
+
+	fmt.Fprintf(w, "level: %s, not solved (%s)\n", maxLevel, strings.Join(names, ", "))
+	if bruteForce {
+		var solutions []*generator.Grid
+		orig.Solutions(limit, func(s *generator.Grid) bool {
+			solutions = append(solutions, s)
+			return true
+		})
+		o.rec.Solutions = len(solutions)
+		if len(solutions) == 0 {
+			fmt.Fprintf(w, "still not solved after search, (%s)\n", strings.Join(names, ", "))
+		} else {
+			fmt.Fprintf(w, "%s found, (%s)\n", solutionCount(len(solutions), limit), strings.Join(names, ", "))
+			for _, s := range solutions {
+				s.DisplayTo(w)
+			}
+		}
+	}
+
+	return o
+}
+
+// solveVariantRecord is solveRecord for a variant puzzle. Ambiguous variants are not repaired.
+func solveVariantRecord(line string, lf *layoutFlags, limit int) *record {
+	r := &record{Encoded: line, Strategies: []string{}}
+
+	v, err := lf.parse(line)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Encoded = v.Encode()
+	r.Clues = v.Clues()
+
+	if !v.Valid() {
+		r.Error = "grid is invalid"
+		return r
+	}
+
+	orig := *v
+	strategies := make(map[string]bool)
+	r.Level, r.Solved = v.Reduce(true, &strategies, 0)
+	r.Strategies = strategyNames(strategies)
+
+	if r.Solved {
+		r.Solutions = 1
+		r.Solution = v.Values()
+		return r
+	}
+
+	r.Solutions = orig.Solutions(limit, func(s *generator.Grid) bool {
+		r.Solution = s.Values()
+		return true
+	})
+	if r.Solutions != 1 {
+		r.Solution = ""
+	}
+
+	return r
+}
+
+// validateVariant returns the reason a variant puzzle fails validation, or "" if it is well formed and has exactly one solution.
+func validateVariant(line string, lf *layoutFlags) string {
+	v, err := lf.parse(line)
+	switch {
+	case err != nil:
+		return err.Error()
+	case !v.Valid():
+		return "conflicting givens"
+	}
+
+	if n := v.CountSolutions(2); n != 1 {
+		return solutionCount(n, 2)
+	}
+
+	return ""
+}
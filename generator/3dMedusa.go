@@ -25,11 +25,11 @@
 package generator
 
 func (g *Grid) medusa(verbose uint) (res bool) {
-	var pairMaps [10]map[pair]bool
+	var pairMaps [maxSize + 1]map[pair]bool
 	g.unitPairs(&pairMaps)
 
 	strongLinks := make(map[pair]cell)
-	for d := 1; d <= 9; d++ {
+	for d := 1; d <= g.size(); d++ {
 		for p := range pairMaps[d] {
 			strongLinks[p] |= 1 << d
 		}
@@ -51,7 +51,7 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 		used[p.left] = true
 
 		digit := c.lowestSetBit()
-		var colors [rows][cols][10]color
+		var colors [maxRows][maxCols][maxSize + 1]color
 		colors[p.left.r][p.left.c][digit] = blue
 
 		g.colorGrid(digit, p.left, &colors, linkEnds, strongLinks, &used)
@@ -59,8 +59,8 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 		// Twice in a cell. If the same color appears twice in a cell, that color can be removed from the whole puzzle.
 		blueMoreThanOnce := false
 		redMoreThanOnce := false
-		for r := zero; r < rows; r++ {
-			for c := zero; c < cols; c++ {
+		for r := zero; r < g.height(); r++ {
+			for c := zero; c < g.width(); c++ {
 				blues := 0
 				reds := 0
 				for _, c := range colors[r][c] {
@@ -89,17 +89,11 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 		blueMoreThanOnce = false
 		redMoreThanOnce = false
 
-		b, r := g.groupColors(&box, &colors)
-		blueMoreThanOnce = blueMoreThanOnce || b
-		redMoreThanOnce = redMoreThanOnce || r
-
-		b, r = g.groupColors(&col, &colors)
-		blueMoreThanOnce = blueMoreThanOnce || b
-		redMoreThanOnce = redMoreThanOnce || r
-
-		b, r = g.groupColors(&row, &colors)
-		blueMoreThanOnce = blueMoreThanOnce || b
-		redMoreThanOnce = redMoreThanOnce || r
+		for _, gr := range g.Layout().groups {
+			b, r := g.groupColors(gr, &colors)
+			blueMoreThanOnce = blueMoreThanOnce || b
+			redMoreThanOnce = redMoreThanOnce || r
+		}
 
 		if blueMoreThanOnce {
 			g.removeColor(verbose, blue, &colors, "twice in a unit", &res)
@@ -112,11 +106,11 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 		}
 
 		// Two colors in a cell. If a cell contains digits that are colored both blue and red, any non-colored digits can be removed.
-		for r := zero; r < rows; r++ {
-			for c := zero; c < cols; c++ {
+		for r := zero; r < g.height(); r++ {
+			for c := zero; c < g.width(); c++ {
 				blueFound := 0
 				redFound := 0
-				for d := 1; d <= 9; d++ {
+				for d := 1; d <= g.size(); d++ {
 					switch colors[r][c][d] {
 					case blue:
 						blueFound |= 1 << d
@@ -126,7 +120,7 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 				}
 
 				if blueFound != 0 && redFound != 0 {
-					for d := 1; d <= 9; d++ {
+					for d := 1; d <= g.size(); d++ {
 						if blueFound&(1<<d) != 0 || redFound&(1<<d) != 0 {
 							continue
 						}
@@ -145,24 +139,24 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 
 		// Two colors elsewhere. In all cells C containing a digit X, if that cell can see a blue X and a red X, then X can be removed from the cell C.
 		// Mark the cells that see each blue and red digit.
-		var blueInfluence, redInfluence, immune [rows][cols][10]bool
-		for r := zero; r < rows; r++ {
-			for c := zero; c < cols; c++ {
-				for d := 1; d <= 9; d++ {
+		var blueInfluence, redInfluence, immune [maxRows][maxCols][maxSize + 1]bool
+		for r := zero; r < g.height(); r++ {
+			for c := zero; c < g.width(); c++ {
+				for d := 1; d <= g.size(); d++ {
 					switch colors[r][c][d] {
 					case blue:
 						immune[r][c][d] = true // Cells that are part of the 3d medusa are not eligible for removal.
-						coloredNeighbors(d, point{r, c}, &blueInfluence)
+						g.coloredNeighbors(d, point{r, c}, &blueInfluence)
 					case red:
 						immune[r][c][d] = true // Cells that are part of the 3d medusa are not eligible for removal.
-						coloredNeighbors(d, point{r, c}, &redInfluence)
+						g.coloredNeighbors(d, point{r, c}, &redInfluence)
 					}
 				}
 			}
 		}
-		for r := zero; r < rows; r++ {
-			for c := zero; c < cols; c++ {
-				for d := 1; d <= 9; d++ {
+		for r := zero; r < g.height(); r++ {
+			for c := zero; c < g.width(); c++ {
+				for d := 1; d <= g.size(); d++ {
 					if !immune[r][c][d] {
 						if blueInfluence[r][c][d] && redInfluence[r][c][d] {
 							if g.pt(point{r, c}).andNot(1 << d) {
@@ -179,12 +173,12 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 		}
 
 		// Two colors unit and cell. If a cell C containing a digit X can see another cell containing a colored X and in C there is a candidate with the opposite color, X can be removed from C.
-		for r := zero; r < rows; r++ {
-			for c := zero; c < cols; c++ {
+		for r := zero; r < g.height(); r++ {
+			for c := zero; c < g.width(); c++ {
 				blueFound := 0
 				redFound := 0
-				var immune [10]bool
-				for d := 1; d <= 9; d++ {
+				var immune [maxSize + 1]bool
+				for d := 1; d <= g.size(); d++ {
 					switch colors[r][c][d] {
 					case blue:
 						blueFound |= 1 << d
@@ -194,12 +188,12 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 						immune[d] = true
 					}
 				}
-				for d := 1; d <= 9; d++ {
-					if !immune[d] && blueFound != 0 && canSeeColor(d, point{r, c}, red, &colors) {
+				for d := 1; d <= g.size(); d++ {
+					if !immune[d] && blueFound != 0 && g.canSeeColor(d, point{r, c}, red, &colors) {
 						if g.pt(point{r, c}).andNot(1 << d) {
 							g.cellChange(&res, verbose, "3dMedusa (two colors unit and cell): in %s, remove %d\n", point{r, c}, d)
 						}
-					} else if !immune[d] && redFound != 0 && canSeeColor(d, point{r, c}, blue, &colors) {
+					} else if !immune[d] && redFound != 0 && g.canSeeColor(d, point{r, c}, blue, &colors) {
 						if g.pt(point{r, c}).andNot(1 << d) {
 							g.cellChange(&res, verbose, "3dMedusa (two colors unit and cell): in %s, remove %d\n", point{r, c}, d)
 						}
@@ -215,16 +209,16 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 
 		// Cell emptied by color.
 	outer:
-		for r := zero; r < rows; r++ {
+		for r := zero; r < g.height(); r++ {
 		inner:
-			for c := zero; c < cols; c++ {
+			for c := zero; c < g.width(); c++ {
 				if bitCount[g.cells[r][c]] == 1 {
 					continue
 				}
 
 				seeBlue := true
 				seeRed := true
-				for d := 1; d <= 9; d++ {
+				for d := 1; d <= g.size(); d++ {
 					if colors[r][c][d] != black {
 						continue inner
 					}
@@ -256,7 +250,7 @@ func (g *Grid) medusa(verbose uint) (res bool) {
 	return
 }
 
-func (g *Grid) colorGrid(digit int, p point, colors *[rows][cols][10]color, linkEnds map[point][]pair, strongLinks map[pair]cell, used *map[point]bool) {
+func (g *Grid) colorGrid(digit int, p point, colors *[maxRows][maxCols][maxSize + 1]color, linkEnds map[point][]pair, strongLinks map[pair]cell, used *map[point]bool) {
 	(*used)[p] = true
 
 	currColor := colors[p.r][p.c][digit]
@@ -290,13 +284,13 @@ func (g *Grid) colorGrid(digit int, p point, colors *[rows][cols][10]color, link
 	}
 }
 
-func (g *Grid) groupColors(gr *group, colors *[rows][cols][10]color) (bool, bool) {
+func (g *Grid) groupColors(gr *group, colors *[maxRows][maxCols][maxSize + 1]color) (bool, bool) {
 	blueMoreThanOnce := false
 	redMoreThanOnce := false
 	for _, ps := range gr.unit {
-		var blues, reds [10]int
+		var blues, reds [maxSize + 1]int
 		for _, p := range ps {
-			for d := 1; d <= 9; d++ {
+			for d := 1; d <= g.size(); d++ {
 				if *g.pt(p)&(1<<d) != 0 {
 					switch colors[p.r][p.c][d] {
 					case blue:
@@ -307,7 +301,7 @@ func (g *Grid) groupColors(gr *group, colors *[rows][cols][10]color) (bool, bool
 				}
 			}
 		}
-		for d := 1; d <= 9; d++ {
+		for d := 1; d <= g.size(); d++ {
 			if blues[d] > 2 {
 				blueMoreThanOnce = true
 			}
@@ -320,9 +314,9 @@ func (g *Grid) groupColors(gr *group, colors *[rows][cols][10]color) (bool, bool
 	return blueMoreThanOnce, redMoreThanOnce
 }
 
-func (g *Grid) removeColor(verbose uint, cl color, colors *[rows][cols][10]color, message string, res *bool) {
-	for r := zero; r < rows; r++ {
-		for c := zero; c < cols; c++ {
+func (g *Grid) removeColor(verbose uint, cl color, colors *[maxRows][maxCols][maxSize + 1]color, message string, res *bool) {
+	for r := zero; r < g.height(); r++ {
+		for c := zero; c < g.width(); c++ {
 			for ci, color := range colors[r][c] {
 				if color == cl {
 					if g.pt(point{r, c}).andNot(1 << ci) {
@@ -334,16 +328,10 @@ func (g *Grid) removeColor(verbose uint, cl color, colors *[rows][cols][10]color
 	}
 }
 
-func canSeeColor(d int, curr point, c color, colors *[rows][cols][10]color) bool {
-	for _, u := range []*[9]point{&box.unit[boxOfPoint(curr)], &col.unit[curr.c], &row.unit[curr.r]} {
-		for _, p := range u {
-			if p == curr {
-				continue
-			}
-
-			if colors[p.r][p.c][d] == c {
-				return true
-			}
+func (g *Grid) canSeeColor(d int, curr point, c color, colors *[maxRows][maxCols][maxSize + 1]color) bool {
+	for _, p := range g.peers(curr) {
+		if colors[p.r][p.c][d] == c {
+			return true
 		}
 	}
 
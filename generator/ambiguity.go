@@ -0,0 +1,304 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type (
+	// Position identifies a cell by its zero-based row and column.
+	Position struct {
+		Row int `json:"row"`
+		Col int `json:"col"`
+	}
+
+	// Placement is a digit in a cell.
+	Placement struct {
+		Position
+		Digit int `json:"digit"`
+	}
+
+	// Pattern is a deadly pattern (an unavoidable set): cells whose digits can be exchanged to turn one solution into another, so at least one of them must be a given for the puzzle to be unique. Digits and Alternates hold the values of the cells in the two solutions.
+	Pattern struct {
+		Cells              []Position
+		Digits, Alternates []int
+	}
+
+	// Ambiguity explains why a puzzle has more than one solution.
+	Ambiguity struct {
+		Solutions    int         // Solutions is the number of solutions found.
+		Limited      bool        // Limited is true if the search stopped at the limit, so there may be more solutions.
+		Undetermined []Position  // Undetermined lists the cells whose values differ between solutions.
+		Patterns     []Pattern   // Patterns lists the minimal deadly patterns between any two of the solutions found, smallest first.
+		Givens       []Placement // Givens are the fewest extra givens found that make the puzzle unique.
+		Repaired     *Grid       // Repaired is the puzzle with Givens added.
+	}
+
+	// differenceSet is the set of cells that differ between two solutions (given by their indexes).
+	differenceSet struct {
+		cells uint128
+		a, b  int
+	}
+)
+
+const (
+	ambiguityTargets      = 32      // ambiguityTargets is the maximum number of solutions tried as the one that the extra givens select.
+	ambiguityMaxSolutions = 1024    // ambiguityMaxSolutions is the most solutions that Ambiguity enumerates, whatever the limit.
+	hittingSetBudget      = 1 << 16 // hittingSetBudget is the maximum number of search nodes used to find the fewest extra givens before settling for the best found so far.
+)
+
+// Ambiguity analyzes a grid with more than one solution. It enumerates up to limit solutions (up to ambiguityMaxSolutions if limit is 0 or larger), finds the cells that differ between them and the deadly patterns that cause the ambiguity, and suggests the fewest extra givens that make the puzzle unique. If the grid has fewer than two solutions, only Solutions is set. It returns ErrNotStandard if the grid has a layout.
+func (g *Grid) Ambiguity(limit int) (*Ambiguity, error) {
+	if !g.Standard() {
+		return nil, ErrNotStandard
+	}
+	if limit <= 0 || limit > ambiguityMaxSolutions {
+		limit = ambiguityMaxSolutions
+	}
+
+	solutions := g.enumerate(limit)
+	res := &Ambiguity{Solutions: len(solutions), Limited: len(solutions) >= limit}
+	if len(solutions) < 2 {
+		return res, nil
+	}
+
+	var undetermined uint128
+	for _, s := range solutions[1:] {
+		undetermined = undetermined.or(solutions[0].differences(s))
+	}
+	for _, p := range undetermined.points() {
+		res.Undetermined = append(res.Undetermined, position(p))
+	}
+
+	for _, ds := range minimalSets(pairSets(solutions)) {
+		var p Pattern
+		for _, pt := range ds.cells.points() {
+			p.Cells = append(p.Cells, position(pt))
+			p.Digits = append(p.Digits, solutions[ds.a].pt(pt).lowestSetBit())
+			p.Alternates = append(p.Alternates, solutions[ds.b].pt(pt).lowestSetBit())
+		}
+		res.Patterns = append(res.Patterns, p)
+	}
+
+	// If the enumeration stopped at the limit, there may be solutions that the new givens do not rule out, so those are enumerated and ruled out in turn. Each round adds at least one given, so there are fewer rounds than cells.
+	repaired := *g
+	for round := 0; len(solutions) > 1 && round < rows*cols; round++ {
+		target, givens := repairGivens(solutions)
+		for _, p := range givens.points() {
+			d := solutions[target].pt(p).lowestSetBit()
+			res.Givens = append(res.Givens, Placement{position(p), d})
+			*repaired.pt(p) = 1 << d
+			repaired.orig[p.r][p.c] = true
+		}
+
+		if len(solutions) < limit {
+			break
+		}
+		solutions = repaired.enumerate(limit)
+	}
+	sort.Slice(res.Givens, func(i, j int) bool {
+		a, b := res.Givens[i], res.Givens[j]
+		return a.Row < b.Row || a.Row == b.Row && a.Col < b.Col
+	})
+	res.Repaired = &repaired
+
+	return res, nil
+}
+
+// enumerate returns up to limit solutions of the grid.
+func (g *Grid) enumerate(limit int) (res []*Grid) {
+	g.Solutions(limit, func(s *Grid) bool {
+		res = append(res, s)
+		return true
+	})
+
+	return
+}
+
+// repairGivens returns the index of a solution and the fewest cells found whose values in that solution rule out all the others. Every other solution must differ from the chosen one in at least one of the cells, so they form a minimum hitting set of the difference sets. Several solutions are tried as the target and the best is kept.
+func repairGivens(solutions []*Grid) (target int, givens uint128) {
+	target = -1
+	for t := 0; t < len(solutions) && t < ambiguityTargets; t++ {
+		hs := minHittingSet(minimalSets(differenceSets(solutions, t)))
+		if target < 0 || hs.count() < givens.count() {
+			target, givens = t, hs
+		}
+	}
+
+	return
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("(%d, %d)", p.Row, p.Col)
+}
+
+func (p Pattern) String() string {
+	parts := make([]string, len(p.Cells))
+	for i, c := range p.Cells {
+		parts[i] = fmt.Sprintf("%s=%d/%d", c, p.Digits[i], p.Alternates[i])
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (p Placement) String() string {
+	return fmt.Sprintf("%s=%d", p.Position, p.Digit)
+}
+
+// differences returns the cells whose candidates differ between two grids.
+func (g *Grid) differences(o *Grid) (res uint128) {
+	for r := zero; r < rows; r++ {
+		for c := zero; c < cols; c++ {
+			if g.cells[r][c] != o.cells[r][c] {
+				res.set(point{r, c})
+			}
+		}
+	}
+
+	return
+}
+
+// differenceSets returns the cells that differ between the target solution and each of the other solutions.
+func differenceSets(solutions []*Grid, target int) []differenceSet {
+	res := make([]differenceSet, 0, len(solutions)-1)
+	for i, s := range solutions {
+		if i != target {
+			res = append(res, differenceSet{solutions[target].differences(s), target, i})
+		}
+	}
+
+	return res
+}
+
+// pairSets returns the distinct sets of cells that differ between any two solutions.
+func pairSets(solutions []*Grid) []differenceSet {
+	seen := make(map[uint128]bool)
+	var res []differenceSet
+	for i := range solutions {
+		for j := i + 1; j < len(solutions); j++ {
+			if d := solutions[i].differences(solutions[j]); !seen[d] {
+				seen[d] = true
+				res = append(res, differenceSet{d, i, j})
+			}
+		}
+	}
+
+	return res
+}
+
+// minimalSets removes every set that contains another set (including duplicates) and returns the rest, smallest first.
+func minimalSets(sets []differenceSet) []differenceSet {
+	sort.SliceStable(sets, func(i, j int) bool { return sets[i].cells.count() < sets[j].cells.count() })
+
+	var res []differenceSet
+outer:
+	for _, s := range sets {
+		for _, m := range res {
+			if s.cells.and(m.cells) == m.cells {
+				continue outer
+			}
+		}
+		res = append(res, s)
+	}
+
+	return res
+}
+
+// minHittingSet returns a smallest set of cells that contains at least one cell from each set. It starts from a greedy solution and improves it with a depth-first search limited to hittingSetBudget nodes.
+func minHittingSet(sets []differenceSet) uint128 {
+	best := greedyHittingSet(sets)
+
+	nodes := 0
+	var search func(chosen uint128, n int)
+	search = func(chosen uint128, n int) {
+		nodes++
+		if nodes > hittingSetBudget || n >= best.count() {
+			return
+		}
+
+		// Branch on the smallest set that is not yet hit.
+		var unhit *uint128
+		min := rows*cols + 1
+		for i := range sets {
+			if s := &sets[i].cells; s.and(chosen).empty() && s.count() < min {
+				unhit, min = s, s.count()
+			}
+		}
+
+		if unhit == nil {
+			best = chosen
+			return
+		}
+
+		if n+1 >= best.count() {
+			return
+		}
+
+		for _, p := range unhit.points() {
+			c := chosen
+			search(*c.set(p), n+1)
+		}
+	}
+	search(uint128{}, 0)
+
+	return best
+}
+
+// greedyHittingSet repeatedly chooses the cell that appears in the most sets that are not yet hit.
+func greedyHittingSet(sets []differenceSet) (res uint128) {
+	for {
+		var counts [rows][cols]int
+		found := false
+		for _, s := range sets {
+			if s.cells.and(res).empty() {
+				found = true
+				s.cells.process(func(r, c uint8) {
+					counts[r][c]++
+				})
+			}
+		}
+
+		if !found {
+			return
+		}
+
+		var best point
+		for r := zero; r < rows; r++ {
+			for c := zero; c < cols; c++ {
+				if counts[r][c] > counts[best.r][best.c] {
+					best = point{r, c}
+				}
+			}
+		}
+		res.set(best)
+	}
+}
+
+func position(p point) Position {
+	return Position{int(p.r), int(p.c)}
+}
@@ -0,0 +1,138 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const multiplePuzzle = ".........9..3.5..1..18.64....81.29..7.......8..67.82....26.95..8..2.3..9..5.1.3.."
+
+func TestAmbiguity(t *testing.T) {
+	g, err := ParseEncoded(multiplePuzzle)
+	assert.NoError(t, err)
+
+	a, err := g.Ambiguity(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 37, a.Solutions)
+	assert.False(t, a.Limited)
+	assert.NotEmpty(t, a.Undetermined)
+	assert.NotEmpty(t, a.Patterns)
+	assert.NotEmpty(t, a.Givens)
+	assert.Equal(t, 1, a.Repaired.CountSolutions(0))
+	assert.Equal(t, g.Clues()+uint(len(a.Givens)), a.Repaired.Clues())
+
+	for _, p := range a.Patterns {
+		assert.True(t, len(p.Cells) >= 4)
+		for i := range p.Cells {
+			assert.NotEqual(t, p.Digits[i], p.Alternates[i])
+		}
+	}
+
+	// Every pair of solutions must differ in a whole pattern.
+	var solutions []*Grid
+	g.Solutions(0, func(s *Grid) bool {
+		solutions = append(solutions, s)
+		return true
+	})
+	for i := range solutions {
+		for j := i + 1; j < len(solutions); j++ {
+			d := solutions[i].differences(solutions[j])
+			found := false
+			for _, p := range a.Patterns {
+				var cells uint128
+				for _, c := range p.Cells {
+					cells.set(point{uint8(c.Row), uint8(c.Col)})
+				}
+				found = found || d.and(cells) == cells
+			}
+			assert.True(t, found)
+		}
+	}
+
+	// Every pattern of the repaired solution must contain one of the extra givens.
+	var solution []*Grid
+	a.Repaired.Solutions(1, func(s *Grid) bool {
+		solution = append(solution, s)
+		return true
+	})
+	for _, p := range a.Patterns {
+		digits, alternates := true, true
+		for i, c := range p.Cells {
+			d := solution[0].cells[c.Row][c.Col].lowestSetBit()
+			digits = digits && d == p.Digits[i]
+			alternates = alternates && d == p.Alternates[i]
+		}
+		if !digits && !alternates {
+			continue
+		}
+
+		hit := false
+		for _, c := range p.Cells {
+			for _, giv := range a.Givens {
+				hit = hit || c == giv.Position
+			}
+		}
+		assert.True(t, hit)
+	}
+	assert.True(t, len(a.Patterns) > 1)
+}
+
+func TestAmbiguityLimited(t *testing.T) {
+	g, err := ParseEncoded(multiplePuzzle)
+	assert.NoError(t, err)
+
+	a, err := g.Ambiguity(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, a.Solutions)
+	assert.True(t, a.Limited)
+	assert.Equal(t, 1, a.Repaired.CountSolutions(0))
+}
+
+func TestAmbiguityUnique(t *testing.T) {
+	g, err := ParseEncoded(hardestPuzzles[0])
+	assert.NoError(t, err)
+
+	a, err := g.Ambiguity(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, a.Solutions)
+	assert.Empty(t, a.Givens)
+	assert.Nil(t, a.Repaired)
+}
+
+func TestMinHittingSet(t *testing.T) {
+	var a, b, c uint128
+	a.set(point{0, 0}).set(point{0, 1})
+	b.set(point{0, 1}).set(point{1, 1})
+	c.set(point{1, 1}).set(point{2, 2})
+
+	hs := minHittingSet([]differenceSet{{a, 0, 1}, {b, 0, 2}, {c, 0, 3}})
+	assert.Equal(t, 2, hs.count())
+	for _, s := range []uint128{a, b, c} {
+		assert.False(t, s.and(hs).empty())
+	}
+}
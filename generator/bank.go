@@ -0,0 +1,277 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package generator
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+type (
+	// Bank is a persistent store of games in a file of JSON lines, one game per line with its canonical form. It holds at most one game of each canonical form, so puzzles that differ only by a transformation that preserves sudokus, such as swapping two rows of a band or relabelling the digits, are stored once. It is safe for concurrent use.
+	Bank struct {
+		mu       sync.Mutex
+		file     *os.File
+		readOnly bool
+		offset   int64 // offset is the size of the part of the file that has been read.
+		lines    int   // lines is the number of lines that have been read.
+		games    []*Game
+		forms    map[string]bool
+	}
+
+	// BankQuery selects games from a bank. Zero fields match every game.
+	BankQuery struct {
+		Levels     []Level  // Levels matches games at any of the levels.
+		MinClues   uint     // MinClues matches games with at least this many clues.
+		MaxClues   uint     // MaxClues matches games with at most this many clues.
+		Strategies []string // Strategies matches games that need all of the strategies.
+		Limit      int      // Limit is the maximum number of games to return.
+	}
+
+	// bankEntry is a line of the file of a bank.
+	bankEntry struct {
+		Canonical string `json:"canonical"`
+		Game      *Game  `json:"game"`
+	}
+)
+
+var errReadOnly = errors.New("the bank was opened read-only")
+
+// OpenBank opens the bank in the file at path, creating the file if it does not exist, and reads its games. Other processes may add games to the same file: Add locks the file while it appends, and first reads any games added since, so that the file never holds two games with the same canonical form.
+func OpenBank(path string) (*Bank, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return readBank(file, false)
+}
+
+// ReadBank opens the bank in the file at path for queries only, and reads its games. It does not create the file, and Add returns an error.
+func ReadBank(path string) (*Bank, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return readBank(file, true)
+}
+
+// readBank reads the games of a bank from its file, holding a shared lock so that no game is read while another process writes it.
+func readBank(file *os.File, readOnly bool) (*Bank, error) {
+	b := &Bank{file: file, forms: make(map[string]bool), readOnly: readOnly}
+	if err := lockFile(file, false); err != nil {
+		file.Close()
+		return nil, err
+	}
+	err := b.load()
+	unlockFile(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// load reads the games that follow the part of the file already read. The caller holds a lock on the file.
+func (b *Bank) load() error {
+	info, err := b.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() <= b.offset {
+		return nil
+	}
+
+	s := bufio.NewScanner(io.NewSectionReader(b.file, b.offset, info.Size()-b.offset))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		b.lines++
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+
+		var e bankEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return fmt.Errorf("%s:%d: %w", b.file.Name(), b.lines, err)
+		}
+		if e.Game == nil {
+			return fmt.Errorf("%s:%d: no game", b.file.Name(), b.lines)
+		}
+		if e.Canonical == "" {
+			if e.Canonical, err = e.Game.Puzzle.Canonical(); err != nil {
+				return fmt.Errorf("%s:%d: %w", b.file.Name(), b.lines, err)
+			}
+		}
+
+		if !b.forms[e.Canonical] {
+			b.forms[e.Canonical] = true
+			b.games = append(b.games, e.Game)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	b.offset = info.Size()
+	return nil
+}
+
+// Add stores a game in the bank unless the bank already holds a game with the same canonical form, including one that another process has added since the bank was opened. It reports whether the game was added, and returns ErrNotStandard if the puzzle has a layout.
+func (b *Bank) Add(g *Game) (bool, error) {
+	form, err := g.Puzzle.Canonical()
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.readOnly {
+		return false, errReadOnly
+	}
+	if b.forms[form] {
+		return false, nil
+	}
+
+	line, err := json.Marshal(bankEntry{form, g})
+	if err != nil {
+		return false, err
+	}
+
+	if err := lockFile(b.file, true); err != nil {
+		return false, err
+	}
+	defer unlockFile(b.file)
+
+	if err := b.load(); err != nil {
+		return false, err
+	}
+	if b.forms[form] {
+		return false, nil
+	}
+
+	// Start a line of its own after a last line that has no newline.
+	last := make([]byte, 1)
+	if b.offset > 0 {
+		if _, err := b.file.ReadAt(last, b.offset-1); err != nil {
+			return false, err
+		}
+		if last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+
+	n, err := b.file.Write(append(line, '\n'))
+	b.offset += int64(n)
+	if err != nil {
+		return false, err
+	}
+
+	b.forms[form] = true
+	b.games = append(b.games, g)
+	return true, nil
+}
+
+// Contains reports whether the bank holds a game whose puzzle has the same canonical form as g. A bank holds only standard grids, so it never contains a grid that has a layout.
+func (b *Bank) Contains(g *Grid) bool {
+	form, err := g.Canonical()
+	if err != nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.forms[form]
+}
+
+// Len returns the number of games in the bank.
+func (b *Bank) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.games)
+}
+
+// Query returns the games of the bank that match q, in the order they were added.
+func (b *Bank) Query(q BankQuery) []*Game {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var res []*Game
+	for _, g := range b.games {
+		if q.Limit > 0 && len(res) >= q.Limit {
+			break
+		}
+		if q.matches(g) {
+			res = append(res, g)
+		}
+	}
+
+	return res
+}
+
+// matches reports whether a game satisfies the query.
+func (q *BankQuery) matches(g *Game) bool {
+	if len(q.Levels) > 0 {
+		found := false
+		for _, l := range q.Levels {
+			found = found || l == g.Level
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if g.Clues < q.MinClues || q.MaxClues > 0 && g.Clues > q.MaxClues {
+		return false
+	}
+
+	for _, s := range q.Strategies {
+		found := false
+		for _, gs := range g.Strategies {
+			found = found || gs == s
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Close closes the file of the bank.
+func (b *Bank) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.file.Close()
+}
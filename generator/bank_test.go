@@ -0,0 +1,214 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package generator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// transformedPuzzle returns marshalPuzzle transposed, with its first two bands and its last two columns swapped, and with each digit d relabelled 10 - d.
+func transformedPuzzle() string {
+	var b []byte
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			sr, sc := c, r // Transposed.
+			if sr < 6 {
+				sr = (sr + 3) % 6
+			}
+			if sc >= 7 {
+				sc = 15 - sc
+			}
+
+			ch := marshalPuzzle[sr*cols+sc]
+			if ch != '.' {
+				ch = '0' + 10 - (ch - '0')
+			}
+			b = append(b, ch)
+		}
+	}
+
+	return string(b)
+}
+
+// canonical returns the canonical form of g.
+func canonical(t *testing.T, g *Grid) string {
+	form, err := g.Canonical()
+	assert.NoError(t, err)
+	return form
+}
+
+func TestCanonical(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+	h, err := ParseEncoded(transformedPuzzle())
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, g.Encode(), h.Encode())
+	assert.Equal(t, canonical(t, g), canonical(t, h))
+	assert.Len(t, canonical(t, g), rows*cols)
+
+	h.cells[0][0] = 1 << 1 // One more given makes a different puzzle.
+	h.orig[0][0] = true
+	assert.NotEqual(t, canonical(t, g), canonical(t, h))
+}
+
+func TestBank(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bank")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bank.jsonl")
+
+	b, err := OpenBank(path)
+	assert.NoError(t, err)
+
+	var games []*Game
+	for seed := int64(1); len(games) < 3; seed++ {
+		if g := Generate(seed); g != nil {
+			games = append(games, g)
+		}
+	}
+	for _, g := range games {
+		added, err := b.Add(g)
+		assert.NoError(t, err)
+		assert.True(t, added)
+	}
+
+	puzzle, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+	transformed, err := ParseEncoded(transformedPuzzle())
+	assert.NoError(t, err)
+	added, err := b.Add(&Game{Level: Hard, Clues: puzzle.Clues(), Strategies: []string{"medusa", "nakedPair"}, Puzzle: puzzle})
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = b.Add(&Game{Level: Hard, Clues: transformed.Clues(), Puzzle: transformed})
+	assert.NoError(t, err)
+	assert.False(t, added) // A duplicate under transformation.
+	assert.True(t, b.Contains(transformed))
+	assert.Equal(t, 4, b.Len())
+	assert.NoError(t, b.Close())
+
+	b, err = OpenBank(path)
+	assert.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 4, b.Len())
+
+	hard := b.Query(BankQuery{Levels: []Level{Hard}, Strategies: []string{"medusa"}})
+	assert.Len(t, hard, 1)
+	assert.Equal(t, strings.ReplaceAll(marshalPuzzle, ".", "0"), hard[0].Puzzle.Encode())
+
+	assert.Len(t, b.Query(BankQuery{MinClues: 36, MaxClues: 36}), 1)
+	assert.Len(t, b.Query(BankQuery{Limit: 2}), 2)
+	assert.Empty(t, b.Query(BankQuery{Strategies: []string{"medusa", "noSuchStrategy"}}))
+}
+
+func TestReadBank(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bank")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bank.jsonl")
+
+	_, err = ReadBank(path)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err)) // Not created.
+
+	b, err := OpenBank(path)
+	assert.NoError(t, err)
+	g := Generate(1)
+	added, err := b.Add(g)
+	assert.NoError(t, err)
+	assert.True(t, added)
+	assert.NoError(t, b.Close())
+
+	r, err := ReadBank(path)
+	assert.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, 1, r.Len())
+	assert.True(t, r.Contains(g.Puzzle))
+	_, err = r.Add(Generate(2))
+	assert.Equal(t, errReadOnly, err)
+	assert.Equal(t, 1, r.Len())
+}
+
+func TestBankShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bank")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bank.jsonl")
+
+	games := make([]*Game, 4)
+	for i := range games {
+		games[i] = Generate(int64(i + 1))
+	}
+
+	// A last line without a newline, as left by an editor.
+	line, err := json.Marshal(bankEntry{canonical(t, games[0].Puzzle), games[0]})
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(path, line, 0644))
+
+	a, err := OpenBank(path)
+	assert.NoError(t, err)
+	defer a.Close()
+	b, err := OpenBank(path)
+	assert.NoError(t, err)
+	defer b.Close()
+
+	added, err := a.Add(games[1])
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = b.Add(games[1]) // Added by a since b was opened.
+	assert.NoError(t, err)
+	assert.False(t, added)
+	assert.Equal(t, 2, b.Len())
+
+	// Banks add the same games at the same time without duplicating any.
+	var wg sync.WaitGroup
+	for _, bank := range []*Bank{a, b} {
+		wg.Add(1)
+		go func(bank *Bank) {
+			defer wg.Done()
+			for _, g := range games[2:] {
+				_, err := bank.Add(g)
+				assert.NoError(t, err)
+			}
+		}(bank)
+	}
+	wg.Wait()
+
+	r, err := ReadBank(path)
+	assert.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, 4, r.Len())
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(string(data), "\n"))
+}
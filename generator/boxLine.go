@@ -24,55 +24,21 @@
 
 package generator
 
+// boxLine removes candidates. When a candidate within a column or row appears only where the line overlaps a single box (or other unit that is not a line, such as a diagonal or window), that candidate can be removed from all cells in the box outside of the line. It returns true if it changes any cells.
 func (g *Grid) boxLine(verbose uint) bool {
-	return g.boxLineGroup(&col, verbose, pointCol, pointRow, func(i, c int) int { return i*3 + c/3 }) ||
-		g.boxLineGroup(&row, verbose, pointRow, pointCol, func(i, r int) int { return r/3*3 + i })
-}
-
-func (g *Grid) boxLineGroup(
-	gr *group,
-	verbose uint,
-	major func(point) uint8,
-	minor func(point) uint8,
-	boxSel func(int, int) int,
-) (res bool) {
-	for ui, u := range gr.unit {
-		boxes := [10][3]bool{}
-
-		for _, p := range u {
-			cell := *g.pt(p)
-			for d := 1; d <= 9; d++ {
-				if cell&(1<<d) != 0 {
-					boxes[d][minor(p)/3] = true
-				}
-			}
+	l := g.Layout()
+	var boxes []int
+	for ui := range l.units {
+		if !l.line(ui) {
+			boxes = append(boxes, ui)
 		}
+	}
 
-		for d := 1; d <= 9; d++ {
-			var index int
-			if boxes[d][0] && !boxes[d][1] && !boxes[d][2] {
-				index = 0
-			} else if !boxes[d][0] && boxes[d][1] && !boxes[d][2] {
-				index = 1
-			} else if !boxes[d][0] && !boxes[d][1] && boxes[d][2] {
-				index = 2
-			} else {
-				continue
-			}
-
-			for i := 0; i < 9; i++ {
-				p := box.unit[boxSel(index, ui)][i]
-
-				if major(p) == major(u[index]) {
-					continue
-				}
-
-				if g.pt(p).andNot(1 << d) {
-					g.cellChange(&res, verbose, "boxLine: all %d's in %s %d appear in box %d removing from %s\n", d, gr.name, ui, boxSel(index, ui), p)
-				}
-			}
+	for _, members := range l.members {
+		if len(members) > 0 && l.line(members[0]) && g.lockedCandidates(members, boxes, verbose, "boxLine: all %[1]d's in %[2]s appear in %[3]s removing from %[4]s\n") {
+			return true
 		}
 	}
 
-	return
+	return false
 }
@@ -0,0 +1,228 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package generator
+
+// canonicalForm returns the least of the arrangements of digits (given row by row, with 0 for an empty cell) that can be reached by the transformations of a Transform, and the transformation that reaches it. Two grids have the same canonical form if and only if each can be turned into the other by these transformations, which preserve the validity of a sudoku.
+//
+// Every order of the columns is tried (for each of the two orientations); the rows are then chosen one at a time, keeping only those that can still lead to the least arrangement. The digits are relabelled in order of their first appearance, which gives the least labelling of each arrangement.
+func canonicalForm(digits [rows * cols]byte) ([rows * cols]byte, Transform) {
+	var (
+		best  [rows * cols]byte
+		found Transform
+	)
+	for i := range best {
+		best[i] = 0xff // Larger than any arrangement.
+	}
+
+	for _, transpose := range []bool{false, true} {
+		var m [rows][cols]byte
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				if transpose {
+					m[r][c] = digits[c*cols+r]
+				} else {
+					m[r][c] = digits[r*cols+c]
+				}
+			}
+		}
+
+		for _, order := range lineOrders {
+			s := canonicalSearch{m: &m, transpose: transpose, cols: order, best: &best, found: &found}
+			s.rows(0, 0, [10]byte{}, 1)
+		}
+	}
+
+	// Digits that do not appear take the remaining labels in order.
+	next := 1
+	for _, d := range found.Digits[1:] {
+		if d >= next {
+			next = d + 1
+		}
+	}
+	for d := 1; d <= 9; d++ {
+		if found.Digits[d] == 0 {
+			found.Digits[d] = next
+			next++
+		}
+	}
+
+	return best, found
+}
+
+// canonicalSearch chooses the order of the rows of a grid whose columns have been put in order, looking for arrangements less than or equal to best. It only places a row while the rows before it match the first rows of best, and records the transformation of each improvement to best in found.
+type canonicalSearch struct {
+	m         *[rows][cols]byte
+	transpose bool
+	cols      [cols]int
+	best      *[rows * cols]byte
+	found     *Transform
+	path      [rows]int
+	used      [rows]bool
+}
+
+// rows places a row at position k of the arrangement, from the band of the row placed before it if that band is not yet full and from any unused band otherwise. labels maps the digits of the grid to the labels given so far, and next is the next label to give.
+func (s *canonicalSearch) rows(k, band int, labels [10]byte, next byte) {
+	if k == rows {
+		return
+	}
+
+	var candidates []int
+	if k%3 == 0 {
+		for r := 0; r < rows; r++ {
+			if !s.used[r] && !s.used[r/3*3] && !s.used[r/3*3+1] && !s.used[r/3*3+2] {
+				candidates = append(candidates, r)
+			}
+		}
+	} else {
+		for r := band * 3; r < band*3+3; r++ {
+			if !s.used[r] {
+				candidates = append(candidates, r)
+			}
+		}
+	}
+
+	// Label each candidate row and keep those that are least.
+	type labelled struct {
+		r      int
+		row    [cols]byte
+		labels [10]byte
+		next   byte
+	}
+	var least []labelled
+	for _, r := range candidates {
+		l := labelled{r: r, labels: labels, next: next}
+		for i, c := range s.cols {
+			d := s.m[r][c]
+			if d != 0 && l.labels[d] == 0 {
+				l.labels[d] = l.next
+				l.next++
+			}
+			l.row[i] = l.labels[d]
+		}
+
+		switch {
+		case len(least) == 0 || l.row == least[0].row:
+			least = append(least, l)
+		case lessRow(l.row, least[0].row):
+			least = append(least[:0], l)
+		}
+	}
+
+	var bestRow [cols]byte
+	copy(bestRow[:], s.best[k*cols:])
+	if lessRow(bestRow, least[0].row) {
+		return
+	}
+	if lessRow(least[0].row, bestRow) {
+		copy(s.best[k*cols:], least[0].row[:])
+		for i := (k + 1) * cols; i < len(s.best); i++ {
+			s.best[i] = 0xff
+		}
+
+		s.path[k] = least[0].r
+		s.found.Transpose, s.found.Cols = s.transpose, s.cols
+		copy(s.found.Rows[:], s.path[:k+1])
+		for d, l := range least[0].labels {
+			s.found.Digits[d] = int(l)
+		}
+	}
+
+	for _, l := range least { // The later rows of best can only improve, so every row in least stays as good as the first.
+		s.used[l.r] = true
+		s.path[k] = l.r
+		s.rows(k+1, l.r/3, l.labels, l.next)
+		s.used[l.r] = false
+	}
+}
+
+// lessRow reports whether row a comes before row b.
+func lessRow(a, b [cols]byte) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+
+	return false
+}
+
+// lineOrders holds the 6^4 orders of nine lines that keep each group of three together: every order of the groups combined with every order of the lines within each group.
+var lineOrders = func() (res [][cols]int) {
+	perms := [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+	for _, groups := range perms {
+		for _, p0 := range perms {
+			for _, p1 := range perms {
+				for _, p2 := range perms {
+					within := [3][3]int{p0, p1, p2}
+					var order [cols]int
+					for i, g := range groups {
+						for j := 0; j < 3; j++ {
+							order[i*3+j] = g*3 + within[i][j]
+						}
+					}
+					res = append(res, order)
+				}
+			}
+		}
+	}
+
+	return
+}()
+
+// Canonical returns the canonical form of the givens of the grid as 81 digits, with 0 for the cells that are not givens: the least such string that any transformation of the givens can produce. Puzzles are isomorphic (each is a transformation of the other) if and only if they have the same canonical form. It returns ErrNotStandard if the grid has a layout.
+func (g *Grid) Canonical() (string, error) {
+	if !g.Standard() {
+		return "", ErrNotStandard
+	}
+
+	form, _ := canonicalForm(g.givenDigits())
+	for i := range form {
+		form[i] += '0'
+	}
+
+	return string(form[:]), nil
+}
+
+// CanonicalTransform returns a transformation that turns the givens of the grid into its canonical form. It returns ErrNotStandard if the grid has a layout.
+func (g *Grid) CanonicalTransform() (Transform, error) {
+	if !g.Standard() {
+		return Transform{}, ErrNotStandard
+	}
+
+	_, t := canonicalForm(g.givenDigits())
+	return t, nil
+}
+
+// givenDigits returns the digits of the givens of the grid, row by row, with 0 for the other cells.
+func (g *Grid) givenDigits() (res [rows * cols]byte) {
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if g.orig[r][c] {
+				res[r*cols+c] = byte(g.cells[r][c].lowestSetBit())
+			}
+		}
+	}
+
+	return
+}
@@ -24,18 +24,16 @@
 
 package generator
 
-import (
-	"fmt"
-	"strings"
-)
+import "strings"
 
-type cell uint16
+// cell holds the candidates of a cell as a bit mask: bit d is set when digit d (1 - 16) is a candidate.
+type cell uint32
 
-var bitCount [1024]int
+var bitCount [1 << (maxSize + 1)]int
 
 func init() {
 	// Brian Kernighan's algorithm to count bits set to 1.
-	for i := 0; i < 1024; i++ {
+	for i := 0; i < len(bitCount); i++ {
 		n := i
 		c := 0
 		for n != 0 {
@@ -61,7 +59,7 @@ func (c *cell) andNot(o cell) bool {
 // digits returns a slice containing all the candidate digits in a cell as individual ints.
 func (c cell) digits() []int {
 	ds := make([]int, 0, 9)
-	for d := 1; d <= 9; d++ {
+	for d := 1; d <= maxSize; d++ {
 		if c&(1<<d) != 0 {
 			ds = append(ds, d)
 		}
@@ -71,7 +69,7 @@ func (c cell) digits() []int {
 }
 
 func (c cell) lowestSetBit() int {
-	for d := 1; d <= 9; d++ {
+	for d := 1; d <= maxSize; d++ {
 		if c&(1<<d) != 0 {
 			return d
 		}
@@ -88,9 +86,9 @@ func (c *cell) setTo(o cell) bool {
 
 func (c cell) String() string {
 	var b strings.Builder
-	for d := 1; d <= 9; d++ {
+	for d := 1; d <= maxSize; d++ {
 		if c&(1<<d) != 0 {
-			fmt.Fprintf(&b, "%d", d)
+			b.WriteByte(digitChar(d))
 		}
 	}
 	return b.String()
@@ -0,0 +1,202 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import "fmt"
+
+// PairRule says what digits two related cells of a layout may hold.
+type PairRule int
+
+const (
+	// Different requires the digits of the cells to differ, as in anti-king and anti-knight sudoku.
+	Different PairRule = iota
+	// NonConsecutive requires the digits of the cells to differ by more than one.
+	NonConsecutive
+)
+
+// pairSet relates each cell of a layout to other cells under a rule.
+type pairSet struct {
+	name    string
+	rule    PairRule
+	offsets []Position // offsets are the moves that relate the cells, which SVG draws in the key to the rule.
+	with    [][]int
+}
+
+var (
+	kingMoves   = []Position{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	knightMoves = []Position{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}}
+	rookSteps   = []Position{{-1, 0}, {0, -1}, {0, 1}, {1, 0}}
+)
+
+// AddUnit adds an extra unit to the layout: Size cells that must contain each digit exactly once. Units of the same kind, such as "window", form a group that the strategies search like the rows or columns. The cells of a shaded unit are shaded by SVG. It must be called before the layout is used by any puzzle.
+func (l *Layout) AddUnit(kind string, cells []Position, shaded bool) error {
+	name := fmt.Sprintf("%s unit", kind)
+	if len(cells) != l.Size {
+		return fmt.Errorf("%s must have %d cells", name, l.Size)
+	}
+
+	u := layoutUnit{kind: kind, shaded: shaded}
+	seen := make(map[int]bool)
+	for _, p := range cells {
+		if p.Row < 0 || p.Row >= l.Height || p.Col < 0 || p.Col >= l.Width {
+			return fmt.Errorf("%s cell %s is outside the %s layout", name, p, l)
+		}
+		i := p.Row*l.Width + p.Col
+		if seen[i] {
+			return fmt.Errorf("%s contains cell %s twice", name, p)
+		}
+		seen[i] = true
+		u.cells = append(u.cells, i)
+	}
+
+	l.units = append(l.units, u)
+	l.link()
+
+	return nil
+}
+
+// AddWindows adds the four shaded Windoku windows to a 9 x 9 layout: the 3 x 3 blocks one cell in from each corner. Each window must contain each digit exactly once.
+func (l *Layout) AddWindows() error {
+	if l.Width != 9 || l.Height != 9 {
+		return fmt.Errorf("windows need a 9x9 layout, not %s", l)
+	}
+
+	for w := 0; w < 4; w++ {
+		top, left := 1+w/2*4, 1+w%2*4
+		var cells []Position
+		for r := top; r < top+3; r++ {
+			for c := left; c < left+3; c++ {
+				cells = append(cells, Position{r, c})
+			}
+		}
+		if err := l.AddUnit("window", cells, true); err != nil {
+			return err
+		}
+	}
+	l.addRule("windoku")
+
+	return nil
+}
+
+// AddPairs relates every cell to the cells at the given offsets (in rows and columns) from it under a rule. The offsets should be symmetric, so that if one cell is related to another, the second is related to the first. It must be called before the layout is used by any puzzle.
+func (l *Layout) AddPairs(name string, offsets []Position, rule PairRule) {
+	p := pairSet{name, rule, offsets, make([][]int, l.Cells())}
+	for i := range p.with {
+		r, c := i/l.Width, i%l.Width
+		for _, o := range offsets {
+			if nr, nc := r+o.Row, c+o.Col; nr >= 0 && nr < l.Height && nc >= 0 && nc < l.Width {
+				p.with[i] = append(p.with[i], nr*l.Width+nc)
+			}
+		}
+	}
+
+	l.pairs = append(l.pairs, p)
+	l.addRule(name)
+	l.link()
+}
+
+// AddAntiKing requires cells a king's move apart (including diagonally adjacent cells) to hold different digits.
+func (l *Layout) AddAntiKing() {
+	l.AddPairs("anti-king", kingMoves, Different)
+}
+
+// AddAntiKnight requires cells a knight's move apart to hold different digits.
+func (l *Layout) AddAntiKnight() {
+	l.AddPairs("anti-knight", knightMoves, Different)
+}
+
+// AddNonConsecutive requires orthogonally adjacent cells to hold digits that differ by more than one.
+func (l *Layout) AddNonConsecutive() {
+	l.AddPairs("non-consecutive", rookSteps, NonConsecutive)
+}
+
+// excluded returns the digits that a cell related by rule to a cell holding the digits of bit cannot hold.
+func (l *Layout) excluded(rule PairRule, bit cell) cell {
+	if rule == NonConsecutive {
+		return (bit<<1 | bit>>1) & l.all
+	}
+
+	return bit
+}
+
+// reach returns how many cells a pair rule reaches from a cell in any direction (at least one).
+func (p *pairSet) reach() int {
+	res := 1
+	for _, o := range p.offsets {
+		for _, d := range []int{o.Row, -o.Row, o.Col, -o.Col} {
+			if d > res {
+				res = d
+			}
+		}
+	}
+
+	return res
+}
+
+// pairsHold returns false if two related cells that are both solved (and, if orig is not nil, both givens) break the rule relating them.
+func (l *Layout) pairsHold(cells []cell, orig []bool) bool {
+	for _, p := range l.pairs {
+		for i, with := range p.with {
+			if orig != nil && !orig[i] || bitCount[cells[i]] != 1 {
+				continue
+			}
+			for _, j := range with {
+				if orig != nil && !orig[j] || bitCount[cells[j]] != 1 {
+					continue
+				}
+				if cells[j]&l.excluded(p.rule, cells[i]) != 0 {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// pairRules removes from the cells related to each solved cell the digits that their rules exclude.
+func (g *Grid) pairRules(verbose uint) bool {
+	l := g.Layout()
+	res := false
+	for _, ps := range l.pairs {
+		for i, with := range ps.with {
+			p := l.point(i)
+			cell := *g.pt(p)
+			if bitCount[cell] != 1 {
+				continue
+			}
+
+			ex := l.excluded(ps.rule, cell)
+			for _, j := range with {
+				q := l.point(j)
+				if removed := *g.pt(q) & ex; removed != 0 && g.pt(q).andNot(removed) {
+					g.cellChange(&res, verbose, "pairRules: %s cell %s is %s, removed %s from %s\n", ps.name, p, cell, removed, q)
+				}
+			}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,134 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddUnit(t *testing.T) {
+	l, _ := NewLayout(4)
+	assert.Error(t, l.AddUnit("short", []Position{{0, 0}}, false))
+	assert.Error(t, l.AddUnit("outside", []Position{{0, 0}, {0, 1}, {0, 2}, {4, 0}}, false))
+	assert.Error(t, l.AddUnit("twice", []Position{{0, 0}, {0, 1}, {0, 2}, {0, 0}}, false))
+	assert.NoError(t, l.AddUnit("centre", []Position{{1, 1}, {1, 2}, {2, 1}, {2, 2}}, true))
+	assert.Len(t, l.units, 13)
+	assert.Equal(t, "centre 0", l.units[12].name)
+	assert.Equal(t, "centre", l.groups[3].name)
+	assert.Len(t, l.shaded(), 4)
+	assert.Contains(t, l.peers[5], 10)
+
+	assert.Error(t, l.AddWindows())
+
+	w, _ := NewLayout(9)
+	assert.NoError(t, w.AddWindows())
+	assert.Equal(t, "9x9 windoku", w.String())
+	assert.Len(t, w.shaded(), 36)
+	assert.Equal(t, 1*9+1, w.units[27].cells[0])
+	assert.Equal(t, 7*9+7, w.units[30].cells[8])
+	assert.Equal(t, "window 3", w.units[30].name)
+}
+
+func TestPairRules(t *testing.T) {
+	l, _ := NewLayout(9)
+	l.AddAntiKing()
+	l.AddAntiKnight()
+	assert.Equal(t, "9x9 anti-king anti-knight", l.String())
+	assert.Len(t, l.peers[0], 20)    // the cells a king's or knight's move from a corner share its box.
+	assert.Len(t, l.peers[40], 20+8) // but a knight's move from the centre always leaves it.
+	assert.Nil(t, l.adjacent)
+
+	g, _ := ParseLayout(l, "1.........1......................................................................")
+	assert.False(t, g.Valid())
+
+	n, _ := NewLayout(4)
+	n.AddNonConsecutive()
+	assert.Len(t, n.adjacent[0], 2)
+	assert.Len(t, n.peers[0], 7)
+
+	g, _ = ParseLayout(n, "12..............")
+	assert.False(t, g.Valid())
+	g, _ = ParseLayout(n, "13..............")
+	assert.True(t, g.Valid())
+
+	// Assigning a digit removes its neighbours from the adjacent cells.
+	c, _ := NewLayout(9)
+	c.AddNonConsecutive()
+	cells := NewGrid(c).cellList()
+	assert.True(t, c.assign(cells, 40, 1<<5))
+	for _, i := range []int{31, 39, 41, 49} {
+		assert.Equal(t, c.all&^(1<<4|1<<5|1<<6), cells[i])
+	}
+	assert.Equal(t, c.all&^(1<<5), cells[30])
+
+	// No 4 x 4 grid is non-consecutive.
+	assert.Equal(t, 0, NewGrid(n).CountSolutions(0))
+}
+
+func TestGeneratePairRules(t *testing.T) {
+	for _, add := range []func(*Layout){(*Layout).AddAntiKing, (*Layout).AddNonConsecutive} {
+		l, _ := NewLayout(6)
+		add(l)
+
+		var game *Game
+		for seed := int64(1); game == nil; seed++ {
+			game = GenerateLayout(l, seed)
+		}
+		assert.True(t, game.Solution.solved(), l.String())
+		assert.True(t, game.Puzzle.Valid(), l.String())
+		assert.Equal(t, 1, game.Puzzle.CountSolutions(0), l.String())
+		assert.Contains(t, game.Puzzle.SVG(1, false, false, nil), l.pairs[0].name)
+
+		g := *game.Puzzle
+		_, solved := g.Reduce(true, nil, 0)
+		assert.True(t, solved, l.String())
+		assert.Equal(t, game.Solution.Values(), g.Values(), l.String())
+	}
+}
+
+func TestPairKey(t *testing.T) {
+	l, _ := NewLayout(9)
+	l.AddAntiKing()
+	l.AddAntiKnight()
+	l.AddNonConsecutive()
+
+	places, lines := l.pairKeyPlaces(40, 450)
+	assert.Equal(t, []Position{{0, 0}, {0, 131}, {0, 279}}, places)
+	assert.Equal(t, 1, lines)
+
+	// A narrower grid puts each rule on a line of its own.
+	places, lines = l.pairKeyPlaces(40, 150)
+	assert.Equal(t, []Position{{0, 0}, {60, 0}, {120, 0}}, places)
+	assert.Equal(t, 3, lines)
+
+	svg := NewGrid(l).SVG(1, false, false, nil)
+	assert.Equal(t, 3, strings.Count(svg, "<circle"))
+	assert.Equal(t, 4, strings.Count(svg, "stroke-width:3")) // The bars of the non-consecutive rule.
+	assert.Equal(t, 8+8, strings.Count(svg, "fill:lightgray"))
+	assert.Contains(t, svg, `height="560"`)
+}
@@ -45,8 +45,12 @@ var pairIndexes = [][2]uint8{
 	{7, 8},
 }
 
-// exocet removes candidates. When 2 of the 3 cells in a box-line intersection together contain 3 or 4 candidates, then in each of the two boxes in the same band but in different lines, if there are cells with the same 3 or 4 candidates, any others can be removed. See https://www.sudokuwiki.org/Exocet for explanation/discussion.
-func (g *Grid) exocet(verbose uint) (res bool) {
+// exocet removes candidates. When 2 of the 3 cells in a box-line intersection together contain 3 or 4 candidates, then in each of the two boxes in the same band but in different lines, if there are cells with the same 3 or 4 candidates, any others can be removed. See https://www.sudokuwiki.org/Exocet for explanation/discussion. It works on each standard 9 x 9 grid of the layout.
+func (g *Grid) exocet(verbose uint) bool {
+	return g.eachGrid(func(sub *Grid) bool { return sub.exocetGrid(verbose) })
+}
+
+func (g *Grid) exocetGrid(verbose uint) (res bool) {
 	for _, b := range box.unit {
 		for _, pi := range pairIndexes {
 			// Find base cells.
@@ -24,10 +24,11 @@
 
 package generator
 
-// Game represents a solved or unsolved puzzle and includes the maximum strategy level used, the number of original clues, a list of strategies used, the original puzzle, and the solution, if found.
+// Game represents a solved or unsolved puzzle and includes the maximum strategy level used, the number of original clues, a list of strategies used, the original puzzle, the solution, if found, and the seed it was generated from (zero if it was not generated).
 type Game struct {
 	Level
 	Clues            uint
 	Strategies       []string
 	Puzzle, Solution *Grid
+	Seed             int64
 }
@@ -25,9 +25,12 @@
 package generator
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"math/rand"
+	"os"
 	"reflect"
 	"runtime"
 	"sort"
@@ -37,10 +40,12 @@ import (
 )
 
 type (
-	// Grid is the primary data structure for the generator. It contains the candidates for each cell in the 9 x 9 puzzle.
+	// Grid is the primary data structure for the generator. It contains the candidates for each cell of the puzzle, which is the standard 9 x 9 puzzle unless the grid has a Layout.
 	Grid struct {
-		orig  [rows][cols]bool
-		cells [rows][cols]cell
+		layout *Layout // layout is the layout of the puzzle, or nil for the standard 9 x 9 layout.
+		orig   [maxRows][maxCols]bool
+		cells  [maxRows][maxCols]cell
+		trace  *trace // trace records the steps of Reduce for Steps; it is nil otherwise.
 	}
 
 	pointCell struct {
@@ -55,21 +60,33 @@ const (
 	zero = uint8(0)
 
 	all = 0b1111111110
+
+	// maxCols and maxRows are the size of the largest board of any layout (a Samurai), and maxSize is the largest number of digits.
+	maxCols = 21
+	maxRows = 21
+	maxSize = 16
 )
 
 var (
-	attempts  uint
+	attempts  uint = 500
 	colorized bool
 )
 
 func init() {
 	rand.Seed(time.Now().Unix())
+}
+
+// AttemptsFlag adds the -a flag, which limits the number of attempts Worker makes to generate each puzzle, to a flag set.
+func AttemptsFlag(fs *flag.FlagSet) {
+	fs.UintVar(&attempts, "a", 500, "maximum `attempts` to generate a puzzle")
+}
 
-	flag.UintVar(&attempts, "a", 500, "maximum `attempts` to generate a puzzle")
-	flag.BoolVar(&colorized, "c", false, "colorize the output for ANSI terminals")
+// ColorFlag adds the -c flag, which colorizes the output of Display, to a flag set.
+func ColorFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&colorized, "c", false, "colorize the output for ANSI terminals")
 }
 
-// ParseEncoded parses an input string contains 81 digits and dots ('.') representing an initial puzzle layout.
+// ParseEncoded parses an input string contains 81 digits and dots ('.') representing an initial puzzle layout. A '0' is also read as an empty cell, as written by Encode.
 func ParseEncoded(i string) (*Grid, error) {
 	if len(i) != 81 {
 		return nil, fmt.Errorf("encoded puzzle must contain 81 characters")
@@ -78,7 +95,7 @@ func ParseEncoded(i string) (*Grid, error) {
 	for r := 0; r < rows; r++ {
 		for c := 0; c < cols; c++ {
 			b := i[r*9+c]
-			if b == '.' {
+			if b == '.' || b == '0' {
 				g.cells[r][c] = all
 			} else {
 				d, err := strconv.Atoi(string(b))
@@ -94,8 +111,56 @@ func ParseEncoded(i string) (*Grid, error) {
 	return &g, nil
 }
 
+// NewGrid returns an empty puzzle on a layout, with every digit a candidate in every cell. A nil layout, or one with nothing added to the plain 9 x 9 layout, gives a standard grid.
+func NewGrid(l *Layout) *Grid {
+	g := &Grid{}
+	if l != nil && !l.standard() {
+		g.layout = l
+	}
+
+	l = g.Layout()
+	for _, p := range l.points {
+		*g.pt(p) = l.all
+	}
+
+	return g
+}
+
+// ParseLayout parses a puzzle encoded as one character per cell in row-major order: '1' - '9' and 'A' - 'G' for the digits 1 - 16, and '.' or '0' for blanks. Absent cells, such as those between the grids of a Samurai, are left out. If l is nil, the layout is chosen from the length of the encoding.
+func ParseLayout(l *Layout, i string) (*Grid, error) {
+	if l == nil {
+		var err error
+		if l, err = LayoutOf(len(i)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(i) != l.Length() {
+		return nil, fmt.Errorf("encoded %s puzzle must contain %d characters", l, l.Length())
+	}
+
+	g := NewGrid(l)
+	for n, p := range l.points {
+		d := parseDigit(i[n])
+		if d < 0 || d > l.Size {
+			return nil, fmt.Errorf("illegal character '%c' in encoded %s puzzle", i[n], l)
+		}
+		if d > 0 {
+			g.orig[p.r][p.c] = true
+			*g.pt(p) = 1 << d
+		}
+	}
+
+	return g, nil
+}
+
 // Randomize generates a random puzzle. There is no guarantee that the puzzle will be solvable or have just one solution.
 func Randomize() *Grid {
+	return randomize(nil)
+}
+
+// randomize fills the three diagonal boxes with shuffled digits taken from rnd (or the shared source if rnd is nil).
+func randomize(rnd *rand.Rand) *Grid {
 	g := Grid{}
 	for r := 0; r < rows; r++ {
 		for c := 0; c < cols; c++ {
@@ -104,11 +169,11 @@ func Randomize() *Grid {
 	}
 
 	indexes := []int{0, 1, 2}
-	rand.Shuffle(len(indexes), func(i, j int) { indexes[i], indexes[j] = indexes[j], indexes[i] })
+	shuffle(rnd, len(indexes), func(i, j int) { indexes[i], indexes[j] = indexes[j], indexes[i] })
 	for i, index := range indexes {
 		u := i*3 + index
 		d := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}
-		rand.Shuffle(len(d), func(i, j int) { d[i], d[j] = d[j], d[i] })
+		shuffle(rnd, len(d), func(i, j int) { d[i], d[j] = d[j], d[i] })
 		for pi, p := range box.unit[u] {
 			*g.pt(p) = 1 << d[pi]
 		}
@@ -117,19 +182,119 @@ func Randomize() *Grid {
 	return &g
 }
 
-func (g *Grid) allPoints() (res []pointCell) {
-	for r := zero; r < rows; r++ {
-		for c := zero; c < cols; c++ {
-			res = append(res, pointCell{point{r, c}, g.cells[r][c]})
+// Layout returns the layout of the grid.
+func (g *Grid) Layout() *Layout {
+	if g.layout == nil {
+		return standardLayout
+	}
+
+	return g.layout
+}
+
+// Standard returns true if the grid is on the plain 9 x 9 layout, as every grid of a game from Generate is.
+func (g *Grid) Standard() bool {
+	return g.layout == nil
+}
+
+// ErrNotStandard is returned by the methods that support only standard 9 x 9 grids, such as Canonical, Transform.Apply and Image, when they are given a grid that has a layout.
+var ErrNotStandard = errors.New("only standard 9 x 9 puzzles are supported")
+
+// size returns the number of digits of the grid's layout.
+func (g *Grid) size() int {
+	return g.Layout().Size
+}
+
+// height returns the number of rows of the grid's board.
+func (g *Grid) height() uint8 {
+	return uint8(g.Layout().Height)
+}
+
+// width returns the number of columns of the grid's board.
+func (g *Grid) width() uint8 {
+	return uint8(g.Layout().Width)
+}
+
+// Clues returns the number of givens in the grid.
+func (g *Grid) Clues() (res uint) {
+	for _, p := range g.Layout().points {
+		if g.orig[p.r][p.c] {
+			res++
 		}
 	}
 
 	return
 }
 
+func (g *Grid) allPoints() (res []pointCell) {
+	for _, p := range g.Layout().points {
+		res = append(res, pointCell{p, *g.pt(p)})
+	}
+
+	return
+}
+
+// eachGroup calls f with each group of units of the grid's layout (the boxes, columns and rows, and then any extra units such as diagonals) until f returns true. It returns whether f returned true.
+func (g *Grid) eachGroup(f func(gr *group) bool) bool {
+	for _, gr := range g.Layout().groups {
+		if f(gr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eachLine calls f with the columns and the rows of the grid, and then with the rows and the columns, until f returns true; a multi-grid layout such as Samurai has lines of its own for each 9 x 9 grid. It returns whether f returned true.
+func (g *Grid) eachLine(f func(major, minor *group) bool) bool {
+	for _, ls := range g.Layout().lines {
+		if f(ls[0], ls[1]) || f(ls[1], ls[0]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sees returns true if p1 and p2 are peers, so that they cannot hold the same digit.
+func (g *Grid) sees(p1, p2 point) bool {
+	l := g.Layout()
+	return l.sees[l.index(p1)][l.index(p2)]
+}
+
+// cellList returns the candidates of the grid numbered as the cells of its layout, as the layout solver and the cage and pair rules use them.
+func (g *Grid) cellList() []cell {
+	l := g.Layout()
+	res := make([]cell, l.Cells())
+	for _, p := range l.points {
+		res[l.index(p)] = *g.pt(p)
+	}
+
+	return res
+}
+
+// origList returns the givens of the grid numbered as the cells of its layout.
+func (g *Grid) origList() []bool {
+	l := g.Layout()
+	res := make([]bool, l.Cells())
+	for _, p := range l.points {
+		res[l.index(p)] = g.orig[p.r][p.c]
+	}
+
+	return res
+}
+
+// setCellList copies candidates numbered as the cells of the layout into the grid.
+func (g *Grid) setCellList(cells []cell) {
+	l := g.Layout()
+	for _, p := range l.points {
+		*g.pt(p) = cells[l.index(p)]
+	}
+}
+
 // cellChange is a convenience function that is called by strategy methods when a cell changes value.
 func (g *Grid) cellChange(res *bool, verbose uint, format string, a ...interface{}) {
 	*res = true
+	g.trace.note(format, a)
 	if verbose >= 1 {
 		fmt.Printf(format, a...)
 	}
@@ -140,6 +305,11 @@ func (g *Grid) cellChange(res *bool, verbose uint, format string, a ...interface
 
 // Display emits a grid to stdout in a framed format.
 func (g *Grid) Display() {
+	g.DisplayTo(os.Stdout)
+}
+
+// DisplayTo emits a grid to w in the framed format used by Display, with a frame around each box. The irregular regions of a jigsaw layout, and the grids of a Samurai, are framed by displayRegions.
+func (g *Grid) DisplayTo(w io.Writer) {
 	const (
 		botLeft  = "\u2514"
 		botRight = "\u2518"
@@ -153,62 +323,138 @@ func (g *Grid) Display() {
 		topT     = "\u252c"
 		vertBar  = "\u2502"
 
-		green  = "32"
 		yellow = "33"
 	)
 
-	width := g.maxWidth() + 2 // Add 2 for margins.
-	bars := strings.Repeat(horizBar, width*3)
-	line := leftT + strings.Join([]string{bars, bars, bars}, plus) + rightT
+	l := g.Layout()
+	width := g.maxWidth() + 2                     // Add 2 for margins.
+	label := len(strconv.Itoa(int(l.Height) - 1)) // The width of the row numbers.
+	if l.BoxRows == 0 || l.present != nil {
+		g.displayRegions(w, width, label)
+		return
+	}
+
+	stacks := l.Width / l.BoxCols
+	bars := make([]string, stacks)
+	for i := range bars {
+		bars[i] = strings.Repeat(horizBar, width*l.BoxCols)
+	}
+	frame := func(left, middle, right string) string {
+		return "\t" + strings.Repeat(" ", label+1) + left + strings.Join(bars, middle) + right + "\n"
+	}
 
 	// Top line with column headers.
-	fmt.Print("\t   ")
-	for d := 0; d < 9; d++ {
-		fmt.Printf("%s", colorize(yellow, center(strconv.Itoa(d), width)))
-		if d == 2 || d == 5 {
-			fmt.Print(" ")
+	fmt.Fprint(w, "\t"+strings.Repeat(" ", label+2))
+	for c := 0; c < l.Width; c++ {
+		fmt.Fprintf(w, "%s", colorize(yellow, center(strconv.Itoa(c), width)))
+		if (c+1)%l.BoxCols == 0 && c+1 < l.Width {
+			fmt.Fprint(w, " ")
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 
 	// First frame line.
-	fmt.Printf("\t  %s%s%s%s%s%s%s\n", topLeft, bars, topT, bars, topT, bars, topRight)
+	fmt.Fprint(w, frame(topLeft, topT, topRight))
 
 	// Grid rows.
-	for r := 0; r < rows; r++ {
-		fmt.Printf("\t%s %s", colorize(yellow, strconv.Itoa(r)), vertBar)
-		for c := 0; c < cols; c++ {
-			cell := g.cells[r][c]
-			orig := g.orig[r][c]
-			s := cell.String()
-			if s == "123456789" {
-				fmt.Printf("%s", center(".", width))
-			} else {
-				if orig {
-					fmt.Printf("%s", colorize(green, center(s, width)))
+	for r := 0; r < l.Height; r++ {
+		fmt.Fprintf(w, "\t%s %s", colorize(yellow, fmt.Sprintf("%*d", label, r)), vertBar)
+		for c := 0; c < l.Width; c++ {
+			fmt.Fprintf(w, "%s", g.displayCell(r, c, width))
+			if (c+1)%l.BoxCols == 0 && c+1 < l.Width {
+				fmt.Fprintf(w, "%s", vertBar)
+			}
+		}
+		fmt.Fprintf(w, "%s\n", vertBar)
+		if (r+1)%l.BoxRows == 0 && r+1 < l.Height {
+			fmt.Fprint(w, frame(leftT, plus, rightT))
+		}
+	}
+
+	// Bottom line.
+	fmt.Fprint(w, frame(botLeft, botT, botRight))
+}
+
+// displayCell returns the candidates of the cell at row r and column c centred in width characters: green for a given, a dot for a cell with every candidate, and blank for an absent cell.
+func (g *Grid) displayCell(r, c, width int) string {
+	const green = "32"
+
+	l := g.Layout()
+	cell := g.cells[r][c]
+	switch {
+	case !l.has(r*l.Width + c):
+		return strings.Repeat(" ", width)
+	case cell == l.all:
+		return center(".", width)
+	case g.orig[r][c]:
+		return colorize(green, center(cell.String(), width))
+	default:
+		return center(cell.String(), width)
+	}
+}
+
+// displayRegions emits a grid with irregular regions or absent cells to w. A bar between two cells, or under a cell, marks a region border; absent cells are left blank.
+func (g *Grid) displayRegions(w io.Writer, width, label int) {
+	const (
+		horizBar = "\u2500"
+		vertBar  = "\u2502"
+
+		yellow = "33"
+	)
+
+	l := g.Layout()
+	line := strings.Repeat(horizBar, l.Width*width+l.Width-1)
+	indent := "\t" + strings.Repeat(" ", label+1)
+
+	// Top line with column headers.
+	fmt.Fprint(w, indent+" ")
+	for c := 0; c < l.Width; c++ {
+		fmt.Fprintf(w, "%s ", colorize(yellow, center(strconv.Itoa(c), width)))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "%s\u250c%s\u2510\n", indent, line)
+
+	for r := 0; r < l.Height; r++ {
+		fmt.Fprintf(w, "\t%s %s", colorize(yellow, fmt.Sprintf("%*d", label, r)), vertBar)
+		for c := 0; c < l.Width; c++ {
+			i := r*l.Width + c
+			fmt.Fprint(w, g.displayCell(r, c, width))
+			if c+1 < l.Width {
+				if l.region[i] != l.region[i+1] {
+					fmt.Fprint(w, vertBar)
 				} else {
-					fmt.Printf("%s", center(s, width))
+					fmt.Fprint(w, " ")
 				}
 			}
-			if c == 2 || c == 5 {
-				fmt.Printf("%s", vertBar)
-			}
 		}
-		fmt.Printf("%s\n", vertBar)
-		if r == 2 || r == 5 {
-			fmt.Printf("\t  %s\n", line)
+		fmt.Fprintf(w, "%s\n", vertBar)
+
+		if r+1 < l.Height {
+			fmt.Fprintf(w, "%s%s", indent, vertBar)
+			for c := 0; c < l.Width; c++ {
+				i := r*l.Width + c
+				if l.region[i] != l.region[i+l.Width] {
+					fmt.Fprint(w, strings.Repeat(horizBar, width))
+				} else {
+					fmt.Fprint(w, strings.Repeat(" ", width))
+				}
+				if c+1 < l.Width {
+					fmt.Fprint(w, " ")
+				}
+			}
+			fmt.Fprintf(w, "%s\n", vertBar)
 		}
 	}
 
-	// Bottom line.
-	fmt.Printf("\t  %s%s%s%s%s%s%s\n", botLeft, bars, botT, bars, botT, bars, botRight)
+	fmt.Fprintf(w, "%s\u2514%s\u2518\n", indent, line)
 }
 
 // digitPlaces returns an array of digits containing values where the bits (1 - 9) are set if the corresponding digit appears in that cell.
-func (g *Grid) digitPlaces(points [9]point) (res [10]positions) {
+func (g *Grid) digitPlaces(points []point) (res [maxSize + 1]positions) {
 	for pi, p := range points {
 		cell := *g.pt(p)
-		for d := 1; d <= 9; d++ {
+		for d := 1; d <= g.size(); d++ {
 			if cell&(1<<d) != 0 {
 				res[d] |= 1 << pi
 			}
@@ -218,10 +464,10 @@ func (g *Grid) digitPlaces(points [9]point) (res [10]positions) {
 }
 
 // digitPoints builds a table of points that contain each digit.
-func (g *Grid) digitPoints(ps [9]point) (res [10][]point) {
+func (g *Grid) digitPoints(ps []point) (res [maxSize + 1][]point) {
 	for _, p := range ps {
 		cell := *g.pt(p)
-		for d := 1; d <= 9; d++ {
+		for d := 1; d <= g.size(); d++ {
 			if cell&(1<<d) != 0 {
 				res[d] = append(res[d], p)
 			}
@@ -233,32 +479,22 @@ func (g *Grid) digitPoints(ps [9]point) (res [10][]point) {
 
 // emptyCell returns true if the grid contains at least one empty cell (no digits set).
 func (g *Grid) emptyCell() bool {
-	for r := 0; r < rows; r++ {
-		for c := 0; c < cols; c++ {
-			if g.cells[r][c] == 0 {
-				return true
-			}
+	for _, p := range g.Layout().points {
+		if *g.pt(p) == 0 {
+			return true
 		}
 	}
 	return false
 }
 
-// Encode returns a "standard" (digits and zeroes) string represenation of a puzzle.
+// Encode returns a "standard" (digits and zeroes) string represenation of a puzzle. The digits above 9 of larger layouts are written as 'A' - 'G', and absent cells are left out, as read by ParseLayout.
 func (g *Grid) Encode() string {
 	var b strings.Builder
-	for r := zero; r < rows; r++ {
-		for c := zero; c < cols; c++ {
-			if g.orig[r][c] {
-				cell := *g.pt(point{r, c})
-				for d := 1; d <= 9; d++ {
-					if cell&(1<<d) != 0 {
-						fmt.Fprintf(&b, "%d", d)
-						break
-					}
-				}
-			} else {
-				fmt.Fprint(&b, "0")
-			}
+	for _, p := range g.Layout().points {
+		if g.orig[p.r][p.c] {
+			b.WriteByte(digitChar(g.pt(p).lowestSetBit()))
+		} else {
+			b.WriteByte('0')
 		}
 	}
 
@@ -276,53 +512,35 @@ func (g *Grid) encodeInts() []int {
 	return encoded
 }
 
-// maxWidth calculates the width in characters of the widest cell in the grid (maximum number of candidate digits). If the width is 9, it is changed to 1 because we will display only a dot ('.').
-func (g *Grid) maxWidth() int {
-	width := 0
-	for r := 0; r < rows; r++ {
-		for c := 0; c < cols; c++ {
-			count := bitCount[g.cells[r][c]]
-			if count == 9 {
-				count = 1
-			}
-			if width < count {
-				width = count
-			}
+// Values returns the digits of all cells, givens or not, using '0' for cells that are not yet solved, in the form of Encode.
+func (g *Grid) Values() string {
+	var b strings.Builder
+	for _, p := range g.Layout().points {
+		cell := *g.pt(p)
+		if bitCount[cell] == 1 {
+			b.WriteByte(digitChar(cell.lowestSetBit()))
+		} else {
+			b.WriteByte('0')
 		}
 	}
 
-	return width
+	return b.String()
 }
 
-// minPoint find the non-solved point with the least number of candidates and returns that point and true if found, otherwise it returns false.
-func (g *Grid) minPoint() (p point, found bool) {
-	min := 10
-	minPoints := make([]point, 0)
-	for r := zero; r < rows; r++ {
-		for c := zero; c < cols; c++ {
-			cell := g.cells[r][c]
-			count := bitCount[cell]
-			if count > 1 {
-				p = point{r, c}
-				if count < min {
-					min = count
-					minPoints = minPoints[:0]
-					minPoints = append(minPoints, p)
-					found = true
-				} else if count == min {
-					minPoints = append(minPoints, p)
-					found = true
-				}
-			}
+// maxWidth calculates the width in characters of the widest cell in the grid (maximum number of candidate digits). If a cell holds every digit, its width is 1 because we will display only a dot ('.').
+func (g *Grid) maxWidth() int {
+	width := 0
+	for _, p := range g.Layout().points {
+		count := bitCount[*g.pt(p)]
+		if count == g.size() {
+			count = 1
+		}
+		if width < count {
+			width = count
 		}
 	}
 
-	if found {
-		rand.Shuffle(len(minPoints), func(i, j int) { minPoints[i], minPoints[j] = minPoints[j], minPoints[i] })
-		return minPoints[0], true
-	}
-
-	return
+	return width
 }
 
 // pt returns the cell at a given point.
@@ -330,7 +548,7 @@ func (g *Grid) pt(p point) *cell {
 	return &g.cells[p.r][p.c]
 }
 
-// Reduce eliminates candidates from cells using logical methods. For example if a cell contains a single digit candidate, that digit can be removed from all other cells in the same box, row, and column.
+// Reduce eliminates candidates from cells using logical methods. For example if a cell contains a single digit candidate, that digit can be removed from all other cells in the same box, row, and column. Every strategy works on the units of the grid's layout (its boxes or jigsaw regions, columns, rows, and any extra units such as diagonals) and on the cells that see one another through them, through killer cages and through pair rules such as anti-king; the rules of a layout and its cages have strategies of their own as well.
 func (g *Grid) Reduce(all bool, strategies *map[string]bool, verbose uint) (Level, bool) {
 	maxLevel := Easy
 
@@ -346,6 +564,8 @@ func (g *Grid) Reduce(all bool, strategies *map[string]bool, verbose uint) (Leve
 		if g.reduceLevel(&maxLevel, Easy, verbose, strategies, []func(uint) bool{
 			g.nakedSingle,
 			g.hiddenSingle,
+			g.pairRules,
+			g.cageCombinations,
 		}) {
 			continue
 		}
@@ -365,6 +585,7 @@ func (g *Grid) Reduce(all bool, strategies *map[string]bool, verbose uint) (Leve
 			}
 
 			if g.reduceLevel(&maxLevel, Standard, verbose, strategies, []func(uint) bool{
+				g.innieOutie,
 				g.xWing,
 				g.yWing,
 				g.singlesChains,
@@ -404,7 +625,9 @@ func (g *Grid) Reduce(all bool, strategies *map[string]bool, verbose uint) (Leve
 
 func (g *Grid) reduceLevel(maxLevel *Level, level Level, verbose uint, strategies *map[string]bool, fs []func(uint) bool) bool {
 	for _, f := range fs {
+		g.trace.begin(g)
 		if f(verbose) {
+			g.trace.end(nameOfFunc(f), level, g)
 			if strategies != nil {
 				name := nameOfFunc(f)
 				(*strategies)[name] = true
@@ -421,60 +644,58 @@ func (g *Grid) reduceLevel(maxLevel *Level, level Level, verbose uint, strategie
 
 // Search uses a brute-force descent to solve the grid and returns a slice of grids that may be empty if no solution was found, may contain a single grid if a unique solution was found, or may contain more than one solution.
 func (g *Grid) Search(solutions *[]*Grid) {
-	if g.solved() {
-		*solutions = append(*solutions, g)
-		return
-	}
-
-	if g.emptyCell() {
-		return
-	}
+	g.search(solutions, nil)
+}
 
-	point, found := g.minPoint()
-	if !found {
+// search appends up to two solutions of the grid to solutions, trying candidates in an order taken from rnd (or in increasing order if rnd is nil).
+func (g *Grid) search(solutions *[]*Grid, rnd *rand.Rand) {
+	if g.layout != nil {
+		g.layoutSolutions(2, rnd, func(solution *Grid) bool {
+			*solutions = append(*solutions, solution)
+			return true
+		})
 		return
 	}
 
-	digits := g.pt(point).digits()
-	rand.Shuffle(len(digits), func(i, j int) { digits[i], digits[j] = digits[j], digits[i] })
-
-	for _, d := range digits {
-		cp := *g
-		*cp.pt(point) = 1 << d
-		_, solved := cp.Reduce(false, nil, 0)
+	s := solver{limit: 2, rnd: rnd, found: func(b *board) bool {
+		*solutions = append(*solutions, b.grid(g))
+		return true
+	}}
+	s.solve(g)
+}
 
-		if solved {
-			*solutions = append(*solutions, &cp)
-			if len(*solutions) > 1 {
-				return
-			}
-			continue
+// solved checks that a grid is completely solved (all units of its layout have each digit appearing exactly once, every cage adds up to its sum, and every pair rule holds).
+func (g *Grid) solved() bool {
+	l := g.Layout()
+	for _, p := range l.points {
+		if bitCount[*g.pt(p)] != 1 {
+			return false
 		}
+	}
 
-		cp.Search(solutions)
-		if len(*solutions) > 1 {
-			return
+	for _, gr := range l.groups {
+		if !g.solvedGroup(gr) {
+			return false
 		}
 	}
 
-	return
-}
+	if len(l.cages) == 0 && len(l.pairs) == 0 {
+		return true
+	}
 
-// solved checks that a grid is completely solved (all boxes, rows, and columns have each digit appearing exactly once).
-func (g *Grid) solved() bool {
-	for r := zero; r < rows; r++ {
-		for c := zero; c < cols; c++ {
-			if bitCount[g.cells[r][c]] != 1 {
-				return false
-			}
+	cells := g.cellList()
+	for _, c := range l.cages {
+		if !c.holds(cells, nil, true) {
+			return false
 		}
 	}
-	return g.solvedGroup(&box) && g.solvedGroup(&col) && g.solvedGroup(&row)
+
+	return l.pairsHold(cells, nil)
 }
 
 func (g *Grid) solvedGroup(gr *group) bool {
 	for _, ps := range gr.unit {
-		cells := [10]int{}
+		cells := [maxSize + 1]int{}
 		for _, p := range ps {
 			cell := *g.pt(p)
 
@@ -486,14 +707,14 @@ func (g *Grid) solvedGroup(gr *group) bool {
 				return false
 			}
 
-			for d := 1; d <= 9; d++ {
+			for d := 1; d <= g.size(); d++ {
 				if cell&(1<<d) != 0 {
 					cells[d]++
 				}
 			}
 		}
 
-		for d := 1; d <= 9; d++ {
+		for d := 1; d <= g.size(); d++ {
 			if cells[d] != 1 {
 				return false
 			}
@@ -503,14 +724,32 @@ func (g *Grid) solvedGroup(gr *group) bool {
 	return true
 }
 
-// Valid returns true if the grid contains at most one occurance of each digit in each unit.
+// Valid returns true if the grid contains at most one occurance of each given digit in each unit and cage, the givens of each cage do not exceed its sum, and no two givens break a pair rule.
 func (g *Grid) Valid() bool {
-	return g.validGroup(&box) && g.validGroup(&col) && g.validGroup(&row)
+	l := g.Layout()
+	for _, gr := range l.groups {
+		if !g.validGroup(gr) {
+			return false
+		}
+	}
+
+	if len(l.cages) == 0 && len(l.pairs) == 0 {
+		return true
+	}
+
+	cells, orig := g.cellList(), g.origList()
+	for _, c := range l.cages {
+		if !c.holds(cells, orig, false) {
+			return false
+		}
+	}
+
+	return l.pairsHold(cells, orig)
 }
 
 func (g *Grid) validGroup(gr *group) bool {
 	for _, u := range gr.unit {
-		var seen [10]bool
+		var seen [maxSize + 1]bool
 		for _, p := range u {
 			if !g.orig[p.r][p.c] {
 				continue
@@ -526,83 +765,157 @@ func (g *Grid) validGroup(gr *group) bool {
 	return true
 }
 
+// Generate builds a puzzle from a random seed. The same seed always yields the same puzzle and solution. It returns nil if the random layout has no solution or if the reduced puzzle cannot be solved logically.
+func Generate(seed int64) *Game {
+	rnd := rand.New(rand.NewSource(seed))
+
+	grid := randomize(rnd)
+	solutions := make([]*Grid, 0, 2)
+	grid.search(&solutions, rnd)
+	if len(solutions) == 0 { // The grid has no solution.
+		return nil
+	}
+
+	// From https://stackoverflow.com/a/7280517/96233.
+
+	*grid = *solutions[0]                                                                    // Copy the first solution
+	points := grid.allPoints()                                                               // Get all points from the first solution.
+	rnd.Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] }) // Shuffle them.
+	grid.dig(points, grid.unavoidableSets(digSetSize, digSetDigits))
+
+	// At this point, grid contains the smallest solution that is unique. Now we rate it.
+	cp := *grid
+	strategies := make(map[string]bool)
+	l, solved := cp.Reduce(true, &strategies, 0)
+	solutions = solutions[:0]
+	cp.search(&solutions, rnd)
+	if !solved || len(solutions) != 1 {
+		return nil
+	}
+
+	solution := solutions[0]
+	var clues uint
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if bitCount[grid.cells[r][c]] == 1 {
+				solution.orig[r][c] = true
+				clues++
+			}
+		}
+	}
+
+	var s []string
+	for n := range strategies {
+		s = append(s, n)
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+
+	grid.orig = solution.orig
+
+	return &Game{l, clues, s, grid, solution, seed}
+}
+
 // Worker generates puzzles. It removes a requested puzzle level from the tasks channel and attempts to generate a puzzle at the level. If it succeeds, it pushes the puzzle to the results channel. If it cannot generate a puzzle, it pushes nil.
 func Worker(tasks chan Level, results chan *Game) {
 outer:
 	for level := range tasks {
-		maxAttempts := attempts
-
-	inner:
-		for {
-			grid := Randomize()
-			solutions := make([]*Grid, 0, 2)
-			grid.Search(&solutions)
-			if len(solutions) == 0 { // The grid has no solution.
-				maxAttempts--
-				if maxAttempts == 0 { // If too many attempts, push a nil and start again with a new level.
-					results <- nil
-					continue outer
-				}
-
-				continue inner
+		for maxAttempts := attempts; maxAttempts > 0; maxAttempts-- {
+			if game := Generate(rand.Int63()); game != nil && game.Level == level {
+				results <- game
+				continue outer
 			}
+		}
 
-			// From https://stackoverflow.com/a/7280517/96233.
+		// If too many attempts, push a nil and start again with a new level.
+		results <- nil
+	}
+}
 
-			*grid = *solutions[0]                                                                     // Copy the first solution
-			points := grid.allPoints()                                                                // Get all points from the first solution.
-			rand.Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] }) // Shuffle them.
+// GenerateLayout builds a puzzle on a layout from a random seed; the standard layout (or nil) is handled by Generate. The same seed always yields the same puzzle and solution. Givens are removed in a random order while the puzzle keeps a single solution, and then, wherever Reduce gets stuck, the digit of a random unsolved cell is given back, so that the puzzle is rated by the strategies it needs. It returns nil if no solution was found. If the layout was given random cages by SetRandomCages, the puzzle and solution use a copy of the layout in which the cells outside its own cages are covered by new ones, and nil is returned if they cannot be added.
+func GenerateLayout(l *Layout, seed int64) *Game {
+	if l == nil || l.standard() {
+		return Generate(seed)
+	}
 
-			for len(points) > 0 {
-				curr := points[0]
-				points = points[1:]
-				*grid.pt(curr.point) = all // Clear the cell.
+	rnd := rand.New(rand.NewSource(seed))
 
-				solutions = solutions[:0]
-				grid.Search(&solutions)
+	solution := l.fill(rnd)
+	if solution == nil {
+		return nil
+	}
 
-				if len(solutions) > 1 { // No longer unique.
-					*grid.pt(curr.point) = curr.cell // Put the value back.
-				}
+	if l.killer {
+		l = l.clone()
+		l.killer = false
+		if err := l.coverCages(solution.cellList(), rnd); err != nil {
+			return nil
+		}
+		solution.layout = l
+	}
+
+	puzzle := *solution
+	for _, p := range l.points {
+		puzzle.orig[p.r][p.c] = true
+	}
+	for _, i := range rnd.Perm(len(l.points)) {
+		p := l.points[i]
+		puzzle.cells[p.r][p.c] = l.all
+		puzzle.orig[p.r][p.c] = false
+		if !puzzle.unique() {
+			puzzle.cells[p.r][p.c] = solution.cells[p.r][p.c]
+			puzzle.orig[p.r][p.c] = true
+		}
+	}
+
+	cp := puzzle
+	strategies := make(map[string]bool)
+	level, solved := cp.Reduce(true, &strategies, 0)
+	for !solved {
+		var open []point
+		for _, p := range l.points {
+			if bitCount[*cp.pt(p)] > 1 {
+				open = append(open, p)
 			}
+		}
+		if len(open) == 0 { // The strategies left a cell with no candidates.
+			return nil
+		}
 
-			// At this point, grid contains the smallest solution that is unique. Now we test the level.
-			cp := *grid
-			strategies := make(map[string]bool)
-			l, solved := cp.Reduce(true, &strategies, 0)
-			solutions = solutions[:0]
-			cp.Search(&solutions)
-			if solved && l == level && len(solutions) == 1 {
-				solution := solutions[0]
-				var clues uint
-				for r := 0; r < rows; r++ {
-					for c := 0; c < cols; c++ {
-						if bitCount[grid.cells[r][c]] == 1 {
-							solution.orig[r][c] = true
-							clues++
-						}
-					}
-				}
+		p := open[rnd.Intn(len(open))]
+		for _, g := range []*Grid{&puzzle, &cp} {
+			g.cells[p.r][p.c] = solution.cells[p.r][p.c]
+			g.orig[p.r][p.c] = true
+		}
 
-				var s []string
-				for n := range strategies {
-					s = append(s, n)
-				}
-				sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+		var lv Level
+		if lv, solved = cp.Reduce(true, &strategies, 0); level < lv {
+			level = lv
+		}
+	}
 
-				grid.orig = solution.orig
+	solution.orig = puzzle.orig
 
-				results <- &Game{level, clues, s, grid, solution}
-				continue outer
-			}
+	var s []string
+	for n := range strategies {
+		s = append(s, n)
+	}
+	sort.Strings(s)
 
-			// If we could not find a unique solution, try again.
-			maxAttempts--
-			if maxAttempts == 0 { // If too many attempts, push a nil and start again with a new level.
-				results <- nil
+	return &Game{level, puzzle.Clues(), s, &puzzle, solution, seed}
+}
+
+// LayoutWorker generates puzzles on a layout in the same way as Worker. It removes a requested puzzle level from the tasks channel and pushes a puzzle at that level to the results channel, or nil if it cannot generate one.
+func LayoutWorker(l *Layout, tasks chan Level, results chan *Game) {
+outer:
+	for level := range tasks {
+		for maxAttempts := attempts; maxAttempts > 0; maxAttempts-- {
+			if game := GenerateLayout(l, rand.Int63()); game != nil && game.Level == level {
+				results <- game
 				continue outer
 			}
 		}
+
+		results <- nil
 	}
 }
 
@@ -645,6 +958,16 @@ func decodeInts(encoded []int) *Grid {
 	return &g
 }
 
+// shuffle permutes n elements using rnd, or the shared source if rnd is nil.
+func shuffle(rnd *rand.Rand, n int, swap func(i, j int)) {
+	if rnd == nil {
+		rand.Shuffle(n, swap)
+		return
+	}
+
+	rnd.Shuffle(n, swap)
+}
+
 func nameOfFunc(f func(uint) bool) string {
 	name := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
 	i := strings.LastIndex(name, ".")
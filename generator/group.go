@@ -26,6 +26,7 @@ package generator
 
 import (
 	"fmt"
+	"math/bits"
 	"strings"
 )
 
@@ -35,7 +36,7 @@ type (
 		unit
 	}
 
-	unit [rows][cols]point
+	unit [][]point
 
 	uint128 struct {
 		ms, ls uint64
@@ -51,6 +52,13 @@ var (
 )
 
 func init() {
+	for _, gr := range []*group{&box, &col, &row} {
+		gr.unit = make([][]point, rows)
+		for i := range gr.unit {
+			gr.unit[i] = make([]point, cols)
+		}
+	}
+
 	for r := zero; r < rows; r++ {
 		for c := zero; c < cols; c++ {
 			p := point{r, c}
@@ -106,6 +114,40 @@ func (u uint128) and(other uint128) uint128 {
 	return uint128{u.ms & other.ms, u.ls & other.ls}
 }
 
+func (u uint128) andNot(other uint128) uint128 {
+	return uint128{u.ms &^ other.ms, u.ls &^ other.ls}
+}
+
+func (u uint128) count() int {
+	return bits.OnesCount64(u.ms) + bits.OnesCount64(u.ls)
+}
+
+func (u uint128) empty() bool {
+	return u.ms == 0 && u.ls == 0
+}
+
+func (u uint128) has(p point) bool {
+	bit := p.r*9 + p.c
+	if bit < 64 {
+		return u.ls&(1<<bit) != 0
+	}
+
+	return u.ms&(1<<(bit-64)) != 0
+}
+
+func (u uint128) or(other uint128) uint128 {
+	return uint128{u.ms | other.ms, u.ls | other.ls}
+}
+
+// points returns the points whose bits are set, in row-major order.
+func (u uint128) points() (res []point) {
+	u.process(func(r, c uint8) {
+		res = append(res, point{r, c})
+	})
+
+	return
+}
+
 func (u uint128) process(f func(uint8, uint8)) {
 	for r := zero; r < rows; r++ {
 		for c := zero; c < cols; c++ {
@@ -147,6 +189,17 @@ func (u uint128) String() string {
 	return b.String() //fmt.Sprintf("%64.64b%64.64b", u.ms, u.ls)
 }
 
+func (u *uint128) set(p point) *uint128 {
+	bit := p.r*9 + p.c
+	if bit < 64 {
+		u.ls |= 1 << bit
+	} else {
+		u.ms |= 1 << (bit - 64)
+	}
+
+	return u
+}
+
 func (u *uint128) unset(p point) *uint128 {
 	bit := p.r*9 + p.c
 	if bit < 64 {
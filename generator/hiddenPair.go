@@ -26,16 +26,15 @@ package generator
 
 // hiddenPair removes other digits from a pair of cells in a group (box, column, row) when that pair contains the only occurrances of the digits in the group and returns true if it changes any cells.
 func (g *Grid) hiddenPair(verbose uint) bool {
-	return g.hiddenPairGroup(&box, verbose) || g.hiddenPairGroup(&col, verbose) || g.hiddenPairGroup(&row, verbose)
-
+	return g.eachGroup(func(gr *group) bool { return g.hiddenPairGroup(gr, verbose) })
 }
 
 func (g *Grid) hiddenPairGroup(gr *group, verbose uint) (res bool) {
 	for ui, u := range gr.unit {
 		points := g.digitPoints(u)
 
-		for d1 := 1; d1 <= 9; d1++ {
-			for d2 := 1; d2 <= 9; d2++ {
+		for d1 := 1; d1 <= g.size(); d1++ {
+			for d2 := 1; d2 <= g.size(); d2++ {
 				if d1 == d2 || len(points[d1]) != 2 || len(points[d2]) != 2 {
 					continue
 				}
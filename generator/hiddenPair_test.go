@@ -67,3 +67,17 @@ func TestHiddenPairRow(t *testing.T) {
 		3, 249, 45, 6, 7, 59, 3, 2, 46, 146, 149, 8, 145, 23, 6, 7, 5, 2348, 248, 248, 1, 9, 159,
 		59, 4, 7, 268, 168, 268, 3, 258, 15, 238, 28, 146, 23, 9, 7, 456, 45}, g.encodeInts())
 }
+
+func TestHiddenPairDiagonal(t *testing.T) {
+	l, _ := NewLayout(9)
+	l.AddDiagonals()
+	g := NewGrid(l)
+	for i := 1; i < 8; i++ {
+		*g.pt(point{uint8(i), uint8(i)}) &^= 1<<1 | 1<<2
+	}
+
+	assert.True(t, g.hiddenPair(0))
+	assert.Equal(t, cell(1<<1|1<<2), *g.pt(point{0, 0}))
+	assert.Equal(t, cell(1<<1|1<<2), *g.pt(point{8, 8}))
+	assert.Equal(t, l.all, *g.pt(point{0, 8}))
+}
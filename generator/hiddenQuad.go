@@ -26,21 +26,21 @@ package generator
 
 // hiddenQuad removes other digits from a quad of cells in a group (box, column, row) when that quad contains the only occurrances of the digits in the group. It returns true if it changes any cells.
 func (g *Grid) hiddenQuad(verbose uint) bool {
-	return g.hiddenQuadGroup(&box, verbose) || g.hiddenQuadGroup(&col, verbose) || g.hiddenQuadGroup(&row, verbose)
+	return g.eachGroup(func(gr *group) bool { return g.hiddenQuadGroup(gr, verbose) })
 }
 
 func (g *Grid) hiddenQuadGroup(gr *group, verbose uint) (res bool) {
 	for ui, u := range gr.unit {
 		places := g.digitPlaces(u)
 
-		for d1 := 1; d1 <= 9; d1++ {
+		for d1 := 1; d1 <= g.size(); d1++ {
 			p1 := places[d1]
 			count := bitCount[p1]
 			if count == 1 || count > 4 {
 				continue
 			}
 
-			for d2 := 1; d2 <= 9; d2++ {
+			for d2 := 1; d2 <= g.size(); d2++ {
 				if d1 == d2 {
 					continue
 				}
@@ -51,7 +51,7 @@ func (g *Grid) hiddenQuadGroup(gr *group, verbose uint) (res bool) {
 					continue
 				}
 
-				for d3 := 1; d3 <= 9; d3++ {
+				for d3 := 1; d3 <= g.size(); d3++ {
 					if d1 == d3 || d2 == d3 {
 						continue
 					}
@@ -62,7 +62,7 @@ func (g *Grid) hiddenQuadGroup(gr *group, verbose uint) (res bool) {
 						continue
 					}
 
-					for d4 := 1; d4 <= 9; d4++ {
+					for d4 := 1; d4 <= g.size(); d4++ {
 						if d1 == d4 || d2 == d4 || d3 == d4 {
 							continue
 						}
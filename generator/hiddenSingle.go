@@ -26,14 +26,14 @@ package generator
 
 // hiddenSingle solves a cell if it contains the only instance of a digit within its group (box, column, row) and returns true if it changes any cells.
 func (g *Grid) hiddenSingle(verbose uint) bool {
-	return g.hiddenSingleGroup(&box, verbose) || g.hiddenSingleGroup(&col, verbose) || g.hiddenSingleGroup(&row, verbose)
+	return g.eachGroup(func(gr *group) bool { return g.hiddenSingleGroup(gr, verbose) })
 }
 
 func (g *Grid) hiddenSingleGroup(gr *group, verbose uint) (res bool) {
 	for ui, u := range gr.unit {
 		points := g.digitPoints(u)
 
-		for d := 1; d <= 9; d++ {
+		for d := 1; d <= g.size(); d++ {
 			if len(points[d]) == 1 {
 				p := points[d][0]
 				if g.pt(p).setTo(1 << d) {
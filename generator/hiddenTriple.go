@@ -26,21 +26,21 @@ package generator
 
 // hiddenTriple removes other digits from a triple of cells in a group (box, column, row) when that triple contains the only occurrances of the digits in the group. It returns true if it changes any cells.
 func (g *Grid) hiddenTriple(verbose uint) bool {
-	return g.hiddenTripleGroup(&box, verbose) || g.hiddenTripleGroup(&col, verbose) || g.hiddenTripleGroup(&row, verbose)
+	return g.eachGroup(func(gr *group) bool { return g.hiddenTripleGroup(gr, verbose) })
 }
 
 func (g *Grid) hiddenTripleGroup(gr *group, verbose uint) (res bool) {
 	for ui, u := range gr.unit {
 		places := g.digitPlaces(u)
 
-		for d1 := 1; d1 <= 9; d1++ {
+		for d1 := 1; d1 <= g.size(); d1++ {
 			p1 := places[d1]
 			count := bitCount[p1]
 			if count == 1 || count > 3 {
 				continue
 			}
 
-			for d2 := 1; d2 <= 9; d2++ {
+			for d2 := 1; d2 <= g.size(); d2++ {
 				if d1 == d2 {
 					continue
 				}
@@ -51,7 +51,7 @@ func (g *Grid) hiddenTripleGroup(gr *group, verbose uint) (res bool) {
 					continue
 				}
 
-				for d3 := 1; d3 <= 9; d3++ {
+				for d3 := 1; d3 <= g.size(); d3++ {
 					if d1 == d3 || d2 == d3 {
 						continue
 					}
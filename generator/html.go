@@ -25,7 +25,10 @@
 package generator
 
 import (
+	"fmt"
 	"html/template"
+	"io"
+	"math"
 	"strconv"
 	"strings"
 
@@ -47,13 +50,19 @@ const html = `
 </html>
 `
 
-// HTML generates the HTML for a grid. The HTML will contain embedded SVG for the actual grid.
-func (g *Grid) HTML(showCandidates bool, colors *[rows][cols][10]color) {
-	s := g.SVG(2.0, false, showCandidates, colors)
+// The geometry of the SVG of a grid, which the PDF writer shares: a square of svgSize units holding cells of svgCell units inside a margin of svgMargin, with digits of svgDigit units whose baseline is svgBaseline units below the top of their cell.
+const (
+	svgSize     = 500
+	svgMargin   = 25
+	svgCell     = 50
+	svgDigit    = 25
+	svgBaseline = 35
+)
 
+// HTML generates the HTML for a grid and displays it in the default browser. The HTML will contain embedded SVG for the actual grid.
+func (g *Grid) HTML(showCandidates bool, colors *[maxRows][maxCols][maxSize + 1]color) {
 	var b strings.Builder
-	t := template.Must(template.New("html").Parse(html))
-	if err := t.Execute(&b, struct{ Body template.HTML }{template.HTML(s)}); err != nil {
+	if err := g.WriteHTML(&b, showCandidates, colors); err != nil {
 		panic(err)
 	}
 
@@ -62,81 +71,341 @@ func (g *Grid) HTML(showCandidates bool, colors *[rows][cols][10]color) {
 	}
 }
 
+// WriteHTML writes the HTML page displayed by HTML to w, so that it can be saved where there is no browser.
+func (g *Grid) WriteHTML(w io.Writer, showCandidates bool, colors *[maxRows][maxCols][maxSize + 1]color) error {
+	s := g.SVG(2.0, false, showCandidates, colors)
+
+	t := template.Must(template.New("html").Parse(html))
+	return t.Execute(w, struct{ Body template.HTML }{template.HTML(s)})
+}
+
 // SVG returns the standard vector graphics representation for a grid.
-func (g *Grid) SVG(scale float64, invert bool, showCandidates bool, colors *[rows][cols][10]color) string {
-	const (
-		xoffset    = 25
-		yoffset    = 25
-		gridWidth  = 450
-		gridHeight = 450
-	)
+func (g *Grid) SVG(scale float64, invert bool, showCandidates bool, colors *[maxRows][maxCols][maxSize + 1]color) string {
+	return g.SVGWith(SVGOptions{Scale: scale, Invert: invert}, showCandidates, colors)
+}
+
+// SVGWith returns the vector graphics representation for a grid drawn with the geometry, fonts and colours of opts. The candidates of unsolved cells are shown if showCandidates is set, coloured by colors if it is not nil.
+func (g *Grid) SVGWith(opts SVGOptions, showCandidates bool, colors *[maxRows][maxCols][maxSize + 1]color) string {
+	return g.svgWith(opts, showCandidates, colors, nil)
+}
+
+// svgWith draws the SVG of SVGWith, annotated with the pattern cells, changed cells and chain links of step if it is not nil. Grids on every layout are drawn alike: thick lines separate the boxes or jigsaw regions (absent cells, such as those between the grids of a Samurai, are left blank), the cells of shaded units such as diagonals are shaded, killer cages are outlined with dotted lines and labelled with their sums, and a key under the grid shows the cells that each pair rule, such as anti-king, relates.
+func (g *Grid) svgWith(opts SVGOptions, showCandidates bool, colors *[maxRows][maxCols][maxSize + 1]color, step *Step) string {
+	opts.defaults()
+
 	var (
-		width  = 500 * scale
-		height = 500 * scale
+		l      = g.Layout()
+		cell   = opts.CellSize
+		margin = opts.Margin
+		width  = l.Width*cell + 2*margin
+		height = l.Height*cell + 2*margin
+		per    = int(math.Ceil(math.Sqrt(float64(l.Size)))) // Candidates per row within a cell.
 		b      strings.Builder
 	)
 
+	// The key to the pair rules goes under the grid, in as many lines as it needs.
+	keyHeight := 4 * cell / 5
+	keyPlaces, keyLines := l.pairKeyPlaces(keyHeight, width-2*margin)
+	height += keyLines * 3 * keyHeight / 2
+
 	canvas := s.New(&b)
-	canvas.Start(int(width), int(height))
-	canvas.Scale(scale)
-	if invert {
-		canvas.Gtransform("translate(500, 500) rotate(180)")
-	}
-	canvas.Rect(0, 0, 500, 500, "fill:white")
-	canvas.Grid(xoffset, yoffset, gridWidth, gridHeight, gridWidth/9, "stroke:black")
-	canvas.Grid(xoffset, yoffset, gridWidth, gridHeight, gridWidth/3, "stroke:black;stroke-width:5;stroke-linecap:round")
-
-	for r := 0; r < rows; r++ {
-		for c := 0; c < cols; c++ {
-			cell := *&g.cells[r][c]
-			digits := cell.String()
-			if len(digits) == 1 {
-				var color string
-				if g.orig[r][c] {
-					color = ";fill:green"
-				} else {
-					color = ";fill:black"
-				}
+	canvas.Start(int(float64(width)*opts.Scale), int(float64(height)*opts.Scale))
+	if opts.Classes {
+		canvas.Style("text/css", opts.css())
+	}
+	canvas.Scale(opts.Scale)
+	if opts.Invert {
+		canvas.Gtransform(fmt.Sprintf("translate(%d, %d) rotate(180)", width, height))
+	}
+	canvas.Rect(0, 0, width, height, opts.attr("background"))
+	for _, i := range l.shaded() {
+		canvas.Rect(margin+i%l.Width*cell, margin+i/l.Width*cell, cell, cell, opts.attr("shade"))
+	}
+	if step != nil {
+		for _, p := range step.Pattern {
+			canvas.Rect(margin+p.Col*cell, margin+p.Row*cell, cell, cell, opts.attr("pattern"))
+		}
+		for _, p := range step.Changed() {
+			canvas.Rect(margin+p.Col*cell, margin+p.Row*cell, cell, cell, opts.attr("target"))
+		}
+	}
+
+	g.svgLines(canvas, &opts)
+
+	if opts.Labels {
+		for i := 0; i < l.Width; i++ {
+			canvas.Text(margin+i*cell+cell/2, margin-margin/3, strconv.Itoa(i+1), opts.attr("label"))
+		}
+		for i := 0; i < l.Height; i++ {
+			canvas.Text(margin/2, margin+i*cell+cell/2+opts.LabelSize/3, string(rune('A'+i)), opts.attr("label"))
+		}
+	}
 
-				canvas.Text(c*50+xoffset+25, r*50+yoffset+35, digits, "font:25px sans-serif;;text-anchor:middle"+color)
-			} else if showCandidates {
-				for d := 1; d <= 9; d++ {
-					if cell&(1<<d) != 0 {
-						cr := (d - 1) / 3
-						cc := (d - 1) % 3
-						var color string
-						if colors != nil && colors[r][c][d] != black {
-							switch colors[r][c][d] {
-							case blue:
-								color = ";fill:blue"
-							case red:
-								color = ";fill:red"
-							default:
-								color = ";fill:black"
-							}
-						} else {
-							color = ";fill:black"
-						}
-
-						canvas.Text(c*50+xoffset+10+cc*15, r*50+yoffset+13+cr*15, strconv.Itoa(d), "font:8px sans-serif;;text-anchor:middle"+color)
+	for _, p := range l.points {
+		r, c := int(p.r), int(p.c)
+		x, y := margin+c*cell, margin+r*cell
+		if bitCount[g.cells[r][c]] == 1 {
+			kind := "digit"
+			if g.orig[r][c] {
+				kind = "given"
+			}
+
+			canvas.Text(x+cell/2, y+cell*svgBaseline/svgCell, g.cells[r][c].String(), opts.attr(kind))
+		} else if showCandidates {
+			for _, d := range g.cells[r][c].digits() {
+				kind := "candidate"
+				if colors != nil {
+					switch colors[r][c][d] {
+					case blue:
+						kind = "blue"
+					case red:
+						kind = "red"
 					}
 				}
+
+				// The candidates are spread evenly over the cell, which for nine of them puts them 15 units apart.
+				cr, cc := (d-1)/per, (d-1)%per
+				canvas.Text(x+(10+cc*30/(per-1))*cell/svgCell, y+(13+cr*30/(per-1))*cell/svgCell, string(digitChar(d)), opts.attr(kind))
 			}
 		}
 	}
-	if invert {
+	g.svgCages(canvas, &opts)
+	g.svgPairKey(canvas, &opts, margin, l.Height*cell+margin+keyHeight/2, keyHeight, keyPlaces)
+	if step != nil && len(step.Links) > 0 {
+		svgLinks(canvas, &opts, step.Links)
+	}
+	if opts.Invert {
 		canvas.Gend()
 	}
 	canvas.Gend()
 
 	canvas.End()
 
-	s := b.String()
+	res := b.String()
 
-	i := strings.Index(s, "\n")
+	i := strings.Index(res, "\n")
 	if i > 0 {
-		s = s[i+1:]
+		res = res[i+1:]
+	}
+
+	return res
+}
+
+// svgLines draws the lines of the grid: thin ones between the cells and thick ones around the boxes or jigsaw regions of its layout and along the edges of the board. Rectangular boxes are drawn with lines the length of the board.
+func (g *Grid) svgLines(canvas *s.SVG, opts *SVGOptions) {
+	var (
+		l      = g.Layout()
+		cell   = opts.CellSize
+		margin = opts.Margin
+	)
+
+	if l.present == nil && l.BoxRows > 0 {
+		for _, thick := range []bool{false, true} {
+			if thick {
+				canvas.Group(opts.attr("thick"))
+			} else {
+				canvas.Group(opts.attr("thin"))
+			}
+			for i := 0; i <= l.Width || i <= l.Height; i++ {
+				if i <= l.Width && (i%l.BoxCols == 0) == thick {
+					canvas.Line(margin+i*cell, margin, margin+i*cell, margin+l.Height*cell)
+				}
+				if i <= l.Height && (i%l.BoxRows == 0) == thick {
+					canvas.Line(margin, margin+i*cell, margin+l.Width*cell, margin+i*cell)
+				}
+			}
+			canvas.Gend()
+		}
+		return
+	}
+
+	if l.present == nil {
+		svgGrid(canvas, margin, margin, l.Width*cell, l.Height*cell, cell, opts.attr("thin"))
+	} else {
+		for _, p := range l.points {
+			canvas.Rect(margin+int(p.c)*cell, margin+int(p.r)*cell, cell, cell, opts.attr("cell"))
+		}
+	}
+
+	// regionAt returns the region of the cell at row r and column c, or -1 if there is no cell there.
+	regionAt := func(r, c int) int {
+		if r < 0 || r >= l.Height || c < 0 || c >= l.Width {
+			return -1
+		}
+		return l.region[r*l.Width+c]
+	}
+
+	canvas.Group(opts.attr("thick"))
+	for _, p := range l.points {
+		r, c := int(p.r), int(p.c)
+		region := l.region[r*l.Width+c]
+		x, y := margin+c*cell, margin+r*cell
+		if regionAt(r, c-1) != region {
+			canvas.Line(x, y, x, y+cell)
+		}
+		if regionAt(r, c+1) < 0 {
+			canvas.Line(x+cell, y, x+cell, y+cell)
+		}
+		if regionAt(r-1, c) != region {
+			canvas.Line(x, y, x+cell, y)
+		}
+		if regionAt(r+1, c) < 0 {
+			canvas.Line(x, y+cell, x+cell, y+cell)
+		}
+	}
+	canvas.Gend()
+}
+
+// svgGrid draws the lines of a grid of w by h units with cells n units apart, its top left corner at x and y, in a group styled by attr. It is Grid of svgo, which cannot take a class.
+func svgGrid(canvas *s.SVG, x, y, w, h, n int, attr string) {
+	canvas.Group(attr)
+	for ix := x; ix <= x+w; ix += n {
+		canvas.Line(ix, y, ix, y+h)
+	}
+	for iy := y; iy <= y+h; iy += n {
+		canvas.Line(x, iy, x+w, iy)
+	}
+	canvas.Gend()
+}
+
+// svgLinks draws the links of a chain as arrows between the candidates they join: solid for strong links and dashed for weak ones.
+func svgLinks(canvas *s.SVG, opts *SVGOptions, links []Link) {
+	var (
+		cell   = float64(opts.CellSize)
+		margin = float64(opts.Margin)
+		inset  = 6 * cell / svgCell // Keeps the ends of an arrow clear of the candidates it joins.
+		id     = opts.ClassPrefix + "arrow"
+	)
+
+	// centre returns the middle of candidate d in the cell at p.
+	centre := func(p Position, d int) (float64, float64) {
+		cr, cc := (d-1)/3, (d-1)%3
+		return margin + float64(p.Col)*cell + float64(10+cc*15)*cell/svgCell, margin + float64(p.Row)*cell + float64(10+cr*15)*cell/svgCell
 	}
 
-	return s
+	canvas.Def()
+	canvas.Marker(id, 8, 4, 8, 8, `orient="auto"`, `markerUnits="userSpaceOnUse"`)
+	canvas.Path("M0,0 L8,4 L0,8 Z", opts.attr("arrow"))
+	canvas.MarkerEnd()
+	canvas.DefEnd()
+
+	for _, l := range links {
+		x1, y1 := centre(l.From, l.Digit)
+		x2, y2 := centre(l.To, l.Digit)
+		length := math.Hypot(x2-x1, y2-y1)
+		if length <= 2*inset {
+			continue
+		}
+		dx, dy := (x2-x1)*inset/length, (y2-y1)*inset/length
+
+		kind := "weak"
+		if l.Strong {
+			kind = "strong"
+		}
+		canvas.Line(int(math.Round(x1+dx)), int(math.Round(y1+dy)), int(math.Round(x2-dx)), int(math.Round(y2-dy)), opts.attr(kind), fmt.Sprintf(`marker-end="url(#%s)"`, id))
+	}
+}
+
+// pairKeyPlaces lays out the key drawn by svgPairKey, with entries of the given height, in lines no wider than width. It returns the position of each entry relative to the top left of the key and the number of lines.
+func (l *Layout) pairKeyPlaces(height, width int) (res []Position, lines int) {
+	x := 0
+	for i := range l.pairs {
+		p := &l.pairs[i]
+		w := (2*p.reach()+1)*(height/(2*p.reach()+1)) + height/5*len(p.name) + 5 // The name is written in letters about a fifth of height wide.
+		if x > 0 && x+w > width {
+			x = 0
+			lines++
+		}
+		res = append(res, Position{lines * 3 * height / 2, x})
+		x += w + 15
+	}
+	if len(res) > 0 {
+		lines++
+	}
+
+	return res, lines
+}
+
+// svgPairKey draws a key to the pair rules of the layout with opts at the places returned by pairKeyPlaces, offset by x and y. Each rule is shown by a small grid, of the given height, around a cell marked with a dot, in which the cells that the rule relates to it are shaded or, for non-consecutive rules, barred where they meet it, followed by the name of the rule.
+func (g *Grid) svgPairKey(canvas *s.SVG, opts *SVGOptions, x, y, height int, places []Position) {
+	l := g.Layout()
+	for i, at := range places {
+		p := &l.pairs[i]
+		reach := p.reach()
+		n := 2*reach + 1
+		cellSize := height / n
+		left, top := x+at.Col, y+at.Row
+		middle := reach*cellSize + cellSize/2 // The centre of the marked cell, from the corner of the small grid.
+
+		for _, o := range p.offsets {
+			if p.rule == NonConsecutive && o.Row*o.Row+o.Col*o.Col == 1 {
+				mx, my := left+middle+o.Col*cellSize/2, top+middle+o.Row*cellSize/2
+				dx, dy := o.Row*cellSize/3, o.Col*cellSize/3
+				canvas.Line(mx-dx, my-dy, mx+dx, my+dy, opts.attr("bar"))
+				continue
+			}
+
+			canvas.Rect(left+(reach+o.Col)*cellSize, top+(reach+o.Row)*cellSize, cellSize, cellSize, opts.attr("shade"))
+		}
+		svgGrid(canvas, left, top, n*cellSize, n*cellSize, cellSize, opts.attr("thin"))
+		canvas.Circle(left+middle, top+middle, cellSize/4, opts.attr("mark"))
+		canvas.Text(left+n*cellSize+5, top+height/2+height/8, p.name, opts.attr("key"))
+	}
+}
+
+// svgCages draws with opts a dotted outline just inside the border of each killer cage, with the sum of the cage in the corner of its first cell.
+func (g *Grid) svgCages(canvas *s.SVG, opts *SVGOptions) {
+	var (
+		l        = g.Layout()
+		cellSize = opts.CellSize
+		margin   = opts.Margin
+		inset    = 4 * cellSize / svgCell
+		cageOf   = l.cageOf()
+	)
+
+	for ci, c := range l.cages {
+		canvas.Group(opts.attr("cage"))
+		for _, i := range c.cells {
+			r, col := i/l.Width, i%l.Width
+			x0, y0 := col*cellSize+margin, r*cellSize+margin
+			x1, y1 := x0+cellSize, y0+cellSize
+
+			same := func(dr, dc int) bool {
+				nr, nc := r+dr, col+dc
+				return nr >= 0 && nr < l.Height && nc >= 0 && nc < l.Width && cageOf[nr*l.Width+nc] == ci
+			}
+
+			// Each side of the cell is drawn inset unless the neighbour on that side is in the same cage, in which case the line runs on to the edge of the cell.
+			left, right, top, bottom := x0+inset, x1-inset, y0+inset, y1-inset
+			if same(0, -1) {
+				left = x0
+			}
+			if same(0, 1) {
+				right = x1
+			}
+			if same(-1, 0) {
+				top = y0
+			}
+			if same(1, 0) {
+				bottom = y1
+			}
+
+			if !same(-1, 0) {
+				canvas.Line(left, y0+inset, right, y0+inset)
+			}
+			if !same(1, 0) {
+				canvas.Line(left, y1-inset, right, y1-inset)
+			}
+			if !same(0, -1) {
+				canvas.Line(x0+inset, top, x0+inset, bottom)
+			}
+			if !same(0, 1) {
+				canvas.Line(x1-inset, top, x1-inset, bottom)
+			}
+		}
+		canvas.Gend()
+
+		first := c.cells[0]
+		canvas.Text(first%l.Width*cellSize+margin+inset+2, first/l.Width*cellSize+margin+inset+opts.CandidateSize+2, strconv.Itoa(c.sum), opts.attr("sum"))
+	}
 }
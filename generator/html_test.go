@@ -0,0 +1,90 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteHTML(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+
+	var b strings.Builder
+	assert.NoError(t, g.WriteHTML(&b, false, nil))
+	assert.True(t, strings.HasPrefix(strings.TrimSpace(b.String()), "<!DOCTYPE html>"))
+	assert.Contains(t, b.String(), g.SVG(2.0, false, false, nil))
+}
+
+func TestSVGWith(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+
+	svg := g.SVG(1, false, false, nil)
+	assert.Contains(t, svg, `<svg width="500" height="500"`)
+	assert.Contains(t, svg, "fill:green")
+	assert.NotContains(t, svg, "<style")
+	assert.Equal(t, svg, g.SVGWith(SVGOptions{}, false, nil))
+
+	svg = g.SVGWith(SVGOptions{CellSize: 40, Margin: 30, Invert: true, Font: "serif", Labels: true}, false, nil)
+	assert.Contains(t, svg, `<svg width="420" height="420"`)
+	assert.Contains(t, svg, "translate(420, 420) rotate(180)")
+	assert.Contains(t, svg, "font:20px serif")
+	assert.Contains(t, svg, ">A</text>")
+	assert.Contains(t, svg, ">9</text>")
+
+	svg = g.SVGWith(SVGOptions{Dark: true, Classes: true, ClassPrefix: "p-", Palette: SVGPalette{Given: "orange"}, ThickLine: 3}, false, nil)
+	assert.Contains(t, svg, ".p-background { fill:#121212 }")
+	assert.Contains(t, svg, ".p-given { font:25px sans-serif;text-anchor:middle;fill:orange }")
+	assert.Contains(t, svg, "stroke-width:3;")
+	assert.Contains(t, svg, `class="p-given"`)
+	assert.NotContains(t, svg, "style=")
+
+	// A font cannot close the style attribute or element, or add declarations.
+	svg = g.SVGWith(SVGOptions{Font: `"Times New Roman", serif;fill:red" onload="alert(1)`}, false, nil)
+	assert.Contains(t, svg, `style="font:25px Times New Roman, seriffillred onloadalert1;text-anchor:middle;fill:green"`)
+	assert.NotContains(t, svg, "onload=")
+	svg = g.SVGWith(SVGOptions{Font: "x }</style><script>alert(1)</script>", Classes: true}, false, nil)
+	assert.NotContains(t, svg, "<script")
+	assert.Contains(t, svg, "font:25px x stylescriptalert1script;")
+	svg = g.SVGWith(SVGOptions{Font: `"";`}, false, nil)
+	assert.Contains(t, svg, "font:25px sans-serif;")
+
+	// Grids on other layouts take the same options.
+	l, _ := NewLayout(6)
+	l.AddDiagonals()
+	l.AddAntiKing()
+	assert.NoError(t, l.AddCage(Cage{3, []Position{{0, 0}, {0, 1}}}))
+	svg = NewGrid(l).SVGWith(SVGOptions{CellSize: 40, Dark: true, Classes: true, Labels: true}, true, nil)
+	assert.Contains(t, svg, `<svg width="290" height="338"`)
+	assert.Contains(t, svg, ".sudoku-shade { fill:#424242 }")
+	assert.Contains(t, svg, `class="sudoku-cage"`)
+	assert.Contains(t, svg, `class="sudoku-key"`)
+	assert.Contains(t, svg, ">F</text>")
+	assert.NotContains(t, svg, "style=")
+}
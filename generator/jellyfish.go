@@ -26,7 +26,7 @@ package generator
 
 // jellyfish finds and removes candidates. A jellyfish is a 4 by 4 sixteen-cell pattern, where is in each column (or row), a candidate is only found in four different rows (or columns). The candidate can be removed from all other columns (or row) that line up with the four rows. jellyfish extends swordfish from 3 units to 4.
 func (g *Grid) jellyfish(verbose uint) bool {
-	return g.jellyfishGroup(&col, verbose) || g.jellyfishGroup(&row, verbose)
+	return g.eachLine(func(major, _ *group) bool { return g.jellyfishGroup(major, verbose) })
 }
 
 func (g *Grid) jellyfishGroup(gr *group, verbose uint) (res bool) {
@@ -54,7 +54,7 @@ func (g *Grid) jellyfishGroup(gr *group, verbose uint) (res bool) {
 
 					digits4 := g.digitPlaces(p4s)
 
-					for d := 1; d <= 9; d++ {
+					for d := 1; d <= g.size(); d++ {
 						d1 := digits1[d]
 						d2 := digits2[d]
 						d3 := digits3[d]
@@ -0,0 +1,222 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// SetRegions replaces the boxes of a layout with irregular regions, making it a jigsaw layout. The map has one character per cell in row-major order; cells with the same character are in the same region. There must be Size regions of Size cells each, and each region must be connected. It must be called before the layout is used by any puzzle.
+func (l *Layout) SetRegions(m string) error {
+	if len(l.grids) > 1 {
+		return fmt.Errorf("the %s layout has more than one grid and cannot have jigsaw regions", l)
+	}
+	if len(m) != l.Cells() {
+		return fmt.Errorf("region map for a %dx%d layout must contain %d characters", l.Width, l.Height, l.Cells())
+	}
+
+	ids := make(map[byte]int)
+	region := make([]int, len(m))
+	for i := 0; i < len(m); i++ {
+		id, ok := ids[m[i]]
+		if !ok {
+			id = len(ids)
+			ids[m[i]] = id
+		}
+		region[i] = id
+	}
+
+	if len(ids) != l.Size {
+		return fmt.Errorf("region map has %d regions (should be %d)", len(ids), l.Size)
+	}
+
+	counts := make([]int, l.Size)
+	for _, r := range region {
+		counts[r]++
+	}
+	for r, n := range counts {
+		if n != l.Size {
+			return fmt.Errorf("region %d of the map has %d cells (should be %d)", r, n, l.Size)
+		}
+		if !l.connected(region, r) {
+			return fmt.Errorf("region %d of the map is not connected", r)
+		}
+	}
+
+	// Drop the old boxes, which follow the rows and columns, and add the new regions in their place.
+	units := l.units[:0]
+	for _, u := range l.units {
+		if u.kind != "box" {
+			units = append(units, u)
+		}
+	}
+	l.units = units
+	l.region = region
+	l.addRegions()
+
+	l.BoxRows, l.BoxCols = 0, 0
+	l.grids = nil
+	l.rules = append(l.rules, "jigsaw")
+	l.link()
+
+	return nil
+}
+
+// Regions returns the region map of the layout in the form read by SetRegions, using digitChar for regions 1 - Size.
+func (l *Layout) Regions() string {
+	return regionMap(l.region)
+}
+
+// regionMap returns the map of the regions of each cell, numbering the regions from 1 in the order that they first appear.
+func regionMap(region []int) string {
+	ids := make(map[int]int)
+	var b strings.Builder
+	for _, r := range region {
+		id, ok := ids[r]
+		if !ok {
+			id = len(ids) + 1
+			ids[r] = id
+		}
+		b.WriteByte(digitChar(id))
+	}
+
+	return b.String()
+}
+
+// RandomRegions returns a random jigsaw region map for a size x size layout, built by repeatedly exchanging cells between neighbouring boxes while keeping every region connected. The same seed always yields the same map.
+func RandomRegions(size int, seed int64) (string, error) {
+	l, err := NewLayout(size)
+	if err != nil {
+		return "", err
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	region := append([]int(nil), l.region...)
+	for swaps := 0; swaps < size*size; {
+		// Pick a cell in one region with a neighbour in another, and a cell of the first region that also borders the second.
+		a := rnd.Intn(len(region))
+		na := l.neighbours(a)
+		b := na[rnd.Intn(len(na))]
+		ra, rb := region[a], region[b]
+		if ra == rb {
+			continue
+		}
+
+		var cs []int
+		for c, r := range region {
+			if r != ra {
+				continue
+			}
+			for _, n := range l.neighbours(c) {
+				if region[n] == rb && n != b {
+					cs = append(cs, c)
+					break
+				}
+			}
+		}
+		if len(cs) == 0 {
+			continue
+		}
+		c := cs[rnd.Intn(len(cs))]
+
+		// Moving b into the first region and c into the second keeps both regions the same size.
+		region[b], region[c] = ra, rb
+		if !l.connected(region, ra) || !l.connected(region, rb) {
+			region[b], region[c] = rb, ra
+			continue
+		}
+
+		swaps++
+	}
+
+	return regionMap(region), nil
+}
+
+// connected returns true if the cells of region r form a single orthogonally connected area.
+func (l *Layout) connected(region []int, r int) bool {
+	start, size := -1, 0
+	for i, ri := range region {
+		if ri == r {
+			if start < 0 {
+				start = i
+			}
+			size++
+		}
+	}
+	if start < 0 {
+		return true
+	}
+
+	seen := make([]bool, len(region))
+	seen[start] = true
+	stack := []int{start}
+	reached := 1
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, n := range l.neighbours(i) {
+			if !seen[n] && region[n] == r {
+				seen[n] = true
+				reached++
+				stack = append(stack, n)
+			}
+		}
+	}
+
+	return reached == size
+}
+
+// neighbours returns the cells of the layout orthogonally adjacent to cell i.
+func (l *Layout) neighbours(i int) []int {
+	r, c := i/l.Width, i%l.Width
+	res := make([]int, 0, 4)
+	if r > 0 {
+		res = append(res, i-l.Width)
+	}
+	if r < l.Height-1 {
+		res = append(res, i+l.Width)
+	}
+	if c > 0 {
+		res = append(res, i-1)
+	}
+	if c < l.Width-1 {
+		res = append(res, i+1)
+	}
+
+	if l.present != nil {
+		n := 0
+		for _, j := range res {
+			if l.present[j] {
+				res[n] = j
+				n++
+			}
+		}
+		res = res[:n]
+	}
+
+	return res
+}
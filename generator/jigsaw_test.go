@@ -0,0 +1,80 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRegions(t *testing.T) {
+	l, _ := NewLayout(4)
+	assert.Error(t, l.SetRegions("aabb"))
+	assert.Error(t, l.SetRegions("aaaabbbbccccdddd"[:15]+"e"))
+	assert.Error(t, l.SetRegions("aaaaabbbccccdddd"))
+	assert.Error(t, l.SetRegions("abaabbabccccdddd")) // Regions a and b are not connected.
+
+	assert.NoError(t, l.SetRegions("aaabcabbccdbcddd"))
+	assert.Equal(t, "1112312233423444", l.Regions())
+	assert.Equal(t, "4x4 jigsaw", l.String())
+	assert.Len(t, l.units, 12)
+	assert.Empty(t, l.grids)
+
+	for _, u := range l.units[8:] {
+		assert.True(t, strings.HasPrefix(u.name, "box "))
+		assert.Len(t, u.cells, 4)
+	}
+}
+
+func TestRandomRegions(t *testing.T) {
+	for _, size := range []int{6, 9} {
+		m, err := RandomRegions(size, 1)
+		assert.NoError(t, err)
+
+		same, _ := RandomRegions(size, 1)
+		assert.Equal(t, m, same)
+
+		l, _ := NewLayout(size)
+		assert.NoError(t, l.SetRegions(m))
+		assert.Equal(t, m, l.Regions())
+
+		var game *Game
+		for seed := int64(1); game == nil; seed++ {
+			game = GenerateLayout(l, seed)
+		}
+		assert.True(t, game.Solution.solved())
+		assert.Equal(t, 1, game.Puzzle.CountSolutions(0))
+
+		var b bytes.Buffer
+		game.Puzzle.DisplayTo(&b)
+		assert.Equal(t, 1+1+2*size-1+1, strings.Count(b.String(), "\n"))
+	}
+
+	_, err := RandomRegions(5, 1)
+	assert.Error(t, err)
+}
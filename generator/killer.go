@@ -0,0 +1,564 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Cage is a killer sudoku cage: a connected set of cells whose digits must all differ and add up to Sum.
+	Cage struct {
+		Sum   int
+		Cells []Position
+	}
+
+	// cage is the form of a Cage kept by a Layout, with the cells numbered as in the layout and the sets of distinct digits that add up to the sum.
+	cage struct {
+		sum    int
+		cells  []int
+		combos []cell
+	}
+)
+
+// cageLabels are the characters used for the cages in the map returned by EncodeCages.
+const cageLabels = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// AddCage adds a killer cage to the layout. The cells must be connected, must lie in the layout, and must not already be in a cage, and some set of distinct digits must add up to the sum. It must be called before the layout is used by any puzzle.
+func (l *Layout) AddCage(c Cage) error {
+	cells := make([]int, len(c.Cells))
+	for i, p := range c.Cells {
+		if p.Row < 0 || p.Row >= l.Height || p.Col < 0 || p.Col >= l.Width {
+			return fmt.Errorf("cage cell %s is outside the %s layout", p, l)
+		}
+		cells[i] = p.Row*l.Width + p.Col
+	}
+
+	if err := l.addCage(c.Sum, cells); err != nil {
+		return err
+	}
+	l.link()
+
+	return nil
+}
+
+// SetCages adds the killer cages described by s, which has the form returned by EncodeCages: a map with one character per cell, where each orthogonally connected group of cells with the same character is a cage and '.' marks a cell outside every cage, then a colon and the sums of the cages separated by commas. The cages are ordered by their first cell in row-major order.
+func (l *Layout) SetCages(s string) error {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return fmt.Errorf("cages must be a map followed by ':' and the sums")
+	}
+	m, sums := s[:i], strings.Split(s[i+1:], ",")
+
+	if len(m) != l.Cells() {
+		return fmt.Errorf("cage map for a %dx%d layout must contain %d characters", l.Width, l.Height, l.Cells())
+	}
+
+	// Label each connected group of equal characters, in the order of their first cells.
+	group := make([]int, len(m))
+	for i := range group {
+		group[i] = -1
+	}
+	var cages [][]int
+	for start := range m {
+		if m[start] == '.' || group[start] >= 0 {
+			continue
+		}
+
+		cells := []int{start}
+		group[start] = len(cages)
+		for k := 0; k < len(cells); k++ {
+			for _, n := range l.neighbours(cells[k]) {
+				if group[n] < 0 && m[n] == m[start] {
+					group[n] = len(cages)
+					cells = append(cells, n)
+				}
+			}
+		}
+		sort.Ints(cells)
+		cages = append(cages, cells)
+	}
+
+	if len(cages) != len(sums) {
+		return fmt.Errorf("cage map has %d cages but %d sums", len(cages), len(sums))
+	}
+
+	for ci, cells := range cages {
+		sum, err := strconv.Atoi(strings.TrimSpace(sums[ci]))
+		if err != nil {
+			return fmt.Errorf("illegal cage sum %q", sums[ci])
+		}
+		if err := l.addCage(sum, cells); err != nil {
+			return err
+		}
+	}
+	l.link()
+
+	return nil
+}
+
+// SetRandomCages makes GenerateLayout cover each puzzle that it generates with new random cages, added to a copy of the layout, to make killer sudoku.
+func (l *Layout) SetRandomCages() {
+	l.killer = true
+	l.addRule("killer")
+}
+
+// Cages returns the killer cages of the layout, ordered by their first cell.
+func (l *Layout) Cages() []Cage {
+	res := make([]Cage, len(l.cages))
+	for ci, c := range l.cages {
+		res[ci].Sum = c.sum
+		for _, i := range c.cells {
+			res[ci].Cells = append(res[ci].Cells, Position{i / l.Width, i % l.Width})
+		}
+	}
+
+	return res
+}
+
+// EncodeCages returns the cages of the layout in the form read by SetCages, labelling the cages so that neighbouring cages differ. It returns "" if the layout has no cages.
+func (l *Layout) EncodeCages() string {
+	if len(l.cages) == 0 {
+		return ""
+	}
+
+	cageOf := l.cageOf()
+	label := make([]byte, len(l.cages))
+	var sums []string
+	for ci, c := range l.cages {
+		var used [len(cageLabels)]bool
+		for _, i := range c.cells {
+			for _, n := range l.neighbours(i) {
+				if o := cageOf[n]; o >= 0 && o < ci {
+					used[strings.IndexByte(cageLabels, label[o])] = true
+				}
+			}
+		}
+		for li := range used {
+			if !used[li] {
+				label[ci] = cageLabels[li]
+				break
+			}
+		}
+		sums = append(sums, strconv.Itoa(c.sum))
+	}
+
+	var b strings.Builder
+	for _, ci := range cageOf {
+		if ci < 0 {
+			b.WriteByte('.')
+		} else {
+			b.WriteByte(label[ci])
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(strings.Join(sums, ","))
+
+	return b.String()
+}
+
+// addCage checks and adds a cage without linking the layout. Cages are kept in the order of their first cells.
+func (l *Layout) addCage(sum int, cells []int) error {
+	if len(cells) == 0 || len(cells) > l.Size {
+		return fmt.Errorf("a cage must have between 1 and %d cells", l.Size)
+	}
+
+	cells = append([]int(nil), cells...)
+	sort.Ints(cells)
+
+	cageOf := l.cageOf()
+	region := make([]int, l.Cells())
+	for i, c := range cells {
+		if !l.has(c) {
+			return fmt.Errorf("cell %s is not part of the %s layout", l.position(c), l)
+		}
+		if cageOf[c] >= 0 {
+			return fmt.Errorf("cell %s is already in a cage", l.position(c))
+		}
+		if i > 0 && c == cells[i-1] {
+			return fmt.Errorf("cell %s appears twice in a cage", l.position(c))
+		}
+		region[c] = 1
+	}
+	if !l.connected(region, 1) {
+		return fmt.Errorf("cage at %s is not connected", l.position(cells[0]))
+	}
+
+	combos := l.combos(len(cells), sum)
+	if len(combos) == 0 {
+		return fmt.Errorf("no %d different digits add up to %d", len(cells), sum)
+	}
+
+	c := cage{sum, cells, combos}
+	i := sort.Search(len(l.cages), func(i int) bool { return l.cages[i].cells[0] > cells[0] })
+	l.cages = append(l.cages, cage{})
+	copy(l.cages[i+1:], l.cages[i:])
+	l.cages[i] = c
+	l.addRule("killer")
+
+	return nil
+}
+
+// addRule adds a name to the rules of the layout unless it is already there.
+func (l *Layout) addRule(name string) {
+	for _, r := range l.rules {
+		if r == name {
+			return
+		}
+	}
+
+	l.rules = append(l.rules, name)
+}
+
+// cageOf returns the index of the cage containing each cell, or -1 for cells outside every cage.
+func (l *Layout) cageOf() []int {
+	res := make([]int, l.Cells())
+	for i := range res {
+		res[i] = -1
+	}
+	for ci, c := range l.cages {
+		for _, i := range c.cells {
+			res[i] = ci
+		}
+	}
+
+	return res
+}
+
+// combos returns the sets of n different digits that add up to sum.
+func (l *Layout) combos(n, sum int) (res []cell) {
+	var choose func(from, n, sum int, m cell)
+	choose = func(from, n, sum int, m cell) {
+		if n == 0 {
+			if sum == 0 {
+				res = append(res, m)
+			}
+			return
+		}
+
+		for d := from; d <= l.Size && d <= sum; d++ {
+			choose(d+1, n-1, sum-d, m|1<<d)
+		}
+	}
+	choose(1, n, sum, 0)
+
+	return
+}
+
+// position returns the row and column of a cell.
+func (l *Layout) position(i int) Position {
+	return Position{i / l.Width, i % l.Width}
+}
+
+// clone returns a copy of the layout that can be changed without affecting the original.
+func (l *Layout) clone() *Layout {
+	c := *l
+	c.rules = append([]string(nil), l.rules...)
+	c.region = append([]int(nil), l.region...)
+	c.units = append([]layoutUnit(nil), l.units...)
+	c.cages = append([]cage(nil), l.cages...)
+	c.pairs = append([]pairSet(nil), l.pairs...)
+
+	return &c
+}
+
+// coverCages covers every cell of the layout that is not already in a cage with random cages of up to five cells whose digits in solution all differ. It returns an error if a cage cannot be added, leaving the layout unchanged.
+func (l *Layout) coverCages(solution []cell, rnd *rand.Rand) error {
+	cageOf := l.cageOf()
+	caged := len(l.cages)
+
+	var cages [][]int
+	for _, start := range rnd.Perm(l.Cells()) {
+		if cageOf[start] >= 0 || !l.has(start) {
+			continue
+		}
+
+		size := 2 + rnd.Intn(4)
+		cells := []int{start}
+		cageOf[start] = caged + len(cages)
+		digits := solution[start]
+		for len(cells) < size {
+			var options []int
+			for _, c := range cells {
+				for _, n := range l.neighbours(c) {
+					if cageOf[n] < 0 && solution[n]&digits == 0 {
+						options = append(options, n)
+					}
+				}
+			}
+			if len(options) == 0 {
+				break
+			}
+
+			n := options[rnd.Intn(len(options))]
+			cells = append(cells, n)
+			cageOf[n] = caged + len(cages)
+			digits |= solution[n]
+		}
+
+		cages = append(cages, cells)
+	}
+
+	prev := l.cages
+	l.cages = append([]cage(nil), l.cages...)
+	for _, cells := range cages {
+		sum := 0
+		for _, i := range cells {
+			sum += solution[i].lowestSetBit()
+		}
+		if err := l.addCage(sum, cells); err != nil {
+			l.cages = prev
+			return err
+		}
+	}
+	l.link()
+
+	return nil
+}
+
+// cageSums removes the candidates of each cage's cells that appear in no possible set of digits for the cage, assigning any cell that is left with a single candidate. It returns whether anything changed and false for ok on a contradiction.
+func (l *Layout) cageSums(cells []cell) (changed, ok bool) {
+	for _, c := range l.cages {
+		allowed := c.allowed(cells)
+		if allowed == 0 {
+			return changed, false
+		}
+
+		for _, i := range c.cells {
+			if cells[i]&^allowed == 0 {
+				continue
+			}
+
+			cells[i] &= allowed
+			changed = true
+			if cells[i] == 0 {
+				return changed, false
+			}
+			if bitCount[cells[i]] == 1 && !l.assign(cells, i, cells[i]) {
+				return changed, false
+			}
+		}
+	}
+
+	return changed, true
+}
+
+// allowed returns the digits of the sets of digits for a cage that contain the cage's solved digits and that every cell of the cage can take part in.
+func (c *cage) allowed(cells []cell) cell {
+	var placed, union cell
+	for _, i := range c.cells {
+		union |= cells[i]
+		if bitCount[cells[i]] == 1 {
+			placed |= cells[i]
+		}
+	}
+
+	var allowed cell
+	for _, combo := range c.combos {
+		if combo&placed != placed || combo&union != combo {
+			continue
+		}
+
+		fits := true
+		for _, i := range c.cells {
+			if cells[i]&combo == 0 {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			allowed |= combo
+		}
+	}
+
+	return allowed
+}
+
+// holds returns false if the digits given or placed in a cage repeat or cannot add up to its sum. If complete is true, every cell of the cage must be solved and the digits must add up to the sum.
+func (c *cage) holds(cells []cell, orig []bool, complete bool) bool {
+	var seen cell
+	sum, solved := 0, 0
+	for _, i := range c.cells {
+		if orig != nil && !orig[i] || bitCount[cells[i]] != 1 {
+			continue
+		}
+		if seen&cells[i] != 0 {
+			return false
+		}
+		seen |= cells[i]
+		sum += cells[i].lowestSetBit()
+		solved++
+	}
+
+	if complete {
+		return solved == len(c.cells) && sum == c.sum
+	}
+
+	return sum <= c.sum && (solved < len(c.cells) || sum == c.sum)
+}
+
+// cageCombinations removes candidates that appear in no set of distinct digits that could fill a cage and add up to its sum.
+func (g *Grid) cageCombinations(verbose uint) bool {
+	l := g.Layout()
+	if len(l.cages) == 0 {
+		return false
+	}
+
+	cells := g.cellList()
+	res := false
+	for _, c := range l.cages {
+		allowed := c.allowed(cells)
+		for _, i := range c.cells {
+			p := l.point(i)
+			if removed := *g.pt(p) &^ allowed; removed != 0 && g.pt(p).andNot(removed) {
+				g.cellChange(&res, verbose, "cageCombinations: cage at %s adding up to %d allows only %s, removed %s from %s\n", l.point(c.cells[0]), c.sum, allowed, removed, p)
+			}
+		}
+	}
+
+	return res
+}
+
+// innieOutie applies the rule of 45 (the digits of a unit add up to 1 + 2 + ... + Size). The cells of a unit outside the cages that lie entirely inside it (the innies) add up to the unit's total less the sums of those cages. When the cages touching a unit cover it, the cells of those cages outside the unit (the outies) add up to the sums of the cages less the unit's total. For up to three innies or outies, candidates that cannot make up the total are removed.
+func (g *Grid) innieOutie(verbose uint) bool {
+	l := g.Layout()
+	if len(l.cages) == 0 {
+		return false
+	}
+
+	cageOf := l.cageOf()
+	total := l.Size * (l.Size + 1) / 2
+
+	res := false
+	for _, u := range l.units {
+		touching := make(map[int]bool)
+		covered := true
+		for _, i := range u.cells {
+			if cageOf[i] >= 0 {
+				touching[cageOf[i]] = true
+			} else {
+				covered = false
+			}
+		}
+
+		insideSum, touchingSum := 0, 0
+		inside := make(map[int]bool)
+		var outies []int
+		for ci := range touching {
+			c := l.cages[ci]
+			touchingSum += c.sum
+			in := true
+			for _, i := range c.cells {
+				if !containsInt(u.cells, i) {
+					in = false
+					outies = append(outies, i)
+				}
+			}
+			if in {
+				inside[ci] = true
+				insideSum += c.sum
+			}
+		}
+
+		var innies []int
+		for _, i := range u.cells {
+			if cageOf[i] < 0 || !inside[cageOf[i]] {
+				innies = append(innies, i)
+			}
+		}
+
+		if n := len(innies); n >= 1 && n <= 3 && g.restrictSum(innies, total-insideSum, verbose, "innies", u.name) {
+			res = true
+		}
+		if n := len(outies); covered && n >= 1 && n <= 3 && g.restrictSum(outies, touchingSum-total, verbose, "outies", u.name) {
+			res = true
+		}
+	}
+
+	return res
+}
+
+// restrictSum removes the candidates of cells that cannot be part of any choice of digits for the cells that adds up to sum, where cells that are peers must differ.
+func (g *Grid) restrictSum(cells []int, sum int, verbose uint, kind, unit string) bool {
+	l := g.Layout()
+	sort.Ints(cells)
+
+	var supported = make([]cell, len(cells))
+	digits := make([]int, len(cells))
+	var choose func(k, left int)
+	choose = func(k, left int) {
+		if k == len(cells) {
+			if left == 0 {
+				for j, d := range digits {
+					supported[j] |= 1 << d
+				}
+			}
+			return
+		}
+
+		for _, d := range g.pt(l.point(cells[k])).digits() {
+			if d > left {
+				break
+			}
+
+			clash := false
+			for j := 0; j < k; j++ {
+				if digits[j] == d && l.sees[cells[k]][cells[j]] {
+					clash = true
+					break
+				}
+			}
+			if !clash {
+				digits[k] = d
+				choose(k+1, left-d)
+			}
+		}
+	}
+	choose(0, sum)
+
+	res := false
+	for j, i := range cells {
+		p := l.point(i)
+		if removed := *g.pt(p) &^ supported[j]; removed != 0 && g.pt(p).andNot(removed) {
+			g.cellChange(&res, verbose, "innieOutie: %s %v of %s add up to %d, removed %s from %s\n", kind, l.unitPoints(cells), unit, sum, removed, p)
+		}
+	}
+
+	return res
+}
+
+func containsInt(s []int, v int) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,150 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombos(t *testing.T) {
+	l, _ := NewLayout(9)
+	assert.Equal(t, []cell{1<<1 | 1<<2}, l.combos(2, 3))
+	assert.Equal(t, []cell{1<<7 | 1<<8 | 1<<9}, l.combos(3, 24))
+	assert.Equal(t, []cell{l.all}, l.combos(9, 45))
+	assert.Len(t, l.combos(2, 10), 4)
+	assert.Empty(t, l.combos(2, 18))
+}
+
+func TestAddCage(t *testing.T) {
+	l, _ := NewLayout(4)
+	assert.Error(t, l.AddCage(Cage{3, []Position{{0, 0}, {0, 4}}}))
+	assert.Error(t, l.AddCage(Cage{3, []Position{{0, 0}, {1, 1}}}))
+	assert.Error(t, l.AddCage(Cage{9, []Position{{0, 0}, {0, 1}}}))
+	assert.Error(t, l.AddCage(Cage{3, nil}))
+
+	assert.NoError(t, l.AddCage(Cage{3, []Position{{0, 1}, {0, 0}}}))
+	assert.Error(t, l.AddCage(Cage{5, []Position{{0, 1}, {0, 2}}}))
+	assert.Equal(t, "4x4 killer", l.String())
+	assert.Equal(t, []Cage{{3, []Position{{0, 0}, {0, 1}}}}, l.Cages())
+	assert.Equal(t, "aa..............:3", l.EncodeCages())
+
+	// The cells of a cage are peers.
+	assert.Contains(t, l.peers[0], 1)
+	assert.Len(t, l.peers[0], 7)
+}
+
+func TestSetCages(t *testing.T) {
+	l, _ := NewLayout(4)
+	assert.Error(t, l.SetCages("aabb"))
+	assert.Error(t, l.SetCages("aabbccddaabbccdd:3,7"))
+	assert.Error(t, l.SetCages("aabbccddaabbccdd:3,7,x,5,5,5"))
+
+	// The two groups of b's are separate cages.
+	assert.NoError(t, l.SetCages("aabbccddbbaaddcc:3,7,4,6,5,5,5,5"))
+	assert.Len(t, l.cages, 8)
+	assert.Equal(t, "aabbbbaaaabbbbaa:3,7,4,6,5,5,5,5", l.EncodeCages()) // Neighbouring cages get different labels.
+
+	k, _ := NewLayout(4)
+	assert.NoError(t, k.SetCages(l.EncodeCages()))
+	assert.Equal(t, l.Cages(), k.Cages())
+}
+
+func TestInnieOutie(t *testing.T) {
+	l, _ := NewLayout(4)
+	assert.NoError(t, l.AddCage(Cage{3, []Position{{0, 0}, {0, 1}}}))
+	assert.NoError(t, l.AddCage(Cage{5, []Position{{0, 2}, {1, 2}}}))
+
+	// The innies of row 0, (0, 2) and (0, 3), add up to 10 - 3.
+	g := NewGrid(l)
+	assert.True(t, g.innieOutie(0))
+	assert.Equal(t, cell(1<<3|1<<4), g.cells[0][2])
+	assert.Equal(t, cell(1<<3|1<<4), g.cells[0][3])
+}
+
+func TestCoverCages(t *testing.T) {
+	l, _ := NewLayout(4)
+	assert.NoError(t, l.AddCage(Cage{3, []Position{{0, 0}, {0, 1}}}))
+	encoded := l.EncodeCages()
+
+	// Without digits in the solution no cage has a possible sum, so nothing is added.
+	assert.Error(t, l.coverCages(make([]cell, 16), rand.New(rand.NewSource(1))))
+	assert.Equal(t, encoded, l.EncodeCages())
+
+	var solution []cell
+	for _, d := range "1234341221434321" {
+		solution = append(solution, 1<<(d-'0'))
+	}
+	assert.NoError(t, l.coverCages(solution, rand.New(rand.NewSource(1))))
+	assert.Equal(t, Cage{3, []Position{{0, 0}, {0, 1}}}, l.Cages()[0])
+	for i, c := range l.cageOf() {
+		assert.True(t, c >= 0, i)
+	}
+	for _, c := range l.cages {
+		var digits cell
+		sum := 0
+		for _, i := range c.cells {
+			assert.Zero(t, digits&solution[i])
+			digits |= solution[i]
+			sum += solution[i].lowestSetBit()
+		}
+		assert.Equal(t, c.sum, sum)
+	}
+}
+
+func TestGenerateKiller(t *testing.T) {
+	l, _ := NewLayout(9)
+	l.SetRandomCages()
+	assert.Equal(t, "9x9 killer", l.String())
+
+	var game *Game
+	for seed := int64(1); game == nil; seed++ {
+		game = GenerateLayout(l, seed)
+	}
+	assert.Empty(t, l.cages)
+
+	cages := game.Puzzle.Layout().EncodeCages()
+	assert.NotEmpty(t, cages)
+	assert.True(t, game.Solution.solved())
+	assert.True(t, game.Puzzle.Valid())
+
+	// Reading the puzzle back with its cages gives the same single solution.
+	k, _ := NewLayout(9)
+	assert.NoError(t, k.SetCages(cages))
+	assert.Equal(t, cages, k.EncodeCages())
+	g, err := ParseLayout(k, game.Puzzle.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, g.CountSolutions(0))
+	g.Solutions(1, func(s *Grid) bool {
+		assert.Equal(t, game.Solution.Values(), s.Values())
+		return true
+	})
+
+	_, solved := g.Reduce(true, nil, 0)
+	assert.True(t, solved)
+	assert.Contains(t, g.SVG(1, false, false, nil), "stroke-dasharray")
+}
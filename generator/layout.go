@@ -0,0 +1,392 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type (
+	// Layout describes the geometry of a puzzle: the number of digits, the size of the grid, and the units in which each digit must appear exactly once. Cells are numbered r*Width + c. A multi-grid layout such as Samurai is a board of overlapping grids, some of whose cells may be absent.
+	Layout struct {
+		Size             int // Size is the number of digits, which is also the number of cells in each unit.
+		Width, Height    int // Width and Height are the number of columns and rows of cells.
+		BoxRows, BoxCols int // BoxRows and BoxCols are the height and width of the boxes, or 0 if the boxes are irregular.
+
+		all      cell         // all has a bit set for each digit (1 - Size).
+		rules    []string     // rules names the extra rules of the layout, such as "diagonal".
+		present  []bool       // present marks the cells that are part of the layout, or is nil if all of them are.
+		grids    []Position   // grids are the top left corners of the standard 9 x 9 grids of the layout (rows, columns and 3 x 3 boxes), if any.
+		region   []int        // region is the box (or jigsaw region) containing each cell, or -1 for an absent cell.
+		units    []layoutUnit // units are the rows, columns, boxes, and any extra units of the layout.
+		cages    []cage       // cages are the killer cages of the layout.
+		pairs    []pairSet    // pairs relate cells under rules such as anti-king or non-consecutive.
+		adjacent [][]int      // adjacent are the cells that must not hold a digit next to that of each cell.
+		peers    [][]int      // peers are the other cells that share a unit or cage with each cell.
+		overlaps [][2]int     // overlaps are the pairs of units that share two or more cells.
+		killer   bool         // killer is true if GenerateLayout should cover each puzzle with new random cages.
+
+		// The rest is derived from the units, cages and pairs by link for the strategies of Grid.
+		points  []point     // points are the cells of the layout in row-major order, leaving out absent cells.
+		groups  []*group    // groups are the units by kind: the boxes (or jigsaw regions), columns and rows, and then any extra units such as diagonals.
+		members [][]int     // members are the units of each group, as indexes into units.
+		crosses [][]int     // crosses are the units that share two or more cells with each unit.
+		lines   [][2]*group // lines pairs the columns and rows that cross one another in order (those of the whole board, or of each 9 x 9 grid of a multi-grid layout), as the fish strategies need.
+		sees    [][]bool    // sees is true for each pair of cells that are peers or that a Different rule relates.
+		seenBy  [][]point   // seenBy are the points of the peers of each cell.
+		unitsOf [][][]point // unitsOf are the units containing each cell, in the order of groups.
+	}
+
+	// layoutUnit is a set of cells that must contain each digit exactly once. Units of the same kind, such as "row" or "diagonal", form a group and are named by link after their kind and their place in it. The cells of shaded units are shaded when rendered.
+	layoutUnit struct {
+		kind   string
+		name   string
+		cells  []int
+		points []point
+		shaded bool
+	}
+)
+
+// standardLayout is the layout of a Grid that has none: the plain 9 x 9 puzzle.
+var standardLayout, _ = NewLayout(rows)
+
+// boxShapes maps the supported puzzle sizes to the number of rows and columns in their boxes.
+var boxShapes = map[int][2]int{
+	4:  {2, 2},
+	6:  {2, 3},
+	8:  {2, 4},
+	9:  {3, 3},
+	12: {3, 4},
+	16: {4, 4},
+}
+
+// NewLayout returns the layout of a size x size puzzle with rectangular boxes: 2 x 2 boxes for 4 x 4, 2 x 3 for 6 x 6, 2 x 4 for 8 x 8, 3 x 3 for 9 x 9, 3 x 4 for 12 x 12, and 4 x 4 for 16 x 16 puzzles.
+func NewLayout(size int) (*Layout, error) {
+	shape, ok := boxShapes[size]
+	if !ok {
+		return nil, fmt.Errorf("unsupported puzzle size %d (must be 4, 6, 8, 9, 12, or 16)", size)
+	}
+
+	l := &Layout{
+		Size:    size,
+		Width:   size,
+		Height:  size,
+		BoxRows: shape[0],
+		BoxCols: shape[1],
+		all:     cell(1<<(size+1) - 2),
+		region:  make([]int, size*size),
+	}
+
+	for r := 0; r < size; r++ {
+		for c := 0; c < size; c++ {
+			l.region[r*size+c] = r/l.BoxRows*(size/l.BoxCols) + c/l.BoxCols
+		}
+	}
+
+	for r := 0; r < size; r++ {
+		cells := make([]int, size)
+		for c := range cells {
+			cells[c] = r*size + c
+		}
+		l.units = append(l.units, layoutUnit{kind: "row", cells: cells})
+	}
+	for c := 0; c < size; c++ {
+		cells := make([]int, size)
+		for r := range cells {
+			cells[r] = r*size + c
+		}
+		l.units = append(l.units, layoutUnit{kind: "col", cells: cells})
+	}
+	l.addRegions()
+	l.link()
+
+	if size == rows {
+		l.grids = []Position{{0, 0}}
+	}
+
+	return l, nil
+}
+
+// LayoutOf returns the layout of the puzzles encoded in n characters, such as 36 for 6 x 6 puzzles or 369 for Samurai puzzles.
+func LayoutOf(n int) (*Layout, error) {
+	if n == samuraiCells {
+		return NewSamurai(), nil
+	}
+
+	size := int(math.Sqrt(float64(n)))
+	if size*size != n {
+		return nil, fmt.Errorf("encoded puzzle of %d characters is not square", n)
+	}
+
+	return NewLayout(size)
+}
+
+// Cells returns the number of cells on the board of the layout, including any absent cells.
+func (l *Layout) Cells() int {
+	return l.Width * l.Height
+}
+
+// String returns the dimensions of the layout followed by any extra rules, such as "6x6" or "9x9 diagonal".
+func (l *Layout) String() string {
+	return strings.Join(append([]string{fmt.Sprintf("%dx%d", l.Width, l.Height)}, l.rules...), " ")
+}
+
+// AddDiagonals adds the two main diagonals as extra units, making the layout an X-Sudoku (Sudoku X) in which each digit appears once on each diagonal. It must be called before the layout is used by any puzzle.
+func (l *Layout) AddDiagonals() {
+	main := make([]int, l.Size)
+	anti := make([]int, l.Size)
+	for i := 0; i < l.Size; i++ {
+		main[i] = i*l.Width + i
+		anti[i] = i*l.Width + l.Size - 1 - i
+	}
+
+	l.units = append(l.units, layoutUnit{kind: "diagonal", cells: main, shaded: true}, layoutUnit{kind: "diagonal", cells: anti, shaded: true})
+	l.rules = append(l.rules, "diagonal")
+	l.link()
+}
+
+// addRegions adds a unit for each box in region.
+func (l *Layout) addRegions() {
+	boxes := make([][]int, l.Size)
+	for i, b := range l.region {
+		boxes[b] = append(boxes[b], i)
+	}
+
+	for _, cells := range boxes {
+		l.units = append(l.units, layoutUnit{kind: "box", cells: cells})
+	}
+}
+
+// link computes the peers of each cell and the overlapping units from the units, cages, and pairs of the layout, and the groups and lines of units seen by the strategies of Grid. It must be called again whenever any of them is added.
+func (l *Layout) link() {
+	n := l.Cells()
+	l.sees = make([][]bool, n)
+	for i := range l.sees {
+		l.sees[i] = make([]bool, n)
+	}
+
+	// The digits of a cage must differ too, so its cells are peers like those of a unit.
+	var sets [][]int
+	for _, u := range l.units {
+		sets = append(sets, u.cells)
+	}
+	for _, c := range l.cages {
+		sets = append(sets, c.cells)
+	}
+
+	l.peers = make([][]int, n)
+	for _, s := range sets {
+		for _, i := range s {
+			for _, j := range s {
+				if i != j && !l.sees[i][j] {
+					l.sees[i][j] = true
+					l.peers[i] = append(l.peers[i], j)
+				}
+			}
+		}
+	}
+
+	// Cells related by a Different rule are peers as well; a NonConsecutive rule relates cells through adjacent instead.
+	l.adjacent = nil
+	for _, p := range l.pairs {
+		for i, with := range p.with {
+			for _, j := range with {
+				switch {
+				case p.rule == NonConsecutive:
+					if l.adjacent == nil {
+						l.adjacent = make([][]int, n)
+					}
+					l.adjacent[i] = append(l.adjacent[i], j)
+				case !l.sees[i][j]:
+					l.sees[i][j] = true
+					l.peers[i] = append(l.peers[i], j)
+				}
+			}
+		}
+	}
+
+	l.seenBy = make([][]point, n)
+	for i, ps := range l.peers {
+		l.seenBy[i] = l.unitPoints(ps)
+	}
+
+	l.overlaps = nil
+	l.crosses = make([][]int, len(l.units))
+	for ui, u := range l.units {
+		in := make([]bool, n)
+		for _, i := range u.cells {
+			in[i] = true
+		}
+
+		for vi := ui + 1; vi < len(l.units); vi++ {
+			shared := 0
+			for _, i := range l.units[vi].cells {
+				if in[i] {
+					shared++
+				}
+			}
+			if shared >= 2 {
+				l.overlaps = append(l.overlaps, [2]int{ui, vi})
+				l.crosses[ui] = append(l.crosses[ui], vi)
+				l.crosses[vi] = append(l.crosses[vi], ui)
+			}
+		}
+	}
+
+	l.points = nil
+	for i := 0; i < n; i++ {
+		if l.has(i) {
+			l.points = append(l.points, l.point(i))
+		}
+	}
+
+	// The boxes, columns and rows come first, in that order, as they do for the standard grid.
+	l.groups = []*group{{name: "box"}, {name: "col"}, {name: "row"}}
+	l.members = make([][]int, len(l.groups))
+	byKind := map[string]int{"box": 0, "col": 1, "row": 2}
+	for ui, u := range l.units {
+		gi, ok := byKind[u.kind]
+		if !ok {
+			gi = len(l.groups)
+			byKind[u.kind] = gi
+			l.groups = append(l.groups, &group{name: u.kind})
+			l.members = append(l.members, nil)
+		}
+
+		gr := l.groups[gi]
+		l.units[ui].name = fmt.Sprintf("%s %d", u.kind, len(gr.unit))
+		l.units[ui].points = l.unitPoints(u.cells)
+		gr.unit = append(gr.unit, l.units[ui].points)
+		l.members[gi] = append(l.members[gi], ui)
+	}
+
+	l.unitsOf = make([][][]point, n)
+	for _, gr := range l.groups {
+		for _, u := range gr.unit {
+			for _, p := range u {
+				i := l.index(p)
+				l.unitsOf[i] = append(l.unitsOf[i], u)
+			}
+		}
+	}
+
+	// The rows of different grids of a multi-grid layout do not line up, so each grid has lines of its own.
+	l.lines = nil
+	if len(l.grids) <= 1 {
+		l.lines = append(l.lines, [2]*group{l.groups[1], l.groups[2]})
+	} else {
+		for _, o := range l.grids {
+			c, r := &group{name: "col"}, &group{name: "row"}
+			for i := 0; i < rows; i++ {
+				var cu, ru []point
+				for j := 0; j < cols; j++ {
+					cu = append(cu, point{uint8(o.Row + j), uint8(o.Col + i)})
+					ru = append(ru, point{uint8(o.Row + i), uint8(o.Col + j)})
+				}
+				c.unit = append(c.unit, cu)
+				r.unit = append(r.unit, ru)
+			}
+			l.lines = append(l.lines, [2]*group{c, r})
+		}
+	}
+}
+
+// point returns the row and column of cell i.
+func (l *Layout) point(i int) point {
+	return point{uint8(i / l.Width), uint8(i % l.Width)}
+}
+
+// index returns the number of the cell at p.
+func (l *Layout) index(p point) int {
+	return int(p.r)*l.Width + int(p.c)
+}
+
+// unitPoints returns the rows and columns of cells.
+func (l *Layout) unitPoints(cells []int) []point {
+	res := make([]point, len(cells))
+	for i, c := range cells {
+		res[i] = l.point(c)
+	}
+
+	return res
+}
+
+// line returns true if unit u is a row or a column.
+func (l *Layout) line(u int) bool {
+	return l.units[u].kind == "row" || l.units[u].kind == "col"
+}
+
+// standard returns true if the layout is the plain 9 x 9 layout with nothing added, which Grid handles without one.
+func (l *Layout) standard() bool {
+	return l.Size == rows && l.Width == cols && l.Height == rows && l.BoxRows == 3 && l.present == nil &&
+		len(l.units) == 3*rows && len(l.cages) == 0 && len(l.pairs) == 0 && !l.killer
+}
+
+// shaded returns the cells that belong to a shaded unit, in increasing order.
+func (l *Layout) shaded() (res []int) {
+	in := make([]bool, l.Cells())
+	for _, u := range l.units {
+		if u.shaded {
+			for _, i := range u.cells {
+				in[i] = true
+			}
+		}
+	}
+
+	for i, s := range in {
+		if s {
+			res = append(res, i)
+		}
+	}
+
+	return
+}
+
+// digitChar returns the character used for a digit: '1' to '9' and then 'A' for 10 up to 'G' for 16.
+func digitChar(d int) byte {
+	if d <= 9 {
+		return byte('0' + d)
+	}
+
+	return byte('A' + d - 10)
+}
+
+// parseDigit returns the digit represented by a character (see digitChar), 0 for a blank ('.' or '0'), or -1 if the character is not a digit.
+func parseDigit(b byte) int {
+	switch {
+	case b == '.' || b == '0':
+		return 0
+	case b >= '1' && b <= '9':
+		return int(b - '0')
+	case b >= 'A' && b <= 'G':
+		return int(b-'A') + 10
+	case b >= 'a' && b <= 'g':
+		return int(b-'a') + 10
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,279 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import "math/rand"
+
+// uniqueBudget is the number of branches after which unique gives up.
+const uniqueBudget = 1 << 14
+
+// layoutSolver holds the state of a brute-force search of a grid on a layout other than the standard one, which the faster solver handles. Like solver, it stops when count reaches limit (if limit is positive) or found returns false. It also gives up, setting exhausted, after budget branches if budget is positive.
+type layoutSolver struct {
+	layout    *Layout
+	limit     int
+	count     int
+	budget    int
+	exhausted bool
+	rnd       *rand.Rand
+	found     func(cells []cell) bool
+}
+
+// assign places a digit (as a single bit) in a cell and removes it from all peers (and its neighbours from any adjacent cells), recursively assigning any cell that is left with a single candidate. It returns false on a contradiction.
+func (l *Layout) assign(cells []cell, i int, bit cell) bool {
+	cells[i] = bit
+	if !l.eliminate(cells, l.peers[i], bit) {
+		return false
+	}
+	if l.adjacent != nil {
+		return l.eliminate(cells, l.adjacent[i], l.excluded(NonConsecutive, bit))
+	}
+
+	return true
+}
+
+// eliminate removes the digits in m from cells ps, assigning any cell that is left with a single candidate. It returns false on a contradiction.
+func (l *Layout) eliminate(cells []cell, ps []int, m cell) bool {
+	for _, p := range ps {
+		c := cells[p]
+		if c&m == 0 {
+			continue
+		}
+
+		c &^= m
+		cells[p] = c
+		if c == 0 {
+			return false
+		}
+		if bitCount[c] == 1 && !l.assign(cells, p, c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hiddenSingles assigns every digit that can appear in only one cell of a unit. It returns whether anything changed and false for ok on a contradiction.
+func (l *Layout) hiddenSingles(cells []cell) (changed, ok bool) {
+	for _, u := range l.units {
+		var once, twice, placed cell
+		for _, i := range u.cells {
+			c := cells[i]
+			twice |= once & c
+			once |= c
+			if bitCount[c] == 1 {
+				placed |= c
+			}
+		}
+
+		if once != l.all { // Some digit has nowhere to go.
+			return changed, false
+		}
+
+		for singles := once &^ twice &^ placed; singles != 0; singles &= singles - 1 {
+			bit := singles & -singles
+
+			found := false
+			for _, i := range u.cells {
+				if cells[i]&bit != 0 {
+					if cells[i] != bit && !l.assign(cells, i, bit) {
+						return changed, false
+					}
+					found = true
+					break
+				}
+			}
+			if !found { // An earlier assignment in this unit removed the only place for the digit.
+				return changed, false
+			}
+
+			changed = true
+		}
+	}
+
+	return changed, true
+}
+
+// propagate removes the digit of every solved cell from its peers. It returns false if the cells contain a contradiction.
+func (l *Layout) propagate(cells []cell) bool {
+	solved := make([]bool, len(cells))
+	for i, c := range cells {
+		if c == 0 && l.has(i) {
+			return false
+		}
+		solved[i] = bitCount[c] == 1
+	}
+
+	for i := range cells {
+		if solved[i] && !l.assign(cells, i, cells[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// deduce applies hidden singles and the sums of any cages until nothing changes. It returns false on a contradiction.
+func (l *Layout) deduce(cells []cell) bool {
+	for {
+		changed, ok := l.hiddenSingles(cells)
+		if !ok {
+			return false
+		}
+		if changed {
+			continue
+		}
+
+		if changed, ok = l.cageSums(cells); !ok {
+			return false
+		}
+		if !changed {
+			return true
+		}
+	}
+}
+
+// singles solves cells using only naked and hidden singles (and cage sums). It returns true if every cell was solved without a contradiction.
+func (l *Layout) singles(cells []cell) bool {
+	if !l.propagate(cells) || !l.deduce(cells) {
+		return false
+	}
+
+	for i, c := range cells {
+		if bitCount[c] != 1 && l.has(i) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// search propagates hidden singles and cage sums and then branches on the cell with the fewest candidates. It returns true when the solver should stop.
+func (s *layoutSolver) search(cells []cell) bool {
+	l := s.layout
+	if !l.deduce(cells) {
+		return false
+	}
+
+	best := -1
+	min := l.Size + 1
+	for i, c := range cells {
+		if n := bitCount[c]; n > 1 && n < min {
+			best, min = i, n
+			if n == 2 {
+				break
+			}
+		}
+	}
+
+	if best < 0 { // Every cell is solved.
+		s.count++
+		if s.found != nil && !s.found(cells) {
+			return true
+		}
+		return s.limit > 0 && s.count >= s.limit
+	}
+
+	digits := cells[best].digits()
+	if s.rnd != nil {
+		s.rnd.Shuffle(len(digits), func(i, j int) { digits[i], digits[j] = digits[j], digits[i] })
+	}
+
+	for _, d := range digits {
+		if s.budget > 0 {
+			if s.budget--; s.budget == 0 {
+				s.exhausted = true
+				return true
+			}
+		}
+
+		cp := append([]cell(nil), cells...)
+		if l.assign(cp, best, 1<<d) && s.search(cp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// solve runs the solver over the candidates of g.
+func (s *layoutSolver) solve(g *Grid) {
+	cells := g.cellList()
+	for i := range cells {
+		cells[i] &= s.layout.all
+	}
+
+	if s.layout.propagate(cells) {
+		s.search(cells)
+	}
+}
+
+// layoutSolutions calls f with each solution of the grid, trying candidates in an order taken from rnd (or in increasing order if rnd is nil), until f returns false or limit solutions have been found.
+func (g *Grid) layoutSolutions(limit int, rnd *rand.Rand, f func(solution *Grid) bool) int {
+	s := layoutSolver{layout: g.Layout(), limit: limit, rnd: rnd}
+	if f != nil {
+		s.found = func(cells []cell) bool {
+			res := *g
+			res.setCellList(cells)
+			return f(&res)
+		}
+	}
+	s.solve(g)
+	return s.count
+}
+
+// unique returns true if the grid has exactly one solution. The search gives up after uniqueBudget branches, which only the largest layouts reach, and then the grid is not known to be unique.
+func (g *Grid) unique() bool {
+	if g.layout == nil {
+		return g.CountSolutions(2) == 1
+	}
+
+	s := layoutSolver{layout: g.Layout(), limit: 2, budget: uniqueBudget}
+	s.solve(g)
+	return s.count == 1 && !s.exhausted
+}
+
+// fill returns a random solution of an empty puzzle on the layout, or nil if none was found. Some layouts, such as jigsaws, occasionally lead the search into a long dead end, so it restarts with a new order after a fixed number of branches.
+func (l *Layout) fill(rnd *rand.Rand) *Grid {
+	const (
+		restarts = 32
+		budget   = 1 << 12
+	)
+
+	var solution *Grid
+	empty := NewGrid(l)
+	for try := 0; try < restarts; try++ {
+		s := layoutSolver{layout: l, limit: 1, budget: budget, rnd: rnd, found: func(cells []cell) bool {
+			solution = NewGrid(l)
+			solution.setCellList(cells)
+			return false
+		}}
+		s.solve(empty)
+		if !s.exhausted {
+			break
+		}
+	}
+
+	return solution
+}
@@ -0,0 +1,226 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLayout(t *testing.T) {
+	for size, peers := range map[int]int{4: 7, 6: 12, 8: 17, 9: 20, 12: 28, 16: 39} {
+		l, err := NewLayout(size)
+		assert.NoError(t, err)
+		assert.Equal(t, size*size, l.Cells())
+		assert.Len(t, l.units, 3*size)
+		for _, p := range l.peers {
+			assert.Len(t, p, peers, size)
+		}
+		for _, u := range l.units {
+			assert.Len(t, u.cells, size)
+		}
+	}
+
+	_, err := NewLayout(7)
+	assert.Error(t, err)
+
+	l, err := LayoutOf(36)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, l.BoxRows)
+	assert.Equal(t, 3, l.BoxCols)
+	assert.Equal(t, "6x6", l.String())
+
+	_, err = LayoutOf(37)
+	assert.Error(t, err)
+}
+
+func TestParseLayout(t *testing.T) {
+	p := "200A050000B008GE0500006E0701000000001DG900000020EG000280000A0063F30008201070A50D0B60000F00007E0C120C0000F000000G00000000G0300B000000A700000CD0900807D0000E00000600010F0430000C079640C010500000000006030000402150GC0504027360000F00200000BG0030000000800D00C0G00B"
+	g, err := ParseLayout(nil, p)
+	assert.NoError(t, err)
+	assert.Equal(t, 16, g.Layout().Size)
+	assert.Equal(t, p, g.Encode())
+	assert.Equal(t, uint(93), g.Clues())
+	assert.True(t, g.Valid())
+
+	g, err = ParseLayout(nil, strings.ToLower(p))
+	assert.NoError(t, err)
+	assert.Equal(t, p, g.Encode())
+
+	_, err = ParseLayout(nil, "12..")
+	assert.Error(t, err)
+
+	l, _ := NewLayout(4)
+	_, err = ParseLayout(l, "5...............")
+	assert.Error(t, err)
+
+	_, err = ParseLayout(l, "1...")
+	assert.Error(t, err)
+
+	g, err = ParseLayout(l, "11..............")
+	assert.NoError(t, err)
+	assert.False(t, g.Valid())
+	assert.Equal(t, 0, g.CountSolutions(0))
+
+	// The plain 9 x 9 layout gives a grid without one, which the faster solver handles.
+	g, err = ParseLayout(nil, hardestPuzzles[0])
+	assert.NoError(t, err)
+	assert.Nil(t, g.layout)
+}
+
+func TestLayoutSolver(t *testing.T) {
+	for _, p := range hardestPuzzles {
+		g, err := ParseEncoded(p)
+		assert.NoError(t, err)
+
+		var want string
+		g.Solutions(1, func(s *Grid) bool {
+			want = s.Values()
+			return true
+		})
+
+		assert.Equal(t, 1, g.layoutSolutions(0, nil, func(s *Grid) bool {
+			assert.Equal(t, want, s.Values())
+			assert.True(t, s.solved())
+			return true
+		}))
+		assert.True(t, g.unique())
+	}
+}
+
+func TestGenerateLayout(t *testing.T) {
+	for _, size := range []int{4, 6, 8, 9, 12, 16} {
+		l, _ := NewLayout(size)
+
+		var game *Game
+		for seed := int64(1); game == nil; seed++ {
+			game = GenerateLayout(l, seed)
+		}
+
+		assert.Equal(t, game.Puzzle.Encode(), GenerateLayout(l, game.Seed).Puzzle.Encode(), size)
+		assert.Equal(t, game.Clues, game.Puzzle.Clues())
+		assert.True(t, game.Solution.solved())
+		assert.NotEmpty(t, game.Strategies)
+
+		var solutions []string
+		game.Puzzle.Solutions(2, func(s *Grid) bool {
+			solutions = append(solutions, s.Values())
+			return true
+		})
+		assert.Equal(t, []string{game.Solution.Values()}, solutions, size)
+
+		cp := *game.Puzzle
+		_, solved := cp.Reduce(true, nil, 0)
+		assert.True(t, solved, size)
+	}
+}
+
+func TestLayoutDisplay(t *testing.T) {
+	l, _ := NewLayout(6)
+	g, _ := ParseLayout(l, "600020001600002000040010000004300200")
+
+	var b bytes.Buffer
+	g.DisplayTo(&b)
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	assert.Len(t, lines, 1+1+6+2+1) // Headers, top, rows, box separators, bottom.
+	assert.Contains(t, lines[2], "6")
+
+	svg := g.SVG(1, false, false, nil)
+	assert.Contains(t, svg, `<svg width="350" height="350"`)
+	assert.Equal(t, int(g.Clues()), strings.Count(svg, "</text>"))
+}
+
+func TestDiagonals(t *testing.T) {
+	l, _ := NewLayout(9)
+	l.AddDiagonals()
+	assert.Equal(t, "9x9 diagonal", l.String())
+	assert.Len(t, l.peers[40], 32)
+	assert.Len(t, l.peers[0], 26)
+	assert.Len(t, l.peers[1], 20)
+	assert.Len(t, l.shaded(), 17)
+	assert.Len(t, l.groups, 4)
+	assert.Equal(t, "diagonal", l.groups[3].name)
+
+	var game *Game
+	for seed := int64(1); game == nil; seed++ {
+		game = GenerateLayout(l, seed)
+	}
+
+	assert.True(t, game.Solution.solved())
+	for _, u := range l.units[27:] {
+		var seen cell
+		for _, p := range u.points {
+			seen |= *game.Solution.pt(p)
+		}
+		assert.Equal(t, l.all, seen, u.name)
+	}
+	assert.Equal(t, 1, game.Puzzle.CountSolutions(0))
+
+	// Without the diagonals, the puzzle is ambiguous.
+	plain, _ := ParseLayout(nil, game.Puzzle.Encode())
+	assert.True(t, plain.CountSolutions(2) > 1)
+
+	g, _ := ParseLayout(l, game.Puzzle.Encode())
+	assert.Contains(t, g.SVG(1, false, false, nil), "fill:lightgray")
+}
+
+func TestNotStandard(t *testing.T) {
+	for _, l := range []*Layout{NewSamurai(), marshalLayouts(t)["4x4"]} {
+		g := NewGrid(l)
+
+		_, err := g.Canonical()
+		assert.Equal(t, ErrNotStandard, err)
+		_, err = g.CanonicalTransform()
+		assert.Equal(t, ErrNotStandard, err)
+		_, err = IdentityTransform().Apply(g)
+		assert.Equal(t, ErrNotStandard, err)
+		_, err = IdentityTransform().ApplyGame(&Game{Puzzle: g})
+		assert.Equal(t, ErrNotStandard, err)
+		_, ok := Isomorphic(g, g)
+		assert.False(t, ok)
+
+		_, err = g.UnavoidableSets(12)
+		assert.Equal(t, ErrNotStandard, err)
+		_, err = g.Minimal()
+		assert.Equal(t, ErrNotStandard, err)
+		_, err = g.Sparsest(1, 1)
+		assert.Equal(t, ErrNotStandard, err)
+		_, err = g.Ambiguity(0)
+		assert.Equal(t, ErrNotStandard, err)
+
+		_, err = g.Image(0, false, nil)
+		assert.Equal(t, ErrNotStandard, err)
+		assert.Equal(t, ErrNotStandard, g.WritePNG(&bytes.Buffer{}, 0, false, nil))
+		assert.Equal(t, ErrNotStandard, g.WriteJPEG(&bytes.Buffer{}, 0, 90, false, nil))
+
+		var b Bank
+		_, err = b.Add(&Game{Puzzle: g})
+		assert.Equal(t, ErrNotStandard, err)
+		assert.False(t, b.Contains(g))
+	}
+}
@@ -24,6 +24,11 @@
 
 package generator
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Level is a type wrapper for the difficulty levels of puzzles.
 type Level int
 
@@ -56,3 +61,35 @@ func (l Level) String() string {
 
 	return ""
 }
+
+// ParseLevel converts the name of a level (case insensitive) to a Level.
+func ParseLevel(s string) (Level, error) {
+	for l := Easy; l <= Extreme; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+
+	return Easy, fmt.Errorf("unknown level %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler using the level name.
+func (l Level) MarshalText() ([]byte, error) {
+	s := l.String()
+	if s == "" {
+		return nil, fmt.Errorf("unknown level %d", int(l))
+	}
+
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (l *Level) UnmarshalText(text []byte) error {
+	v, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+
+	*l = v
+	return nil
+}
@@ -0,0 +1,838 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	gameJSON struct {
+		Level      Level    `json:"level"`
+		Clues      uint     `json:"clues"`
+		Strategies []string `json:"strategies"`
+		Seed       int64    `json:"seed,omitempty"`
+		Puzzle     *Grid    `json:"puzzle"`
+		Solution   *Grid    `json:"solution,omitempty"`
+	}
+
+	gridJSON struct {
+		Layout     *Layout  `json:"layout,omitempty"`
+		Givens     string   `json:"givens"`
+		Candidates []string `json:"candidates,omitempty"`
+	}
+
+	// layoutJSON describes a layout by the calls that build it: NewLayout or NewMultiLayout, then SetRegions, AddUnit for each extra unit, AddPairs for each pair rule, and SetCages.
+	layoutJSON struct {
+		Size        int        `json:"size"`
+		Grids       []Position `json:"grids,omitempty"`
+		Regions     string     `json:"regions,omitempty"`
+		Units       []unitJSON `json:"units,omitempty"`
+		Pairs       []pairJSON `json:"pairs,omitempty"`
+		Cages       string     `json:"cages,omitempty"`
+		RandomCages bool       `json:"randomCages,omitempty"`
+		Rules       []string   `json:"rules,omitempty"`
+	}
+
+	unitJSON struct {
+		Kind   string     `json:"kind"`
+		Cells  []Position `json:"cells"`
+		Shaded bool       `json:"shaded,omitempty"`
+	}
+
+	pairJSON struct {
+		Name    string     `json:"name"`
+		Offsets []Position `json:"offsets"`
+		Rule    string     `json:"rule"`
+	}
+)
+
+const (
+	binaryVersion       = 1
+	binaryLayoutVersion = 2 // binaryLayoutVersion marks the binary form of a grid that has a layout.
+
+	gridCandidates = 1 << 0 // Flag indicating that a full table of candidates follows the solved values in the binary form of a grid.
+	binaryGiven    = 1 << 7 // binaryGiven marks a given in the value byte of each cell of the binary form of a grid that has a layout.
+)
+
+// pairRuleNames are the names of the pair rules in the JSON form of a layout.
+var pairRuleNames = map[PairRule]string{Different: "different", NonConsecutive: "nonConsecutive"}
+
+// MarshalJSON implements json.Marshaler. The layout is described by its size, the corners of its grids if it has several, its jigsaw regions, extra units, pair rules and cages in the forms read by SetRegions, AddUnit, AddPairs and SetCages, and the names of its rules.
+func (l *Layout) MarshalJSON() ([]byte, error) {
+	j := layoutJSON{Size: l.Size, Cages: l.EncodeCages(), RandomCages: l.killer, Rules: l.rules}
+	if len(l.grids) > 1 || l.Width != l.Size || l.Height != l.Size {
+		j.Grids = l.grids
+	}
+	if l.BoxRows == 0 {
+		j.Regions = l.Regions()
+	}
+	for _, u := range l.units {
+		if u.kind != "row" && u.kind != "col" && u.kind != "box" {
+			cells := make([]Position, len(u.cells))
+			for i, c := range u.cells {
+				cells[i] = l.position(c)
+			}
+			j.Units = append(j.Units, unitJSON{u.kind, cells, u.shaded})
+		}
+	}
+	for _, p := range l.pairs {
+		j.Pairs = append(j.Pairs, pairJSON{p.name, p.offsets, pairRuleNames[p.rule]})
+	}
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler and accepts the form produced by MarshalJSON.
+func (l *Layout) UnmarshalJSON(data []byte) error {
+	var j layoutJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	var (
+		res *Layout
+		err error
+	)
+	if len(j.Grids) > 0 {
+		name := ""
+		if len(j.Rules) > 0 {
+			name = j.Rules[0]
+		}
+		res, err = NewMultiLayout(name, j.Grids)
+	} else {
+		res, err = NewLayout(j.Size)
+	}
+	if err != nil {
+		return err
+	}
+
+	if j.Regions != "" {
+		if err := res.SetRegions(j.Regions); err != nil {
+			return err
+		}
+	}
+	for _, u := range j.Units {
+		if err := res.AddUnit(u.Kind, u.Cells, u.Shaded); err != nil {
+			return err
+		}
+	}
+	for _, p := range j.Pairs {
+		rule, ok := PairRule(-1), false
+		for r, name := range pairRuleNames {
+			if name == p.Rule {
+				rule, ok = r, true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("unknown pair rule %q", p.Rule)
+		}
+		res.AddPairs(p.Name, p.Offsets, rule)
+	}
+	if j.Cages != "" {
+		if err := res.SetCages(j.Cages); err != nil {
+			return err
+		}
+	}
+
+	res.killer = j.RandomCages
+	res.rules = append([]string(nil), j.Rules...)
+	*l = *res
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler. The text form of a layout is its JSON form, with any spaces in its strings escaped so that it holds no white space.
+func (l *Layout) MarshalText() ([]byte, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.ReplaceAll(data, []byte(" "), []byte(`\u0020`)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler and accepts the format produced by MarshalText.
+func (l *Layout) UnmarshalText(text []byte) error {
+	return l.UnmarshalJSON(text)
+}
+
+// MarshalText implements encoding.TextMarshaler. The text form of a grid is the 81 givens, using '.' for non-givens. If any cell holds something other than its given or the full set of candidates, a colon follows and then all 81 cells, each as a single digit or as a bracketed list of its candidates (for example "5[137]..."). A grid that has a layout starts with the text form of its layout, and then has a given and a cell for each cell of the layout, written with the characters of Encode.
+func (g *Grid) MarshalText() ([]byte, error) {
+	var b strings.Builder
+	if !g.Standard() {
+		text, err := g.layout.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		b.Write(text)
+	}
+	b.WriteString(g.givens())
+
+	if !g.pristine() {
+		b.WriteByte(':')
+		for _, p := range g.Layout().points {
+			cell := *g.pt(p)
+			if bitCount[cell] == 1 {
+				b.WriteString(cell.String())
+			} else {
+				fmt.Fprintf(&b, "[%s]", cell)
+			}
+		}
+	}
+
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler and accepts the format produced by MarshalText.
+func (g *Grid) UnmarshalText(text []byte) error {
+	s := string(text)
+	var l *Layout
+	if strings.HasPrefix(s, "{") {
+		d := json.NewDecoder(strings.NewReader(s))
+		l = &Layout{}
+		if err := d.Decode(l); err != nil {
+			return err
+		}
+		s = s[d.InputOffset():]
+	}
+
+	var cands string
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		s, cands = s[:i], s[i+1:]
+	}
+
+	p, err := parseGivens(l, s)
+	if err != nil {
+		return err
+	}
+
+	if cands != "" {
+		cells, err := parseCandidates(cands, len(p.Layout().points))
+		if err != nil {
+			return err
+		}
+		if err := p.setCandidates(cells); err != nil {
+			return err
+		}
+	}
+
+	*g = *p
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The candidates are omitted if the grid holds only givens, and the layout if the grid has none.
+func (g *Grid) MarshalJSON() ([]byte, error) {
+	j := gridJSON{Layout: g.layout, Givens: g.givens()}
+	if !g.pristine() {
+		points := g.Layout().points
+		j.Candidates = make([]string, 0, len(points))
+		for _, p := range points {
+			j.Candidates = append(j.Candidates, g.pt(p).String())
+		}
+	}
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (g *Grid) UnmarshalJSON(data []byte) error {
+	var j gridJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	p, err := parseGivens(j.Layout, j.Givens)
+	if err != nil {
+		return err
+	}
+
+	if j.Candidates != nil {
+		n := len(p.Layout().points)
+		if len(j.Candidates) != n {
+			return fmt.Errorf("candidates must contain %d cells, found %d", n, len(j.Candidates))
+		}
+
+		cells := make([]cell, n)
+		for i, s := range j.Candidates {
+			if cells[i], err = parseCell(s); err != nil {
+				return err
+			}
+		}
+		if err := p.setCandidates(cells); err != nil {
+			return err
+		}
+	}
+
+	*g = *p
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The binary form is a version byte, a flags byte, 41 bytes of packed nibbles holding the value of each solved cell (0 for unsolved), and 11 bytes of bits marking the givens. If any unsolved cell holds less than the full set of candidates, 81 little-endian 16-bit candidate masks follow. A grid that has a layout has a version byte of 2 and a flags byte, the length of the JSON form of its layout as a uvarint followed by the layout, a byte for each cell of the layout holding its value with the top bit set for a given, and if needed a little-endian 24-bit candidate mask for each cell.
+func (g *Grid) MarshalBinary() ([]byte, error) {
+	var flags byte
+	if !g.pristineUnsolved() {
+		flags |= gridCandidates
+	}
+
+	if !g.Standard() {
+		return g.marshalLayoutBinary(flags)
+	}
+
+	res := make([]byte, 2, 2+41+11+rows*cols*2)
+	res[0] = binaryVersion
+	res[1] = flags
+
+	var values [41]byte
+	var givens [11]byte
+	for i := 0; i < rows*cols; i++ {
+		cell := g.cells[i/cols][i%cols]
+		if bitCount[cell] == 1 {
+			values[i/2] |= byte(cell.lowestSetBit()) << (4 * (i % 2))
+		}
+		if g.orig[i/cols][i%cols] {
+			givens[i/8] |= 1 << (i % 8)
+		}
+	}
+	res = append(res, values[:]...)
+	res = append(res, givens[:]...)
+
+	if flags&gridCandidates != 0 {
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				res = append(res, byte(g.cells[r][c]), byte(g.cells[r][c]>>8))
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// marshalLayoutBinary returns the binary form of a grid that has a layout.
+func (g *Grid) marshalLayoutBinary(flags byte) ([]byte, error) {
+	layout, err := json.Marshal(g.layout)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteByte(binaryLayoutVersion)
+	b.WriteByte(flags)
+	writeUvarint(&b, uint64(len(layout)))
+	b.Write(layout)
+
+	points := g.layout.points
+	for _, p := range points {
+		var v byte
+		if cell := *g.pt(p); bitCount[cell] == 1 {
+			v = byte(cell.lowestSetBit())
+		}
+		if g.orig[p.r][p.c] {
+			v |= binaryGiven
+		}
+		b.WriteByte(v)
+	}
+
+	if flags&gridCandidates != 0 {
+		for _, p := range points {
+			cell := *g.pt(p)
+			b.Write([]byte{byte(cell), byte(cell >> 8), byte(cell >> 16)})
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (g *Grid) UnmarshalBinary(data []byte) error {
+	if len(data) > 0 && data[0] == binaryLayoutVersion {
+		return g.unmarshalLayoutBinary(data)
+	}
+	if len(data) < 2+41+11 {
+		return fmt.Errorf("binary grid too short: %d bytes", len(data))
+	}
+	if data[0] != binaryVersion {
+		return fmt.Errorf("unsupported binary grid version %d", data[0])
+	}
+
+	flags := data[1]
+	values := data[2 : 2+41]
+	givens := data[2+41 : 2+41+11]
+	rest := data[2+41+11:]
+
+	p := Grid{}
+	for i := 0; i < rows*cols; i++ {
+		r, c := i/cols, i%cols
+		v := values[i/2] >> (4 * (i % 2)) & 0xf
+		if v > 9 {
+			return fmt.Errorf("illegal value %d in binary grid at %s", v, point{uint8(r), uint8(c)})
+		}
+
+		if v == 0 {
+			p.cells[r][c] = all
+		} else {
+			p.cells[r][c] = 1 << v
+		}
+
+		if givens[i/8]&(1<<(i%8)) != 0 {
+			if v == 0 {
+				return fmt.Errorf("given at %s has no value", point{uint8(r), uint8(c)})
+			}
+			p.orig[r][c] = true
+		}
+	}
+
+	if flags&gridCandidates != 0 {
+		if len(rest) != rows*cols*2 {
+			return fmt.Errorf("binary grid candidates must contain %d bytes, found %d", rows*cols*2, len(rest))
+		}
+
+		cells := make([]cell, rows*cols)
+		for i := range cells {
+			cells[i] = cell(rest[2*i]) | cell(rest[2*i+1])<<8
+		}
+		if err := p.setCandidates(cells); err != nil {
+			return err
+		}
+	} else if len(rest) != 0 {
+		return fmt.Errorf("binary grid has %d extra bytes", len(rest))
+	}
+
+	*g = p
+	return nil
+}
+
+// unmarshalLayoutBinary reads the binary form of a grid that has a layout.
+func (g *Grid) unmarshalLayoutBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("binary grid too short: %d bytes", len(data))
+	}
+	flags := data[1]
+	b := bytes.NewReader(data[2:])
+
+	layout, err := readBytes(b)
+	if err != nil {
+		return err
+	}
+	l := &Layout{}
+	if err := json.Unmarshal(layout, l); err != nil {
+		return err
+	}
+
+	p := NewGrid(l)
+	points := p.Layout().points
+	values := make([]byte, len(points))
+	if _, err := io.ReadFull(b, values); err != nil {
+		return fmt.Errorf("binary grid must contain %d values", len(points))
+	}
+	for i, pt := range points {
+		v := int(values[i] &^ binaryGiven)
+		if v > l.Size {
+			return fmt.Errorf("illegal value %d in binary grid at %s", v, pt)
+		}
+		if v > 0 {
+			*p.pt(pt) = 1 << v
+		}
+		if values[i]&binaryGiven != 0 {
+			if v == 0 {
+				return fmt.Errorf("given at %s has no value", pt)
+			}
+			p.orig[pt.r][pt.c] = true
+		}
+	}
+
+	if flags&gridCandidates != 0 {
+		if b.Len() != len(points)*3 {
+			return fmt.Errorf("binary grid candidates must contain %d bytes, found %d", len(points)*3, b.Len())
+		}
+
+		cells := make([]cell, len(points))
+		for i := range cells {
+			var c [3]byte
+			b.Read(c[:])
+			cells[i] = cell(c[0]) | cell(c[1])<<8 | cell(c[2])<<16
+		}
+		if err := p.setCandidates(cells); err != nil {
+			return err
+		}
+	} else if b.Len() != 0 {
+		return fmt.Errorf("binary grid has %d extra bytes", b.Len())
+	}
+
+	*g = *p
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler. The text form of a game is a single line of space-separated fields: level, clues, seed, comma-separated strategies ("-" if none), puzzle, and solution ("-" if none). The puzzle and solution use the text form of Grid.
+func (g *Game) MarshalText() ([]byte, error) {
+	if g.Puzzle == nil {
+		return nil, fmt.Errorf("game has no puzzle")
+	}
+
+	level, err := g.Level.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	strategies := "-"
+	if len(g.Strategies) > 0 {
+		strategies = strings.Join(g.Strategies, ",")
+	}
+
+	puzzle, err := g.Puzzle.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	solution := []byte("-")
+	if g.Solution != nil {
+		if solution, err = g.Solution.MarshalText(); err != nil {
+			return nil, err
+		}
+	}
+
+	return []byte(fmt.Sprintf("%s %d %d %s %s %s", level, g.Clues, g.Seed, strategies, puzzle, solution)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler and accepts the format produced by MarshalText.
+func (g *Game) UnmarshalText(text []byte) error {
+	fields := strings.Fields(string(text))
+	if len(fields) != 6 {
+		return fmt.Errorf("game must contain 6 fields, found %d", len(fields))
+	}
+
+	var res Game
+	if err := res.Level.UnmarshalText([]byte(fields[0])); err != nil {
+		return err
+	}
+
+	clues, err := strconv.ParseUint(fields[1], 10, 8)
+	if err != nil {
+		return fmt.Errorf("illegal clue count %q", fields[1])
+	}
+	res.Clues = uint(clues)
+
+	if res.Seed, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+		return fmt.Errorf("illegal seed %q", fields[2])
+	}
+
+	if fields[3] != "-" {
+		res.Strategies = strings.Split(fields[3], ",")
+	}
+
+	res.Puzzle = &Grid{}
+	if err := res.Puzzle.UnmarshalText([]byte(fields[4])); err != nil {
+		return err
+	}
+
+	if fields[5] != "-" {
+		res.Solution = &Grid{}
+		if err := res.Solution.UnmarshalText([]byte(fields[5])); err != nil {
+			return err
+		}
+	}
+
+	*g = res
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (g *Game) MarshalJSON() ([]byte, error) {
+	strategies := g.Strategies
+	if strategies == nil {
+		strategies = []string{}
+	}
+
+	return json.Marshal(gameJSON{g.Level, g.Clues, strategies, g.Seed, g.Puzzle, g.Solution})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (g *Game) UnmarshalJSON(data []byte) error {
+	var j gameJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Puzzle == nil {
+		return fmt.Errorf("game has no puzzle")
+	}
+	if len(j.Strategies) == 0 {
+		j.Strategies = nil
+	}
+
+	*g = Game{j.Level, j.Clues, j.Strategies, j.Puzzle, j.Solution, j.Seed}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The binary form of a game is a version byte, a level byte, the seed as a varint, the clue count and number of strategies as uvarints, each strategy as a uvarint length followed by its name, and finally the puzzle and solution as uvarint lengths followed by their binary forms (a zero length for a missing solution).
+func (g *Game) MarshalBinary() ([]byte, error) {
+	if g.Puzzle == nil {
+		return nil, fmt.Errorf("game has no puzzle")
+	}
+
+	var b bytes.Buffer
+	b.WriteByte(binaryVersion)
+	b.WriteByte(byte(g.Level))
+	writeVarint(&b, g.Seed)
+	writeUvarint(&b, uint64(g.Clues))
+	writeUvarint(&b, uint64(len(g.Strategies)))
+	for _, s := range g.Strategies {
+		writeUvarint(&b, uint64(len(s)))
+		b.WriteString(s)
+	}
+
+	for _, gr := range []*Grid{g.Puzzle, g.Solution} {
+		if gr == nil {
+			writeUvarint(&b, 0)
+			continue
+		}
+
+		data, err := gr.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		writeUvarint(&b, uint64(len(data)))
+		b.Write(data)
+	}
+
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (g *Game) UnmarshalBinary(data []byte) error {
+	b := bytes.NewReader(data)
+
+	version, err := b.ReadByte()
+	if err != nil {
+		return err
+	}
+	if version != binaryVersion {
+		return fmt.Errorf("unsupported binary game version %d", version)
+	}
+
+	var res Game
+	level, err := b.ReadByte()
+	if err != nil {
+		return err
+	}
+	res.Level = Level(level)
+	if res.Level.String() == "" {
+		return fmt.Errorf("unknown level %d", level)
+	}
+
+	if res.Seed, err = binary.ReadVarint(b); err != nil {
+		return err
+	}
+
+	clues, err := binary.ReadUvarint(b)
+	if err != nil {
+		return err
+	}
+	res.Clues = uint(clues)
+
+	n, err := binary.ReadUvarint(b)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		s, err := readBytes(b)
+		if err != nil {
+			return err
+		}
+		res.Strategies = append(res.Strategies, string(s))
+	}
+
+	for _, gr := range []**Grid{&res.Puzzle, &res.Solution} {
+		data, err := readBytes(b)
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		*gr = &Grid{}
+		if err := (*gr).UnmarshalBinary(data); err != nil {
+			return err
+		}
+	}
+
+	if res.Puzzle == nil {
+		return fmt.Errorf("game has no puzzle")
+	}
+	if b.Len() != 0 {
+		return fmt.Errorf("binary game has %d extra bytes", b.Len())
+	}
+
+	*g = res
+	return nil
+}
+
+// givens returns the givens of the grid, one for each cell of its layout, using '.' for cells that are not givens.
+func (g *Grid) givens() string {
+	var b strings.Builder
+	for _, p := range g.Layout().points {
+		if g.orig[p.r][p.c] {
+			b.WriteByte(digitChar(g.pt(p).lowestSetBit()))
+		} else {
+			b.WriteByte('.')
+		}
+	}
+
+	return b.String()
+}
+
+// parseGivens parses the givens written by givens: the 81 of a standard grid if l is nil, and otherwise those of the layout.
+func parseGivens(l *Layout, s string) (*Grid, error) {
+	if l == nil {
+		return ParseEncoded(s)
+	}
+
+	return ParseLayout(l, s)
+}
+
+// pristine returns true if every cell that is not a given still contains all candidates.
+func (g *Grid) pristine() bool {
+	l := g.Layout()
+	for _, p := range l.points {
+		if !g.orig[p.r][p.c] && *g.pt(p) != l.all {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pristineUnsolved returns true if every cell is either solved or still contains all candidates.
+func (g *Grid) pristineUnsolved() bool {
+	l := g.Layout()
+	for _, p := range l.points {
+		cell := *g.pt(p)
+		if bitCount[cell] != 1 && cell != l.all {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setCandidates replaces the candidates of every cell of the layout, in the order of its points, checking that the givens are unchanged.
+func (g *Grid) setCandidates(cells []cell) error {
+	l := g.Layout()
+	for i, p := range l.points {
+		cell := cells[i]
+		if cell&^l.all != 0 {
+			return fmt.Errorf("illegal candidates %#b at %s", cell, p)
+		}
+		if g.orig[p.r][p.c] && cell != *g.pt(p) {
+			return fmt.Errorf("candidates %s at %s do not match the given %s", cell, p, *g.pt(p))
+		}
+		*g.pt(p) = cell
+	}
+
+	return nil
+}
+
+// parseCandidates parses the n cells of the text form written by MarshalText.
+func parseCandidates(s string, n int) ([]cell, error) {
+	res := make([]cell, n)
+	i := 0
+	for len(s) > 0 {
+		if i == len(res) {
+			return res, fmt.Errorf("too many cells in candidates")
+		}
+
+		var digits string
+		if s[0] == '[' {
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return res, fmt.Errorf("unterminated candidate list in %q", s)
+			}
+			digits, s = s[1:end], s[end+1:]
+		} else {
+			digits, s = s[:1], s[1:]
+		}
+
+		var err error
+		if res[i], err = parseCell(digits); err != nil {
+			return res, err
+		}
+		i++
+	}
+
+	if i != len(res) {
+		return res, fmt.Errorf("candidates must contain %d cells, found %d", len(res), i)
+	}
+
+	return res, nil
+}
+
+// parseCell converts a string of candidate digits, written as by digitChar, to a cell.
+func parseCell(s string) (res cell, err error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty candidate list")
+	}
+
+	for i := 0; i < len(s); i++ {
+		d := parseDigit(s[i])
+		if d <= 0 {
+			return 0, fmt.Errorf("illegal candidate '%c'", s[i])
+		}
+		res |= 1 << d
+	}
+
+	return res, nil
+}
+
+func readBytes(b *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(b)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(b.Len()) {
+		return nil, fmt.Errorf("length %d exceeds remaining %d bytes", n, b.Len())
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	res := make([]byte, n)
+	_, err = b.Read(res)
+	return res, err
+}
+
+func writeUvarint(b *bytes.Buffer, v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func writeVarint(b *bytes.Buffer, v int64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutVarint(buf[:], v)])
+}
@@ -0,0 +1,292 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const marshalPuzzle = "....2473.54.37.26.237.....47...3.84...3481....84.6...33......59.7..93..2..62..3.."
+
+func TestParseEncodedZeros(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+	h, err := ParseEncoded(g.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, *g, *h)
+	assert.Equal(t, uint(36), h.Clues())
+
+	_, err = ParseEncoded("x" + marshalPuzzle[1:])
+	assert.Error(t, err)
+}
+
+func TestGridMarshalText(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+
+	text, err := g.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, marshalPuzzle, string(text))
+
+	g.Reduce(false, nil, 0)
+	text, err = g.MarshalText()
+	assert.NoError(t, err)
+
+	var d Grid
+	assert.NoError(t, d.UnmarshalText(text))
+	assert.Equal(t, *g, d)
+}
+
+func TestGridMarshalJSON(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+	g.Reduce(false, nil, 0)
+
+	data, err := json.Marshal(g)
+	assert.NoError(t, err)
+
+	var d Grid
+	assert.NoError(t, json.Unmarshal(data, &d))
+	assert.Equal(t, *g, d)
+}
+
+func TestGridMarshalBinary(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+
+	data, err := g.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Len(t, data, 54)
+
+	var d Grid
+	assert.NoError(t, d.UnmarshalBinary(data))
+	assert.Equal(t, *g, d)
+
+	g.Reduce(false, nil, 0)
+	data, err = g.MarshalBinary()
+	assert.NoError(t, err)
+	assert.NoError(t, d.UnmarshalBinary(data))
+	assert.Equal(t, *g, d)
+}
+
+func TestGridUnmarshalErrors(t *testing.T) {
+	var g Grid
+	assert.Error(t, g.UnmarshalText([]byte(marshalPuzzle[1:])))
+	assert.Error(t, g.UnmarshalText([]byte(marshalPuzzle+":123")))
+	assert.Error(t, g.UnmarshalBinary([]byte{binaryVersion}))
+	assert.Error(t, json.Unmarshal([]byte(`{"givens":"`+marshalPuzzle+`","candidates":["1"]}`), &g))
+}
+
+func TestGridUnmarshalEmptyCandidates(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+	g.Reduce(false, nil, 0)
+
+	text, err := g.MarshalText()
+	assert.NoError(t, err)
+	colon := strings.IndexByte(string(text), ':')
+	end := strings.IndexByte(string(text), ']')
+	empty := string(text[:colon+1]) + "[]" + string(text[end+1:])
+
+	var d Grid
+	assert.EqualError(t, d.UnmarshalText([]byte(empty)), "empty candidate list")
+
+	data, err := json.Marshal(g)
+	assert.NoError(t, err)
+	var j map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &j))
+	j["candidates"].([]interface{})[0] = ""
+	data, err = json.Marshal(j)
+	assert.NoError(t, err)
+	assert.EqualError(t, json.Unmarshal(data, &d), "empty candidate list")
+}
+
+func TestGameMarshal(t *testing.T) {
+	var game *Game
+	for seed := int64(1); game == nil; seed++ {
+		game = Generate(seed)
+	}
+
+	text, err := game.MarshalText()
+	assert.NoError(t, err)
+	var d Game
+	assert.NoError(t, d.UnmarshalText(text))
+	assert.Equal(t, *game, d)
+
+	data, err := json.Marshal(game)
+	assert.NoError(t, err)
+	d = Game{}
+	assert.NoError(t, json.Unmarshal(data, &d))
+	assert.Equal(t, *game, d)
+
+	data, err = game.MarshalBinary()
+	assert.NoError(t, err)
+	d = Game{}
+	assert.NoError(t, d.UnmarshalBinary(data))
+	assert.Equal(t, *game, d)
+}
+
+func TestGenerateSeed(t *testing.T) {
+	for seed := int64(1); seed < 10; seed++ {
+		g1 := Generate(seed)
+		g2 := Generate(seed)
+		assert.Equal(t, g1, g2)
+	}
+}
+
+// marshalLayouts returns layouts of each kind that the encodings of a grid must keep.
+func marshalLayouts(t *testing.T) map[string]*Layout {
+	res := make(map[string]*Layout)
+	for _, size := range []int{4, 6, 12, 16} {
+		l, err := NewLayout(size)
+		assert.NoError(t, err)
+		res[l.String()] = l
+	}
+
+	x, _ := NewLayout(6)
+	x.AddDiagonals()
+	res["diagonal"] = x
+
+	w, _ := NewLayout(9)
+	assert.NoError(t, w.AddWindows())
+	res["windoku"] = w
+
+	n, _ := NewLayout(9)
+	n.AddNonConsecutive()
+	res["non-consecutive"] = n
+
+	a, _ := NewLayout(9)
+	a.AddAntiKnight()
+	res["anti-knight"] = a
+
+	u, _ := NewLayout(4)
+	assert.NoError(t, u.AddUnit("centre cells", []Position{{1, 1}, {1, 2}, {2, 1}, {2, 2}}, true))
+	res["unit"] = u
+
+	j, _ := NewLayout(4)
+	assert.NoError(t, j.SetRegions("aaabcabbccdbcddd"))
+	res["jigsaw"] = j
+
+	k, _ := NewLayout(4)
+	assert.NoError(t, k.SetCages("aabbacbbbcacbaaa:9,10,5,4,10,2"))
+	res["killer"] = k
+
+	res["samurai"] = NewSamurai()
+
+	return res
+}
+
+// marshalLayoutGrid returns a puzzle on l whose givens are two thirds of a solution, and whose first empty cell has lost a candidate.
+func marshalLayoutGrid(t *testing.T, l *Layout) *Grid {
+	var solution *Grid
+	NewGrid(l).Solutions(1, func(s *Grid) bool {
+		solution = s
+		return false
+	})
+	if !assert.NotNil(t, solution, l.String()) {
+		return nil
+	}
+
+	encoded := []byte(solution.Encode())
+	for i := range encoded {
+		if i%3 == 0 {
+			encoded[i] = '0'
+		}
+	}
+	g, err := ParseLayout(l, string(encoded))
+	assert.NoError(t, err)
+
+	p := l.points[0]
+	g.pt(p).andNot(l.all &^ *solution.pt(p) & (l.all - l.all>>1))
+
+	return g
+}
+
+func TestGridMarshalLayouts(t *testing.T) {
+	for name, l := range marshalLayouts(t) {
+		g := marshalLayoutGrid(t, l)
+		if g == nil {
+			continue
+		}
+		assert.False(t, g.pristine(), name)
+
+		text, err := g.MarshalText()
+		assert.NoError(t, err, name)
+		assert.NotContains(t, string(text), " ", name)
+		var d Grid
+		assert.NoError(t, d.UnmarshalText(text), name)
+		assert.Equal(t, *g, d, name)
+
+		data, err := json.Marshal(g)
+		assert.NoError(t, err, name)
+		d = Grid{}
+		assert.NoError(t, json.Unmarshal(data, &d), name)
+		assert.Equal(t, *g, d, name)
+
+		data, err = g.MarshalBinary()
+		assert.NoError(t, err, name)
+		assert.Equal(t, byte(binaryLayoutVersion), data[0], name)
+		d = Grid{}
+		assert.NoError(t, d.UnmarshalBinary(data), name)
+		assert.Equal(t, *g, d, name)
+
+		game := Game{Level: Easy, Clues: g.Clues(), Strategies: []string{"nakedSingle"}, Puzzle: g}
+		text, err = game.MarshalText()
+		assert.NoError(t, err, name)
+		var dg Game
+		assert.NoError(t, dg.UnmarshalText(text), name)
+		assert.Equal(t, game, dg, name)
+
+		// Givens alone, without candidates.
+		puzzle, _ := ParseLayout(l, g.Encode())
+		data, err = json.Marshal(puzzle)
+		assert.NoError(t, err, name)
+		assert.NotContains(t, string(data), "candidates", name)
+		d = Grid{}
+		assert.NoError(t, json.Unmarshal(data, &d), name)
+		assert.Equal(t, g.Encode(), d.Encode(), name)
+		assert.Equal(t, l.String(), d.Layout().String(), name)
+	}
+}
+
+func TestLayoutMarshalErrors(t *testing.T) {
+	var l Layout
+	assert.Error(t, json.Unmarshal([]byte(`{"size":5}`), &l))
+	assert.Error(t, json.Unmarshal([]byte(`{"size":4,"regions":"aabb"}`), &l))
+	assert.Error(t, json.Unmarshal([]byte(`{"size":4,"pairs":[{"name":"x","offsets":[{"row":0,"col":1}],"rule":"bogus"}]}`), &l))
+	assert.Error(t, json.Unmarshal([]byte(`{"size":4,"units":[{"kind":"x","cells":[{"row":0,"col":0}]}]}`), &l))
+	assert.Error(t, json.Unmarshal([]byte(`{"size":4,"cages":"aabb:3"}`), &l))
+
+	var g Grid
+	assert.Error(t, g.UnmarshalText([]byte(`{"size":4}123`)))
+	assert.Error(t, g.UnmarshalText([]byte(`{"size":4}1234123412341234:1`)))
+	assert.Error(t, json.Unmarshal([]byte(`{"layout":{"size":4},"givens":"123","candidates":["1"]}`), &g))
+	assert.Error(t, g.UnmarshalBinary([]byte{binaryLayoutVersion, 0, 10, '{'}))
+	assert.Error(t, g.UnmarshalBinary([]byte{binaryLayoutVersion, 0, 10, '{', '"', 's', 'i', 'z', 'e', '"', ':', '4', '}', 5}))
+}
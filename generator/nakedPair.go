@@ -26,7 +26,7 @@ package generator
 
 // nakedPair checks a group for 2 cells containing only the same pair of values. If present, those values can be removed from all other cells in the group. It returns true if it changes any cells.
 func (g *Grid) nakedPair(verbose uint) bool {
-	return g.nakedPairGroup(&box, verbose) || g.nakedPairGroup(&col, verbose) || g.nakedPairGroup(&row, verbose)
+	return g.eachGroup(func(gr *group) bool { return g.nakedPairGroup(gr, verbose) })
 }
 
 func (g *Grid) nakedPairGroup(gr *group, verbose uint) (res bool) {
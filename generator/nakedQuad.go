@@ -26,8 +26,7 @@ package generator
 
 // nakedQuad checks a group for 4 cells with the same quad of values. If present, those values can be removed from all other cells in the group. It returns true if it changes any cells.
 func (g *Grid) nakedQuad(verbose uint) bool {
-	return g.nakedQuadGroup(&box, verbose) || g.nakedQuadGroup(&col, verbose) || g.nakedQuadGroup(&row, verbose)
-
+	return g.eachGroup(func(gr *group) bool { return g.nakedQuadGroup(gr, verbose) })
 }
 
 func (g *Grid) nakedQuadGroup(gr *group, verbose uint) (res bool) {
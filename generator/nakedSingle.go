@@ -24,9 +24,9 @@
 
 package generator
 
-// nakedSingle removes a solved digit from all other candidates in the same unit (box, row, or column) and returns true if it modifies the grid.
+// nakedSingle removes a solved digit from all other candidates in the same unit (box, row, column, or extra unit such as a diagonal) or killer cage and returns true if it modifies the grid.
 func (g *Grid) nakedSingle(verbose uint) bool {
-	return g.nakedSingleGroup(&box, verbose) || g.nakedSingleGroup(&col, verbose) || g.nakedSingleGroup(&row, verbose)
+	return g.eachGroup(func(gr *group) bool { return g.nakedSingleGroup(gr, verbose) }) || g.nakedSingleCages(verbose)
 }
 
 func (g *Grid) nakedSingleGroup(gr *group, verbose uint) (res bool) {
@@ -51,3 +51,24 @@ func (g *Grid) nakedSingleGroup(gr *group, verbose uint) (res bool) {
 
 	return
 }
+
+func (g *Grid) nakedSingleCages(verbose uint) (res bool) {
+	l := g.Layout()
+	for _, c := range l.cages {
+		u := l.unitPoints(c.cells)
+		for _, p1 := range u {
+			cell := *g.pt(p1)
+			if bitCount[cell] != 1 {
+				continue
+			}
+
+			for _, p2 := range u {
+				if p1 != p2 && g.pt(p2).andNot(cell) {
+					g.cellChange(&res, verbose, "nakedSingle: in cage at %s cell %s allows only %s, removed from %s\n", u[0], p1, cell, p2)
+				}
+			}
+		}
+	}
+
+	return
+}
@@ -26,7 +26,7 @@ package generator
 
 // nakedTriple checks a group for 3 cells with the same triple of values. If present, those values can be removed from all other cells in the group. It returns true if it changes any cells.
 func (g *Grid) nakedTriple(verbose uint) bool {
-	return g.nakedTripleGroup(&box, verbose) || g.nakedTripleGroup(&col, verbose) || g.nakedTripleGroup(&row, verbose)
+	return g.eachGroup(func(gr *group) bool { return g.nakedTripleGroup(gr, verbose) })
 }
 
 func (g *Grid) nakedTripleGroup(gr *group, verbose uint) (res bool) {
@@ -0,0 +1,342 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// PDFOptions controls the layout of the booklet written by WritePDF. Lengths are in points (1/72 inch); zero values select the defaults.
+type PDFOptions struct {
+	PageWidth, PageHeight float64 // PageWidth and PageHeight are the size of each page (default A4).
+	Margin                float64 // Margin is the blank border around each page (default 36, half an inch).
+	PerPage               int     // PerPage is the number of puzzles on each page (default 2).
+	SolutionsPerPage      int     // SolutionsPerPage is the number of solutions on each page of the answer key (default 12).
+	Title                 string  // Title is printed at the top of every page and recorded as the title of the document.
+	PageNumbers           bool    // PageNumbers prints the number of each page at its foot.
+	AnswerKey             bool    // AnswerKey adds pages with the solutions of the puzzles after the puzzles themselves.
+}
+
+// PageSizes maps the names of common paper sizes to their width and height in points.
+var PageSizes = map[string][2]float64{
+	"a4":     {595.28, 841.89},
+	"a5":     {419.53, 595.28},
+	"letter": {612, 792},
+	"legal":  {612, 1008},
+}
+
+// helveticaWidths are the widths of the printable ASCII characters (' ' to '~') in the standard Helvetica font, in thousandths of the font size.
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// The fonts of a booklet: the standard Helvetica fonts, which every PDF reader provides, so nothing needs to be embedded.
+const (
+	pdfRegular = "F1"
+	pdfBold    = "F2"
+)
+
+type (
+	// pdfPage holds the content stream of a page as it is drawn, and the heading printed at its top.
+	pdfPage struct {
+		bytes.Buffer
+		heading string
+	}
+
+	// pdfAlign is the horizontal alignment of text about its position.
+	pdfAlign int
+)
+
+const (
+	alignLeft pdfAlign = iota
+	alignCenter
+)
+
+// WritePDF writes a print-ready booklet of games to w as a PDF document: the puzzles, PerPage to a page, followed by the solutions if AnswerKey is set. Each grid is drawn with the same geometry as Grid.SVG, with the givens in green. It needs no fonts or external programs.
+func WritePDF(w io.Writer, games []*Game, opts PDFOptions) error {
+	if len(games) == 0 {
+		return errors.New("no games to write")
+	}
+
+	if opts.PageWidth <= 0 || opts.PageHeight <= 0 {
+		opts.PageWidth, opts.PageHeight = PageSizes["a4"][0], PageSizes["a4"][1]
+	}
+	if opts.Margin <= 0 {
+		opts.Margin = 36
+	}
+	if opts.PerPage <= 0 {
+		opts.PerPage = 2
+	}
+	if opts.SolutionsPerPage <= 0 {
+		opts.SolutionsPerPage = 12
+	}
+	if 2*opts.Margin >= math.Min(opts.PageWidth, opts.PageHeight) {
+		return fmt.Errorf("margin of %g points leaves no room on a %g x %g page", opts.Margin, opts.PageWidth, opts.PageHeight)
+	}
+
+	var pages []*pdfPage
+	for i := 0; i < len(games); i += opts.PerPage {
+		p := &pdfPage{heading: opts.Title}
+		slots := opts.slots(opts.PerPage, p.heading != "")
+		for j := 0; j < opts.PerPage && i+j < len(games); j++ {
+			g := games[i+j]
+			label := fmt.Sprintf("%d  %s (%d)", i+j+1, g.Level, g.Clues)
+			p.puzzle(slots[j], label, g.Puzzle)
+		}
+		pages = append(pages, p)
+	}
+
+	if opts.AnswerKey {
+		heading := "Answers"
+		if opts.Title != "" {
+			heading = opts.Title + ": " + heading
+		}
+		for i := 0; i < len(games); i += opts.SolutionsPerPage {
+			p := &pdfPage{heading: heading}
+			slots := opts.slots(opts.SolutionsPerPage, true)
+			for j := 0; j < opts.SolutionsPerPage && i+j < len(games); j++ {
+				if g := games[i+j]; g.Solution != nil {
+					p.puzzle(slots[j], strconv.Itoa(i+j+1), g.Solution)
+				}
+			}
+			pages = append(pages, p)
+		}
+	}
+
+	for n, p := range pages {
+		if p.heading != "" {
+			p.text(pdfBold, 14, opts.Margin, opts.PageHeight-opts.Margin-14, p.heading, alignLeft)
+		}
+		if opts.PageNumbers {
+			p.text(pdfRegular, 10, opts.PageWidth/2, opts.Margin/2, strconv.Itoa(n+1), alignCenter)
+		}
+	}
+
+	return writePDFDocument(w, opts, pages)
+}
+
+// slots divides the part of a page inside the margins, and below the heading if there is one, into n slots of equal size, as nearly square as possible. Each slot is given as its left edge, top edge, width, and height.
+func (opts *PDFOptions) slots(n int, heading bool) [][4]float64 {
+	cols := int(math.Sqrt(float64(n)))
+	if opts.PageWidth > opts.PageHeight { // Landscape pages hold more columns than rows.
+		cols = int(math.Ceil(math.Sqrt(float64(n))))
+	}
+	rowCount := (n + cols - 1) / cols
+
+	top := opts.PageHeight - opts.Margin
+	if heading {
+		top -= 28
+	}
+	width := (opts.PageWidth - 2*opts.Margin) / float64(cols)
+	height := (top - opts.Margin) / float64(rowCount)
+
+	res := make([][4]float64, 0, n)
+	for r := 0; r < rowCount; r++ {
+		for c := 0; c < cols; c++ {
+			res = append(res, [4]float64{opts.Margin + float64(c)*width, top - float64(r)*height, width, height})
+		}
+	}
+
+	return res
+}
+
+// puzzle draws a grid with a label above it, as large as fits in a slot.
+func (p *pdfPage) puzzle(slot [4]float64, label string, g *Grid) {
+	const labelSize = 11
+
+	x, top, width, height := slot[0], slot[1], slot[2], slot[3]
+	size := math.Min(width, height-labelSize-4)
+	if size <= 0 {
+		return
+	}
+
+	left := x + (width-size)/2
+	scale := size / svgSize
+	p.text(pdfRegular, labelSize, left+svgMargin*scale, top-labelSize, label, alignLeft)
+	p.grid(g, left, top-labelSize-4, scale)
+}
+
+// grid draws a grid with the geometry of Grid.SVG, with its top left corner at (x, top) and scale points to each SVG unit.
+func (p *pdfPage) grid(g *Grid, x, top, scale float64) {
+	at := func(u, v float64) (float64, float64) {
+		return x + u*scale, top - v*scale
+	}
+
+	for i := 0; i <= rows; i++ {
+		if i%3 == 0 {
+			continue
+		}
+		x0, y0 := at(svgMargin, float64(svgMargin+i*svgCell))
+		x1, y1 := at(svgMargin+cols*svgCell, float64(svgMargin+i*svgCell))
+		p.line(x0, y0, x1, y1, scale)
+		x0, y0 = at(float64(svgMargin+i*svgCell), svgMargin)
+		x1, y1 = at(float64(svgMargin+i*svgCell), svgMargin+rows*svgCell)
+		p.line(x0, y0, x1, y1, scale)
+	}
+	for i := 0; i <= rows; i += 3 {
+		x0, y0 := at(svgMargin, float64(svgMargin+i*svgCell))
+		x1, y1 := at(svgMargin+cols*svgCell, float64(svgMargin+i*svgCell))
+		p.line(x0, y0, x1, y1, 5*scale)
+		x0, y0 = at(float64(svgMargin+i*svgCell), svgMargin)
+		x1, y1 = at(float64(svgMargin+i*svgCell), svgMargin+rows*svgCell)
+		p.line(x0, y0, x1, y1, 5*scale)
+	}
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			digits := g.cells[r][c].String()
+			if len(digits) != 1 {
+				continue
+			}
+
+			if g.orig[r][c] {
+				p.WriteString("0 0.5 0 rg\n") // SVG green.
+			}
+			tx, ty := at(float64(svgMargin+c*svgCell+svgCell/2), float64(svgMargin+r*svgCell+svgBaseline))
+			p.text(pdfRegular, svgDigit*scale, tx, ty, digits, alignCenter)
+			if g.orig[r][c] {
+				p.WriteString("0 g\n")
+			}
+		}
+	}
+}
+
+// line strokes a line of the given width with round caps.
+func (p *pdfPage) line(x0, y0, x1, y1, width float64) {
+	fmt.Fprintf(p, "%s w 1 J %s %s m %s %s l S\n", pdfNumber(width), pdfNumber(x0), pdfNumber(y0), pdfNumber(x1), pdfNumber(y1))
+}
+
+// text draws a string in a font of the given size with its baseline at y, aligned about x.
+func (p *pdfPage) text(font string, size, x, y float64, s string, align pdfAlign) {
+	if align == alignCenter {
+		x -= textWidth(s, size) / 2
+	}
+	fmt.Fprintf(p, "BT /%s %s Tf %s %s Td %s Tj ET\n", font, pdfNumber(size), pdfNumber(x), pdfNumber(y), pdfString(s))
+}
+
+// textWidth returns the width of a string set in Helvetica at the given size. Characters outside printable ASCII are taken to be as wide as a digit.
+func textWidth(s string, size float64) float64 {
+	w := 0
+	for _, r := range s {
+		if r >= ' ' && r <= '~' {
+			w += helveticaWidths[r-' ']
+		} else {
+			w += 556
+		}
+	}
+
+	return float64(w) * size / 1000
+}
+
+// pdfNumber formats a number for a content stream with at most two decimal places.
+func pdfNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// pdfString returns a PDF literal string for s in WinAnsiEncoding, replacing characters that it cannot represent with '?'.
+func pdfString(s string) string {
+	var b bytes.Buffer
+	b.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= ' ' && r <= '~':
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	b.WriteByte(')')
+
+	return b.String()
+}
+
+// writePDFDocument writes the objects of a PDF document with the given pages to w: the catalog, the page tree, the two fonts, the document information, and a page object and compressed content stream for each page, followed by the cross-reference table.
+func writePDFDocument(w io.Writer, opts PDFOptions, pages []*pdfPage) error {
+	const firstPage = 6 // The number of the object of the first page; the objects before it are fixed.
+
+	var (
+		b       bytes.Buffer
+		offsets []int
+	)
+	object := func(format string, a ...interface{}) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&b, format, a...)
+		b.WriteString("\nendobj\n")
+	}
+
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	var kids bytes.Buffer
+	for i := range pages {
+		fmt.Fprintf(&kids, " %d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Pages /Kids [%s ] /Count %d >>", kids.String(), len(pages))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object("<< /Title %s /Producer (dogdaze.org/sudoku) >>", pdfString(opts.Title))
+
+	for i, p := range pages {
+		object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			pdfNumber(opts.PageWidth), pdfNumber(opts.PageHeight), pdfRegular, pdfBold, firstPage+2*i+1)
+
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(p.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		object("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.Bytes())
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,85 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePDF(t *testing.T) {
+	var games []*Game
+	for i := 0; i < 5; i++ {
+		g, err := ParseEncoded(marshalPuzzle)
+		assert.NoError(t, err)
+		s := *g
+		s.Reduce(true, nil, 0)
+		games = append(games, &Game{Level: Easy, Clues: g.Clues(), Puzzle: g, Solution: &s})
+	}
+
+	var b bytes.Buffer
+	assert.NoError(t, WritePDF(&b, games, PDFOptions{Title: "Book (1)", PageNumbers: true, AnswerKey: true, PerPage: 2}))
+	pdf := b.Bytes()
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), "/Count 4") // Three pages of puzzles and one of answers.
+
+	// Every entry of the cross-reference table must point at its object.
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(pdf)
+	assert.NotNil(t, m)
+	xref, _ := strconv.Atoi(string(m[1]))
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(pdf[xref:], -1)
+	assert.Len(t, offsets, 5+2*4)
+	for i, o := range offsets {
+		n, _ := strconv.Atoi(string(o[1]))
+		assert.True(t, bytes.HasPrefix(pdf[n:], []byte(fmt.Sprintf("%d 0 obj", i+1))), "object %d", i+1)
+	}
+
+	var contents []string
+	for _, s := range regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(pdf, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(s[1]))
+		assert.NoError(t, err)
+		c, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		contents = append(contents, string(c))
+	}
+	assert.Len(t, contents, 4)
+	assert.Contains(t, contents[0], `(Book \(1\)) Tj`)
+	assert.Contains(t, contents[0], fmt.Sprintf(`(1  Easy \(%d\)) Tj`, games[0].Clues))
+	assert.Contains(t, contents[2], fmt.Sprintf(`(5  Easy \(%d\)) Tj`, games[0].Clues))
+	assert.Contains(t, contents[3], "(Book \\(1\\): Answers) Tj")
+	assert.Contains(t, contents[3], "(4) Tj ET")                                                   // The page number.
+	assert.Equal(t, 2*int(games[0].Clues), bytes.Count([]byte(contents[0]), []byte("0 0.5 0 rg"))) // The givens of two puzzles.
+
+	assert.Error(t, WritePDF(&b, nil, PDFOptions{}))
+	assert.Error(t, WritePDF(&b, games, PDFOptions{Margin: 400}))
+}
@@ -0,0 +1,328 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package generator
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// playData is the puzzle, solution and hints embedded in a playable page as JSON.
+	playData struct {
+		Title    string `json:"title"`
+		Puzzle   string `json:"puzzle"`
+		Solution string `json:"solution"`
+		Hints    []hint `json:"hints"`
+	}
+
+	// hint explains how a cell is solved: the strategy of the step that placed its digit, and the note of that step that mentions the cell.
+	hint struct {
+		Cell     int    `json:"cell"`
+		Digit    int    `json:"digit"`
+		Strategy string `json:"strategy"`
+		Note     string `json:"note"`
+	}
+)
+
+// notePoint matches the cells in the notes of a step, which are numbered from zero, so that hints can show them in the usual R1C1 notation.
+var notePoint = regexp.MustCompile(`\((\d), (\d)\)`)
+
+// hints returns a hint for each cell that the logical strategies solve, in the order that they solve them, followed by hints from the solution for any cells that need a search.
+func (g *Game) hints() (res []hint) {
+	steps, _ := g.Puzzle.Steps()
+
+	solved := make(map[int]bool)
+	for _, s := range steps {
+		for _, p := range s.Changed() {
+			after := s.After.cells[p.Row][p.Col]
+			if bitCount[after] != 1 || g.Puzzle.orig[p.Row][p.Col] {
+				continue
+			}
+
+			point := point{uint8(p.Row), uint8(p.Col)}.String()
+			note := ""
+			for _, n := range s.Notes {
+				if strings.Contains(n, point) {
+					note = n
+				}
+			}
+			if i := strings.Index(note, ": "); i >= 0 { // The strategy is named by the hint.
+				note = note[i+2:]
+			}
+			note = notePoint.ReplaceAllStringFunc(note, func(m string) string {
+				sub := notePoint.FindStringSubmatch(m)
+				r, _ := strconv.Atoi(sub[1])
+				c, _ := strconv.Atoi(sub[2])
+				return fmt.Sprintf("R%dC%d", r+1, c+1)
+			})
+
+			i := p.Row*cols + p.Col
+			solved[i] = true
+			res = append(res, hint{i, after.lowestSetBit(), s.Strategy, note})
+		}
+	}
+
+	values := g.Solution.Values()
+	for i := 0; i < rows*cols; i++ {
+		if !solved[i] && !g.Puzzle.orig[i/cols][i%cols] {
+			res = append(res, hint{i, int(values[i] - '0'), "search", ""})
+		}
+	}
+
+	return
+}
+
+// WritePlayable writes a self-contained HTML page to w on which the puzzle of the game can be played in a browser without a network connection. Digits and pencil marks are entered with the keyboard or the buttons under the grid, entries can be checked against the embedded solution and undone, and hints come from the steps that the logical strategies take to solve the puzzle.
+func (g *Game) WritePlayable(w io.Writer, title string) error {
+	data := playData{title, g.Puzzle.Encode(), g.Solution.Values(), g.hints()}
+
+	t := template.Must(template.New("play").Parse(playable))
+	return t.Execute(w, data)
+}
+
+const playable = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <style>
+        body { font-family: sans-serif; display: flex; flex-direction: column; align-items: center; }
+        table { border-collapse: collapse; border: 3px solid black; }
+        td { width: 3em; height: 3em; padding: 0; border: 1px solid gray; text-align: center; vertical-align: middle; cursor: pointer; font-size: 1.4em; }
+        td:nth-child(3n) { border-right: 3px solid black; }
+        tr:nth-child(3n) td { border-bottom: 3px solid black; }
+        td.given { color: green; cursor: default; }
+        td.selected { background: #bbdefb; }
+        td.peer { background: #eceff1; }
+        td.wrong { color: red; }
+        td.hint { background: #fff59d; }
+        td .marks { display: grid; grid-template-columns: repeat(3, 1fr); font-size: 0.45em; color: #555; height: 100%; align-items: center; }
+        .keys button { width: 2.5em; height: 2.5em; font-size: 1.2em; margin: 0.1em; }
+        .tools button { margin: 0.5em 0.2em; padding: 0.4em 0.8em; }
+        .tools button.on { background: #bbdefb; }
+        #message { min-height: 2.5em; max-width: 36em; text-align: center; }
+    </style>
+</head>
+<body>
+    <h1>{{.Title}}</h1>
+    <table id="grid"></table>
+    <p id="message"></p>
+    <div class="keys">
+        <button>1</button><button>2</button><button>3</button><button>4</button><button>5</button><button>6</button><button>7</button><button>8</button><button>9</button><button>&#x232b;</button>
+    </div>
+    <div class="tools">
+        <button id="pencil" title="Toggle pencil marks (P)">Pencil</button>
+        <button id="undo" title="Undo (Z)">Undo</button>
+        <button id="check" title="Check against the solution (C)">Check</button>
+        <button id="hint" title="Hint (H)">Hint</button>
+    </div>
+    <script>
+    (function () {
+        "use strict";
+        var data = {{.}};
+        var values = [], marks = [], cells = [], history = [];
+        var selected = -1, pencil = false, hinted = -1;
+
+        function name(i) { return "R" + (Math.floor(i / 9) + 1) + "C" + (i % 9 + 1); }
+        function given(i) { return data.puzzle[i] !== "0"; }
+        function say(text) { document.getElementById("message").textContent = text; }
+
+        function draw() {
+            for (var i = 0; i < 81; i++) {
+                var td = cells[i];
+                td.classList.remove("selected", "peer", "hint");
+                if (values[i]) {
+                    td.textContent = values[i];
+                } else {
+                    td.textContent = "";
+                    if (marks[i].length) {
+                        var grid = document.createElement("div");
+                        grid.className = "marks";
+                        for (var d = 1; d <= 9; d++) {
+                            var span = document.createElement("span");
+                            span.textContent = marks[i].indexOf(d) >= 0 ? d : "";
+                            grid.appendChild(span);
+                        }
+                        td.appendChild(grid);
+                    }
+                }
+                if (selected >= 0 && i !== selected && (Math.floor(i / 9) === Math.floor(selected / 9) || i % 9 === selected % 9 ||
+                        (Math.floor(i / 27) === Math.floor(selected / 27) && Math.floor(i % 9 / 3) === Math.floor(selected % 9 / 3)))) {
+                    td.classList.add("peer");
+                }
+            }
+            if (selected >= 0) {
+                cells[selected].classList.add("selected");
+            }
+            if (hinted >= 0) {
+                cells[hinted].classList.add("hint");
+            }
+            document.getElementById("pencil").classList.toggle("on", pencil);
+        }
+
+        // change records the current state of a cell for undo and then applies f to it.
+        function change(i, f) {
+            if (i < 0 || given(i)) {
+                return;
+            }
+            history.push({cell: i, value: values[i], marks: marks[i].slice()});
+            f();
+            cells[i].classList.remove("wrong");
+            if (values.join("") === data.solution) {
+                say("Solved!");
+            }
+            draw();
+        }
+
+        function enter(d) {
+            var i = selected;
+            change(i, function () {
+                if (d === 0) {
+                    values[i] = 0;
+                    marks[i] = [];
+                } else if (pencil && !values[i]) {
+                    var k = marks[i].indexOf(d);
+                    if (k >= 0) {
+                        marks[i].splice(k, 1);
+                    } else {
+                        marks[i].push(d);
+                    }
+                } else {
+                    values[i] = values[i] === d ? 0 : d;
+                }
+            });
+        }
+
+        function undo() {
+            var h = history.pop();
+            if (h) {
+                values[h.cell] = h.value;
+                marks[h.cell] = h.marks;
+                cells[h.cell].classList.remove("wrong");
+                selected = h.cell;
+                draw();
+            }
+        }
+
+        function check() {
+            var wrong = 0, empty = 0;
+            for (var i = 0; i < 81; i++) {
+                var bad = values[i] && String(values[i]) !== data.solution[i];
+                cells[i].classList.toggle("wrong", !!bad);
+                if (bad) {
+                    wrong++;
+                }
+                if (!values[i]) {
+                    empty++;
+                }
+            }
+            say(wrong ? wrong + (wrong === 1 ? " digit is" : " digits are") + " wrong." : empty ? "So far, so good: " + empty + " cells to go." : "Solved!");
+        }
+
+        // hint points to the next cell that the solving steps fill and that is not yet correct; asking again for the same cell fills it in.
+        function hint() {
+            for (var k = 0; k < data.hints.length; k++) {
+                var h = data.hints[k];
+                if (String(values[h.cell]) === data.solution[h.cell]) {
+                    continue;
+                }
+                if (hinted === h.cell) {
+                    selected = h.cell;
+                    change(h.cell, function () { values[h.cell] = h.digit; });
+                    hinted = -1;
+                    say(name(h.cell) + " is " + h.digit + ".");
+                } else {
+                    hinted = selected = h.cell;
+                    say("Look at " + name(h.cell) + (h.strategy === "search" ? ": no strategy solves it, so it needs trial and error." : ", which " + h.strategy + " solves" + (h.note ? ": " + h.note + "." : ".")) + " Ask again to fill it in.");
+                }
+                draw();
+                return;
+            }
+            say("There is nothing left to hint at.");
+        }
+
+        var table = document.getElementById("grid");
+        for (var r = 0; r < 9; r++) {
+            var tr = table.insertRow();
+            for (var c = 0; c < 9; c++) {
+                var i = r * 9 + c;
+                var td = tr.insertCell();
+                values.push(given(i) ? Number(data.puzzle[i]) : 0);
+                marks.push([]);
+                cells.push(td);
+                if (given(i)) {
+                    td.className = "given";
+                }
+                td.addEventListener("click", (function (i) {
+                    return function () { selected = i; draw(); };
+                })(i));
+            }
+        }
+
+        document.querySelectorAll(".keys button").forEach(function (b, k) {
+            b.addEventListener("click", function () { enter(k < 9 ? k + 1 : 0); });
+        });
+        document.getElementById("pencil").addEventListener("click", function () { pencil = !pencil; draw(); });
+        document.getElementById("undo").addEventListener("click", undo);
+        document.getElementById("check").addEventListener("click", check);
+        document.getElementById("hint").addEventListener("click", hint);
+
+        document.addEventListener("keydown", function (e) {
+            var k = e.key.toLowerCase();
+            if (e.ctrlKey || e.metaKey || e.altKey) {
+                return;
+            } else if (k >= "1" && k <= "9") {
+                enter(Number(k));
+            } else if (k === "0" || k === "backspace" || k === "delete") {
+                enter(0);
+            } else if (k === "p") {
+                pencil = !pencil;
+            } else if (k === "z") {
+                undo();
+            } else if (k === "c") {
+                check();
+            } else if (k === "h") {
+                hint();
+            } else if (k.indexOf("arrow") === 0 && selected >= 0) {
+                var dr = {arrowup: 8, arrowdown: 1}[k] || 0, dc = {arrowleft: 8, arrowright: 1}[k] || 0;
+                selected = (Math.floor(selected / 9) + dr) % 9 * 9 + (selected % 9 + dc) % 9;
+            } else {
+                return;
+            }
+            e.preventDefault();
+            draw();
+        });
+
+        draw();
+    })();
+    </script>
+</body>
+</html>
+`
@@ -0,0 +1,60 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package generator
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePlayable(t *testing.T) {
+	game := Generate(1)
+
+	var b strings.Builder
+	assert.NoError(t, game.WritePlayable(&b, "Puzzle <1>"))
+	page := b.String()
+	assert.Contains(t, page, "<title>Puzzle &lt;1&gt;</title>")
+
+	start := strings.Index(page, "var data = ")
+	assert.True(t, start > 0)
+	end := strings.Index(page[start:], ";\n")
+	var data playData
+	assert.NoError(t, json.Unmarshal([]byte(page[start+len("var data = "):start+end]), &data))
+
+	assert.Equal(t, game.Puzzle.Encode(), data.Puzzle)
+	assert.Equal(t, game.Solution.Values(), data.Solution)
+	assert.Len(t, data.Hints, rows*cols-int(game.Clues))
+
+	seen := make(map[int]bool)
+	for _, h := range data.Hints {
+		assert.False(t, seen[h.Cell])
+		seen[h.Cell] = true
+		assert.Equal(t, byte('0'), data.Puzzle[h.Cell])
+		assert.Equal(t, data.Solution[h.Cell], byte('0'+h.Digit))
+		assert.NotRegexp(t, `\(\d, \d\)`, h.Note) // Cells are in R1C1 notation.
+	}
+}
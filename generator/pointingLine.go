@@ -24,49 +24,52 @@
 
 package generator
 
-// pointingLine removes candidates. When a candidate within a box appears only in a single column or row, that candidate can be removed from all cells in the column or row outside of the box. It returns true if it changes any cells.
+// pointingLine removes candidates. When a candidate within a box (or any other unit that is not a line, such as a diagonal or window) appears only where the box overlaps a single column, row or other unit, that candidate can be removed from all cells in that unit outside of the box. It returns true if it changes any cells.
 func (g *Grid) pointingLine(verbose uint) bool {
-	return g.pointingLineGroup("col", verbose, func(p point) *[9]point {
-		return &col.unit[p.c]
-	}, func(p point) uint8 {
-		return p.c
-	}) || g.pointingLineGroup("row", verbose, func(p point) *[9]point {
-		return &row.unit[p.r]
-	}, func(p point) uint8 {
-		return p.r
-	})
+	l := g.Layout()
+	var boxes []int
+	for ui := range l.units {
+		if !l.line(ui) {
+			boxes = append(boxes, ui)
+		}
+	}
+
+	// The units pointed along are taken a group at a time (the columns before the rows), so that each pass over the boxes looks at one kind of line.
+	for _, members := range l.members {
+		if g.lockedCandidates(boxes, members, verbose, "pointingLine: in %[2]s removing %[1]d from %[4]s along %[3]s\n") {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (g *Grid) pointingLineGroup(
-	gr string,
-	verbose uint,
-	sel func(point) *[9]point,
-	axis func(point) uint8,
-) (res bool) {
-	for ui, u := range box.unit {
-		points := g.digitPoints(u)
+// lockedCandidates removes a digit from the cells of unit b outside unit a when every place for the digit in a lies inside b, for each unit a of from and each unit b of into that overlaps it. The changes are reported with format, which is given the digit, the names of a and b, and the point changed. It returns true if it changes any cells.
+func (g *Grid) lockedCandidates(from, into []int, verbose uint, format string) (res bool) {
+	l := g.Layout()
+	for _, a := range from {
+		ua := l.units[a]
+		points := g.digitPoints(ua.points)
 
-		// Loop through the digits and determine if all of them are on the same line (col or row). If so, then all other cells in that line that are not in the current box can have those digits removed.
-	outer:
-		for d := 1; d <= 9; d++ {
+		for d := 1; d <= l.Size; d++ {
 			if len(points[d]) == 0 {
-				return false
+				continue
 			}
 
-			a := axis(points[d][0])
-			for _, p := range points[d][1:] {
-				if axis(p) != a { // All points for each digit must be on the same axis (row or column).
-					continue outer
-				}
-			}
-
-			for _, p := range sel(points[d][0]) {
-				if int(p.r/3*3+p.c/3) == ui { // If the point is on the axis (row or column) of interest, skip it.
+			for _, b := range l.crosses[a] {
+				if !containsInt(into, b) || !l.within(points[d], b) {
 					continue
 				}
 
-				if g.pt(p).andNot(1 << d) {
-					g.cellChange(&res, verbose, "pointingLine: in box %d removing %d from %s along %s %d\n", ui, d, p, gr, a)
+				ub := l.units[b]
+				for _, p := range ub.points {
+					if containsInt(ua.cells, l.index(p)) {
+						continue
+					}
+
+					if g.pt(p).andNot(1 << d) {
+						g.cellChange(&res, verbose, format, d, ua.name, ub.name, p)
+					}
 				}
 			}
 		}
@@ -74,3 +77,14 @@ func (g *Grid) pointingLineGroup(
 
 	return
 }
+
+// within returns true if all of the points lie in unit u.
+func (l *Layout) within(points []point, u int) bool {
+	for _, p := range points {
+		if !containsInt(l.units[u].cells, l.index(p)) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -29,7 +29,7 @@ import "fmt"
 type positions uint16
 
 func (p positions) places() (res []int) {
-	for i := 0; i < 9; i++ {
+	for i := 0; i < maxSize; i++ {
 		if p&(1<<i) != 0 {
 			res = append(res, i)
 		}
@@ -0,0 +1,285 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	imagecolor "image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+)
+
+// cssDPI is the resolution at which one SVG unit is one pixel: the SVG of a grid is drawn on a 500 x 500 pixel canvas at scale 1.
+const cssDPI = 96
+
+// MaxDPI is the highest resolution that Image draws at, giving a 3125 x 3125 pixel image of about 39 MB.
+const MaxDPI = 600
+
+var (
+	rasterWhite = imagecolor.RGBA{255, 255, 255, 255}
+	rasterBlack = imagecolor.RGBA{0, 0, 0, 255}
+	rasterGreen = imagecolor.RGBA{0, 128, 0, 255}
+	rasterBlue  = imagecolor.RGBA{0, 0, 255, 255}
+	rasterRed   = imagecolor.RGBA{255, 0, 0, 255}
+)
+
+type (
+	// raster draws antialiased shapes given in SVG units on an image, scale pixels to the unit.
+	raster struct {
+		img   *image.RGBA
+		scale float64
+	}
+
+	// glyphPoint is a point of a glyph stroke, in a box 0.6 wide and 1 high with y increasing downwards and the baseline at 1.
+	glyphPoint struct {
+		x, y float64
+	}
+)
+
+// glyphs are the digits 1 - 9 as strokes, since the standard library has no fonts. They are drawn with lines of uniform width, like a plotter font.
+var glyphs = func() map[byte][][]glyphPoint {
+	arc := func(cx, cy, rx, ry, from, to float64) []glyphPoint {
+		const steps = 24
+		res := make([]glyphPoint, 0, steps+1)
+		for i := 0; i <= steps; i++ {
+			a := (from + (to-from)*float64(i)/steps) * math.Pi / 180
+			res = append(res, glyphPoint{cx + rx*math.Cos(a), cy + ry*math.Sin(a)})
+		}
+		return res
+	}
+
+	six := [][]glyphPoint{
+		arc(0.3, 0.7, 0.27, 0.3, 0, 360),
+		arc(0.57, 0.7, 0.54, 0.68, 250, 180),
+	}
+	nine := make([][]glyphPoint, len(six))
+	for i, s := range six {
+		for _, p := range s {
+			nine[i] = append(nine[i], glyphPoint{0.6 - p.x, 1 - p.y})
+		}
+	}
+
+	return map[byte][][]glyphPoint{
+		'1': {{{0.12, 0.2}, {0.38, 0}, {0.38, 1}}},
+		'2': {append(arc(0.3, 0.27, 0.27, 0.27, 200, 380), glyphPoint{0.02, 1}, glyphPoint{0.6, 1})},
+		'3': {arc(0.3, 0.25, 0.26, 0.25, 200, 450), arc(0.3, 0.73, 0.29, 0.27, 270, 520)},
+		'4': {{{0.45, 1}, {0.45, 0}, {0, 0.7}, {0.6, 0.7}}},
+		'5': {append([]glyphPoint{{0.55, 0}, {0.1, 0}, {0.06, 0.47}}, arc(0.3, 0.68, 0.28, 0.32, 215, 520)...)},
+		'6': six,
+		'7': {{{0, 0}, {0.6, 0}, {0.2, 1}}},
+		'8': {arc(0.3, 0.25, 0.24, 0.24, 0, 360), arc(0.3, 0.73, 0.29, 0.27, 0, 360)},
+		'9': nine,
+	}
+}()
+
+// Image draws a grid as a raster image with the same layout as SVG: givens in green, placed digits in black and, if showCandidates is set, the candidates of unsolved cells coloured by colors. The image is drawn at dpi dots per inch, where 96 (or 0) gives the 500 x 500 pixels of the SVG at scale 1. It returns ErrNotStandard if the grid has a layout, and an error if dpi is above MaxDPI.
+func (g *Grid) Image(dpi float64, showCandidates bool, colors *[maxRows][maxCols][maxSize + 1]color) (*image.RGBA, error) {
+	if !g.Standard() {
+		return nil, ErrNotStandard
+	}
+	if dpi <= 0 {
+		dpi = cssDPI
+	}
+	if !(dpi <= MaxDPI) {
+		return nil, fmt.Errorf("dpi %g is above the maximum of %d", dpi, MaxDPI)
+	}
+
+	scale := dpi / cssDPI
+	size := int(math.Ceil(svgSize * scale))
+	r := raster{image.NewRGBA(image.Rect(0, 0, size, size)), scale}
+	r.fill(0, 0, svgSize, svgSize, rasterWhite)
+
+	const gridSize = rows * svgCell
+	for i := 0; i <= rows; i++ {
+		width := 1.0
+		if i%3 == 0 {
+			width = 5
+		}
+		at := float64(svgMargin + i*svgCell)
+		r.fill(svgMargin-width/2, at-width/2, svgMargin+gridSize+width/2, at+width/2, rasterBlack)
+		r.fill(at-width/2, svgMargin-width/2, at+width/2, svgMargin+gridSize+width/2, rasterBlack)
+	}
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			x, y := float64(svgMargin+col*svgCell), float64(svgMargin+row*svgCell)
+			cell := g.cells[row][col]
+			if digits := cell.String(); len(digits) == 1 {
+				c := rasterBlack
+				if g.orig[row][col] {
+					c = rasterGreen
+				}
+				r.glyph(digits[0], x+svgCell/2, y+svgBaseline, 0.72*svgDigit, c)
+			} else if showCandidates {
+				for d := 1; d <= 9; d++ {
+					if cell&(1<<d) == 0 {
+						continue
+					}
+
+					c := rasterBlack
+					if colors != nil {
+						switch colors[row][col][d] {
+						case blue:
+							c = rasterBlue
+						case red:
+							c = rasterRed
+						}
+					}
+					cr, cc := (d-1)/3, (d-1)%3
+					r.glyph(byte('0'+d), x+10+float64(cc*15), y+13+float64(cr*15), 6, c)
+				}
+			}
+		}
+	}
+
+	return r.img, nil
+}
+
+// WritePNG writes the image of a grid drawn by Image to w in PNG format, recording its resolution so that it prints at the intended size.
+func (g *Grid) WritePNG(w io.Writer, dpi float64, showCandidates bool, colors *[maxRows][maxCols][maxSize + 1]color) error {
+	if dpi <= 0 {
+		dpi = cssDPI
+	}
+
+	img, err := g.Image(dpi, showCandidates, colors)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return err
+	}
+
+	_, err = w.Write(withPHYs(b.Bytes(), dpi))
+	return err
+}
+
+// WriteJPEG writes the image of a grid drawn by Image to w in JPEG format at the given quality (1 - 100).
+func (g *Grid) WriteJPEG(w io.Writer, dpi float64, quality int, showCandidates bool, colors *[maxRows][maxCols][maxSize + 1]color) error {
+	img, err := g.Image(dpi, showCandidates, colors)
+	if err != nil {
+		return err
+	}
+
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
+// withPHYs inserts a pHYs chunk giving the resolution in dpi after the IHDR chunk of an encoded PNG, which image/png does not write.
+func withPHYs(data []byte, dpi float64) []byte {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4 // The signature, then the length, type, data and CRC of IHDR.
+	if len(data) < ihdrEnd {
+		return data
+	}
+
+	ppm := uint32(math.Round(dpi / 0.0254)) // Pixels per metre.
+	chunk := make([]byte, 4+4+9+4)
+	binary.BigEndian.PutUint32(chunk, 9)
+	copy(chunk[4:], "pHYs")
+	binary.BigEndian.PutUint32(chunk[8:], ppm)
+	binary.BigEndian.PutUint32(chunk[12:], ppm)
+	chunk[16] = 1 // The unit is the metre.
+	binary.BigEndian.PutUint32(chunk[17:], crc32.ChecksumIEEE(chunk[4:17]))
+
+	res := make([]byte, 0, len(data)+len(chunk))
+	res = append(res, data[:ihdrEnd]...)
+	res = append(res, chunk...)
+	return append(res, data[ihdrEnd:]...)
+}
+
+// blend mixes c into the pixel at (x, y) in proportion to coverage (0 - 1).
+func (r *raster) blend(x, y int, c imagecolor.RGBA, coverage float64) {
+	if coverage <= 0 || !(image.Point{x, y}).In(r.img.Rect) {
+		return
+	}
+	if coverage > 1 {
+		coverage = 1
+	}
+
+	o := r.img.RGBAAt(x, y)
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a)*(1-coverage) + float64(b)*coverage))
+	}
+	r.img.SetRGBA(x, y, imagecolor.RGBA{mix(o.R, c.R), mix(o.G, c.G), mix(o.B, c.B), 255})
+}
+
+// fill fills the rectangle from (x0, y0) to (x1, y1) in SVG units, shading the pixels on its edges by how much of them it covers.
+func (r *raster) fill(x0, y0, x1, y1 float64, c imagecolor.RGBA) {
+	x0, y0, x1, y1 = x0*r.scale, y0*r.scale, x1*r.scale, y1*r.scale
+	for py := int(math.Floor(y0)); float64(py) < y1; py++ {
+		cy := math.Min(y1, float64(py+1)) - math.Max(y0, float64(py))
+		for px := int(math.Floor(x0)); float64(px) < x1; px++ {
+			cx := math.Min(x1, float64(px+1)) - math.Max(x0, float64(px))
+			r.blend(px, py, c, cx*cy)
+		}
+	}
+}
+
+// glyph draws a digit with its baseline centred at (x, y) in SVG units, height units tall.
+func (r *raster) glyph(ch byte, x, y, height float64, c imagecolor.RGBA) {
+	strokes, ok := glyphs[ch]
+	if !ok {
+		return
+	}
+
+	h := height * r.scale
+	left, top := x*r.scale-0.3*h, y*r.scale-h
+	width := math.Max(0.1*h, 1) // The width of the strokes in pixels.
+
+	var segments [][4]float64
+	for _, s := range strokes {
+		for i := 1; i < len(s); i++ {
+			segments = append(segments, [4]float64{left + s[i-1].x*h, top + s[i-1].y*h, left + s[i].x*h, top + s[i].y*h})
+		}
+	}
+
+	pad := width
+	for py := int(math.Floor(top - pad)); float64(py) <= top+h+pad; py++ {
+		for px := int(math.Floor(left - pad)); float64(px) <= left+0.6*h+pad; px++ {
+			d := math.Inf(1)
+			for _, s := range segments {
+				d = math.Min(d, segmentDistance(float64(px)+0.5, float64(py)+0.5, s))
+			}
+			r.blend(px, py, c, width/2-d+0.5)
+		}
+	}
+}
+
+// segmentDistance returns the distance from (x, y) to the line segment s, given as its two end points.
+func segmentDistance(x, y float64, s [4]float64) float64 {
+	dx, dy := s[2]-s[0], s[3]-s[1]
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((x-s[0])*dx+(y-s[1])*dy)/l))
+	}
+
+	return math.Hypot(x-s[0]-t*dx, y-s[1]-t*dy)
+}
@@ -0,0 +1,89 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGridImage(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+
+	img, err := g.Image(0, false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 500, img.Bounds().Dx())
+	assert.Equal(t, rasterBlack, img.RGBAAt(svgMargin, 200))           // The left edge of the grid.
+	assert.Equal(t, rasterWhite, img.RGBAAt(svgMargin+5, svgMargin+5)) // The top left corner of an empty cell.
+
+	// The stem of the given 1 in row 4, column 5 runs down the middle of its cell.
+	assert.Equal(t, rasterGreen, img.RGBAAt(5*svgCell+svgMargin+svgCell/2+1, 4*svgCell+svgMargin+svgBaseline-5))
+
+	var colors [maxRows][maxCols][maxSize + 1]color
+	colors[0][0][1] = red
+	g.Reduce(false, nil, 0)
+	img, err = g.Image(192, true, &colors)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, img.Bounds().Dx())
+	found := false
+	for y := 0; y < 2*(svgMargin+svgCell/3); y++ {
+		for x := 0; x < 2*(svgMargin+svgCell/3); x++ {
+			c := img.RGBAAt(x, y)
+			found = found || c.R > 200 && c.G < 100 && c.B < 100
+		}
+	}
+	assert.True(t, found, "red candidate")
+
+	var b bytes.Buffer
+	assert.NoError(t, g.WritePNG(&b, 300, true, nil))
+	i := bytes.Index(b.Bytes(), []byte("pHYs"))
+	assert.Equal(t, 37, i)
+	assert.Equal(t, uint32(11811), binary.BigEndian.Uint32(b.Bytes()[i+4:])) // 300 dpi in pixels per metre.
+	decoded, err := png.Decode(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1563, decoded.Bounds().Dx())
+
+	b.Reset()
+	assert.NoError(t, g.WriteJPEG(&b, 0, 90, false, nil))
+	decoded, err = jpeg.Decode(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, 500, decoded.Bounds().Dy())
+
+	img, err = g.Image(MaxDPI, false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3125, img.Bounds().Dx())
+	for _, dpi := range []float64{MaxDPI + 1, 100000, math.Inf(1), math.NaN()} {
+		_, err = g.Image(dpi, false, nil)
+		assert.Error(t, err, "%g", dpi)
+		assert.Error(t, g.WritePNG(&b, dpi, false, nil), "%g", dpi)
+	}
+}
@@ -0,0 +1,192 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import "fmt"
+
+// samuraiGrids and butterflyGrids are the top left corners of the 9 x 9 grids of the Samurai and butterfly layouts.
+var (
+	samuraiGrids   = []Position{{0, 0}, {0, 12}, {6, 6}, {12, 0}, {12, 12}}
+	butterflyGrids = []Position{{0, 0}, {0, 3}, {3, 0}, {3, 3}}
+)
+
+// samuraiCells is the number of cells in a Samurai puzzle: five grids, four of whose boxes are each shared by two grids.
+const samuraiCells = 5*rows*cols - 4*3*3
+
+// NewSamurai returns the layout of a Samurai puzzle: five 9 x 9 grids on a 21 x 21 board, with the centre grid sharing a corner box with each of the other four.
+func NewSamurai() *Layout {
+	l, err := NewMultiLayout("samurai", samuraiGrids)
+	if err != nil {
+		panic(err)
+	}
+
+	return l
+}
+
+// NewButterfly returns the layout of a butterfly puzzle: four 9 x 9 grids overlapping on a 12 x 12 board, each sharing six rows or columns with two of the others.
+func NewButterfly() *Layout {
+	l, err := NewMultiLayout("butterfly", butterflyGrids)
+	if err != nil {
+		panic(err)
+	}
+
+	return l
+}
+
+// NewMultiLayout returns a layout made of standard 9 x 9 grids whose top left corners are at grids. The grids may overlap, but only on whole boxes, so each corner must lie on a multiple of 3. Each grid has its own rows and columns, the boxes are shared, and the board cells outside every grid are absent from the layout. The name is added to the rules of the layout.
+func NewMultiLayout(name string, grids []Position) (*Layout, error) {
+	if len(grids) == 0 {
+		return nil, fmt.Errorf("%s layout has no grids", name)
+	}
+
+	l := &Layout{
+		Size:    rows,
+		BoxRows: 3,
+		BoxCols: 3,
+		all:     all,
+		rules:   []string{name},
+		grids:   append([]Position(nil), grids...),
+	}
+	for _, g := range grids {
+		if g.Row < 0 || g.Col < 0 || g.Row%3 != 0 || g.Col%3 != 0 {
+			return nil, fmt.Errorf("grid of %s layout at %s does not start on a box", name, g)
+		}
+		if g.Row+rows > l.Height {
+			l.Height = g.Row + rows
+		}
+		if g.Col+cols > l.Width {
+			l.Width = g.Col + cols
+		}
+	}
+
+	l.present = make([]bool, l.Cells())
+	for _, g := range grids {
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				l.present[(g.Row+r)*l.Width+g.Col+c] = true
+			}
+		}
+	}
+
+	// Every box of the board is numbered so that boxes keep their positions; those outside every grid are left empty and absent cells get no region.
+	l.region = make([]int, l.Cells())
+	for i := range l.region {
+		l.region[i] = -1
+		if l.present[i] {
+			l.region[i] = i/l.Width/3*(l.Width/3) + i%l.Width/3
+		}
+	}
+
+	for _, g := range grids {
+		for r := 0; r < rows; r++ {
+			cells := make([]int, cols)
+			for c := range cells {
+				cells[c] = (g.Row+r)*l.Width + g.Col + c
+			}
+			l.units = append(l.units, layoutUnit{kind: "row", cells: cells})
+		}
+		for c := 0; c < cols; c++ {
+			cells := make([]int, rows)
+			for r := range cells {
+				cells[r] = (g.Row+r)*l.Width + g.Col + c
+			}
+			l.units = append(l.units, layoutUnit{kind: "col", cells: cells})
+		}
+	}
+
+	boxes := make([][]int, l.Height/3*(l.Width/3))
+	for i, b := range l.region {
+		if b >= 0 {
+			boxes[b] = append(boxes[b], i)
+		}
+	}
+	for _, cells := range boxes {
+		if len(cells) > 0 {
+			l.units = append(l.units, layoutUnit{kind: "box", cells: cells})
+		}
+	}
+
+	// Grids such as those of the butterfly may cover the whole board, leaving no cell absent.
+	if l.Length() == l.Cells() {
+		l.present = nil
+	}
+	l.link()
+
+	return l, nil
+}
+
+// Grids returns the top left corners of the standard 9 x 9 grids that make up the layout: one for a plain 9 x 9 layout, several for a Samurai, and none for other sizes or jigsaw layouts.
+func (l *Layout) Grids() []Position {
+	return append([]Position(nil), l.grids...)
+}
+
+// has returns true if cell i is part of the layout. Only multi-grid layouts such as Samurai have absent cells.
+func (l *Layout) has(i int) bool {
+	return l.present == nil || l.present[i]
+}
+
+// Length returns the number of characters in an encoded puzzle on the layout: one for each cell that is present.
+func (l *Layout) Length() int {
+	if l.present == nil {
+		return l.Cells()
+	}
+
+	n := 0
+	for _, p := range l.present {
+		if p {
+			n++
+		}
+	}
+
+	return n
+}
+
+// eachGrid calls f with each standard 9 x 9 grid of g until f returns true, for the strategies that only know about rows, columns and 3 x 3 boxes. A standard grid is passed as it is; each grid of any other layout is copied into a standard grid and, if f changed it, copied back. Any extra units of the layout only remove solutions, so what holds for a grid without them holds for it with them. It returns whether f returned true.
+func (g *Grid) eachGrid(f func(*Grid) bool) bool {
+	if g.layout == nil {
+		return f(g)
+	}
+
+	for _, o := range g.layout.grids {
+		sub := Grid{trace: g.trace}
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				sub.orig[r][c] = g.orig[o.Row+r][o.Col+c]
+				sub.cells[r][c] = g.cells[o.Row+r][o.Col+c]
+			}
+		}
+
+		if f(&sub) {
+			for r := 0; r < rows; r++ {
+				for c := 0; c < cols; c++ {
+					g.cells[o.Row+r][o.Col+c] = sub.cells[r][c]
+				}
+			}
+			return true
+		}
+	}
+
+	return false
+}
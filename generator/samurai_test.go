@@ -0,0 +1,133 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSamurai(t *testing.T) {
+	l := NewSamurai()
+	assert.Equal(t, "21x21 samurai", l.String())
+	assert.Equal(t, 21*21, l.Cells())
+	assert.Equal(t, 369, l.Length())
+	assert.Len(t, l.units, 5*18+41)
+	assert.False(t, l.has(9))
+	assert.Equal(t, -1, l.region[9])
+	assert.Len(t, l.peers[0], 20)
+	assert.Len(t, l.peers[6*21+6], 32) // In the corner box shared by the top left and centre grids.
+	assert.Nil(t, l.peers[9])
+	assert.Len(t, l.lines, 5)
+	assert.Len(t, l.groups[2].unit, 5*9)
+
+	same, err := LayoutOf(369)
+	assert.NoError(t, err)
+	assert.Equal(t, l.String(), same.String())
+
+	assert.Error(t, l.SetRegions(strings.Repeat("1", 21*21)))
+
+	b := NewButterfly()
+	assert.Equal(t, "12x12 butterfly", b.String())
+	assert.Nil(t, b.present)
+	assert.Equal(t, 144, b.Length())
+	assert.Len(t, b.units, 4*18+16)
+
+	_, err = NewMultiLayout("bad", []Position{{0, 0}, {4, 4}})
+	assert.Error(t, err)
+	_, err = NewMultiLayout("none", nil)
+	assert.Error(t, err)
+}
+
+func TestParseSamurai(t *testing.T) {
+	l := NewSamurai()
+	_, err := ParseLayout(l, strings.Repeat(".", 21*21))
+	assert.Error(t, err)
+
+	p := strings.Repeat(".", 368) + "9"
+	g, err := ParseLayout(nil, p)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), g.Clues())
+	assert.True(t, g.orig[20][20])
+	assert.Equal(t, strings.Replace(p, ".", "0", -1), g.Encode())
+	assert.Len(t, g.Values(), 369)
+
+	var b bytes.Buffer
+	g.DisplayTo(&b)
+	assert.Contains(t, b.String(), "9 │")
+}
+
+func TestGenerateSamurai(t *testing.T) {
+	for _, l := range []*Layout{NewSamurai(), NewButterfly()} {
+		game := GenerateLayout(l, 1)
+		if !assert.NotNil(t, game, l.String()) {
+			continue
+		}
+
+		assert.True(t, game.Solution.solved(), l.String())
+		assert.Equal(t, 1, game.Puzzle.CountSolutions(0), l.String())
+		assert.Equal(t, l.Length(), len(game.Puzzle.Encode()), l.String())
+
+		// Every grid of the solution is a solved Sudoku on its own, and the grids agree where they overlap.
+		grids := 0
+		game.Solution.eachGrid(func(sub *Grid) bool {
+			assert.True(t, sub.solved(), l.String())
+			grids++
+			return false
+		})
+		assert.Equal(t, len(l.grids), grids, l.String())
+
+		g := *game.Puzzle
+		_, solved := g.Reduce(true, nil, 0)
+		assert.True(t, solved, l.String())
+		assert.Equal(t, game.Solution.Values(), g.Values(), l.String())
+
+		svg := game.Puzzle.SVG(1, false, false, nil)
+		assert.Equal(t, int(game.Clues), strings.Count(svg, "</text>"), l.String())
+	}
+}
+
+func TestGenerateSamuraiTime(t *testing.T) {
+	// Before the search for x-cycles was limited, some of these seeds ran for minutes.
+	for _, l := range []*Layout{NewSamurai(), NewButterfly()} {
+		for seed := int64(0); seed < 6; seed++ {
+			done := make(chan *Game, 1)
+			go func(l *Layout, seed int64) {
+				done <- GenerateLayout(l, seed)
+			}(l, seed)
+
+			select {
+			case game := <-done:
+				assert.NotNil(t, game, "%s %d", l, seed)
+			case <-time.After(20 * time.Second):
+				t.Fatalf("%s puzzle %d was not generated within 20 seconds", l, seed)
+			}
+		}
+	}
+}
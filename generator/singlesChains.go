@@ -27,11 +27,11 @@ package generator
 // singlesChains removes candidates by two methods. Prior to removing any candidates, chains are created between cells that contain the only two occurances of a digit in a unit (box, row, or column). The chains connect the units together through the doubly occurring digits. Starting at an arbitrary location in the chain, the cells are alternately colored with two different colors. "Twice in a unit": if the same color occurs twice in a single unit, all cells marked with that color anywhere in the puzzle can be removed. "Two colors elsewhere": if a non-chain cell containing the digit can "see" two cells colored with opposite colors, the digit can be removing from the non-chain cell.
 func (g *Grid) singlesChains(verbose uint) (res bool) {
 	// Create a pairs set containing cells where the cells contain the only two occurrances of a digit in the unit. We use a set so that the pairs are unique.
-	var pairMaps [10]map[pair]bool
+	var pairMaps [maxSize + 1]map[pair]bool
 	g.unitPairs(&pairMaps)
 
 	// Color the points in the chains.
-	for d := 1; d <= 9; d++ {
+	for d := 1; d <= g.size(); d++ {
 		pairMap := pairMaps[d]
 
 		for len(pairMap) != 0 {
@@ -82,38 +82,33 @@ func (g *Grid) singlesChains(verbose uint) (res bool) {
 			}
 
 			// Search for "Two colors elsewhere".
-			for r := zero; r < rows; r++ {
-				for c := zero; c < cols; c++ {
-					p := point{r, c}
-
-					if *g.pt(p)&(1<<d) == 0 {
-						continue
-					}
+			for _, p := range g.Layout().points {
+				if *g.pt(p)&(1<<d) == 0 {
+					continue
+				}
 
-					if _, ok := colors[p]; ok { // Skip if part of chain.
-						continue
-					}
+				if _, ok := colors[p]; ok { // Skip if part of chain.
+					continue
+				}
 
-					b := boxOf(r, c)
-					var seesBlue *point
-					for _, blue := range blues {
-						if b == boxOfPoint(blue) || c == blue.c || r == blue.r {
-							pt := blue
-							seesBlue = &pt
-						}
+				var seesBlue *point
+				for _, blue := range blues {
+					if g.sees(p, blue) {
+						pt := blue
+						seesBlue = &pt
 					}
-					var seesRed *point
-					for _, red := range reds {
-						if b == boxOfPoint(red) || c == red.c || r == red.r {
-							pt := red
-							seesRed = &pt
-						}
+				}
+				var seesRed *point
+				for _, red := range reds {
+					if g.sees(p, red) {
+						pt := red
+						seesRed = &pt
 					}
+				}
 
-					if seesBlue != nil && seesRed != nil {
-						if g.pt(p).andNot(1 << d) {
-							g.cellChange(&res, verbose, "singlesChain: in %s, removing %d for two colors elsewhere (%s, %s)\n", p, d, *seesBlue, *seesRed)
-						}
+				if seesBlue != nil && seesRed != nil {
+					if g.pt(p).andNot(1 << d) {
+						g.cellChange(&res, verbose, "singlesChain: in %s, removing %d for two colors elsewhere (%s, %s)\n", p, d, *seesBlue, *seesRed)
 					}
 				}
 			}
@@ -130,7 +125,7 @@ func (g *Grid) twiceInAUnit(colors []point) bool {
 				continue
 			}
 
-			if boxOfPoint(p1) == boxOfPoint(p2) || p1.c == p2.c || p1.r == p2.r {
+			if g.sees(p1, p2) {
 				return true
 			}
 		}
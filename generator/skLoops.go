@@ -24,7 +24,12 @@
 
 package generator
 
-func (g *Grid) skLoops(verbose uint) (res bool) {
+// skLoops removes candidates using SK loops: rings of box-line intersections around four solved cells at the corners of a rectangle, where each link of the ring shares its digits with the next. It works on each standard 9 x 9 grid of the layout.
+func (g *Grid) skLoops(verbose uint) bool {
+	return g.eachGrid(func(sub *Grid) bool { return sub.skLoopsGrid(verbose) })
+}
+
+func (g *Grid) skLoopsGrid(verbose uint) (res bool) {
 	solved := make(map[point]bool)
 	for r := zero; r < rows; r++ {
 		for c := zero; c < cols; c++ {
@@ -133,7 +138,7 @@ func (g *Grid) checkSides(rect [4]point, l, r, t, b point) bool {
 	return true
 }
 
-func (g *Grid) colorDigit(p point, c color, mask cell, d int, colors *[rows][cols][10]color) {
+func (g *Grid) colorDigit(p point, c color, mask cell, d int, colors *[maxRows][maxCols][maxSize + 1]color) {
 	cell := *g.pt(p)
 	if cell&mask&(1<<d) != 0 {
 		colors[p.r][p.c][d] = c
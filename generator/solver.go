@@ -0,0 +1,266 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import "math/rand"
+
+type (
+	// board is the compact form of a grid used by the fast solver: a candidate mask per cell (bits 1 - 9, as in cell) indexed by r*9 + c.
+	board [rows * cols]cell
+
+	// solver holds the state of a brute-force search. It stops when count reaches limit (if limit is positive) or found returns false.
+	solver struct {
+		limit int
+		count int
+		rnd   *rand.Rand
+		found func(b *board) bool
+	}
+)
+
+var (
+	peers     [rows * cols][20]uint8 // peers contains the 20 cells that share a box, column, or row with each cell.
+	unitCells [27][9]uint8           // unitCells contains the cells of the 9 boxes, 9 columns, and 9 rows.
+)
+
+func init() {
+	for i := 0; i < rows*cols; i++ {
+		r, c := i/cols, i%cols
+		unitCells[r/3*3+c/3][r%3*3+c%3] = uint8(i)
+		unitCells[9+c][r] = uint8(i)
+		unitCells[18+r][c] = uint8(i)
+	}
+
+	for i := 0; i < rows*cols; i++ {
+		r, c := i/cols, i%cols
+		n := 0
+		for j := 0; j < rows*cols; j++ {
+			jr, jc := j/cols, j%cols
+			if j != i && (jr == r || jc == c || jr/3 == r/3 && jc/3 == c/3) {
+				peers[i][n] = uint8(j)
+				n++
+			}
+		}
+	}
+}
+
+// newBoard copies the candidates of a grid into a board and propagates its solved cells. It returns false if the grid contains a contradiction.
+func newBoard(g *Grid) (*board, bool) {
+	b := &board{}
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			b[r*cols+c] = g.cells[r][c] & all
+		}
+	}
+
+	for _, c := range b {
+		if c == 0 {
+			return b, false
+		}
+	}
+
+	// Remove each solved digit from its peers. Cells that become solved along the way are propagated by assign, so only the cells that were solved on entry are handled here.
+	var solved [rows * cols]bool
+	for i, c := range b {
+		solved[i] = bitCount[c] == 1
+	}
+	for i := range b {
+		if solved[i] && !b.assign(uint8(i), b[i]) {
+			return b, false
+		}
+	}
+
+	return b, true
+}
+
+// assign places a digit (as a single bit) in a cell and removes it from all peers, recursively assigning any peer that is left with a single candidate. It returns false on a contradiction.
+func (b *board) assign(i uint8, bit cell) bool {
+	b[i] = bit
+	for _, p := range peers[i] {
+		c := b[p]
+		if c&bit == 0 {
+			continue
+		}
+
+		c &^= bit
+		b[p] = c
+		if c == 0 {
+			return false
+		}
+		if bitCount[c] == 1 && !b.assign(p, c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hiddenSingles assigns every digit that can appear in only one cell of a unit. It returns whether anything changed and false for ok on a contradiction.
+func (b *board) hiddenSingles() (changed, ok bool) {
+	for _, u := range unitCells {
+		var once, twice, placed cell
+		for _, i := range u {
+			c := b[i]
+			twice |= once & c
+			once |= c
+			if bitCount[c] == 1 {
+				placed |= c
+			}
+		}
+
+		if once != all { // Some digit has nowhere to go.
+			return changed, false
+		}
+
+		for singles := once &^ twice &^ placed; singles != 0; singles &= singles - 1 {
+			bit := singles & -singles
+
+			found := false
+			for _, i := range u {
+				if b[i]&bit != 0 {
+					if b[i] != bit && !b.assign(i, bit) {
+						return changed, false
+					}
+					found = true
+					break
+				}
+			}
+			if !found { // An earlier assignment in this unit removed the only place for the digit.
+				return changed, false
+			}
+
+			changed = true
+		}
+	}
+
+	return changed, true
+}
+
+// search propagates hidden singles and then branches on the cell with the fewest candidates. It returns true when the solver should stop.
+func (b *board) search(s *solver) bool {
+	for {
+		changed, ok := b.hiddenSingles()
+		if !ok {
+			return false
+		}
+		if !changed {
+			break
+		}
+	}
+
+	best := -1
+	min := 10
+	for i, c := range b {
+		if n := bitCount[c]; n > 1 && n < min {
+			best, min = i, n
+			if n == 2 {
+				break
+			}
+		}
+	}
+
+	if best < 0 { // Every cell is solved.
+		s.count++
+		if s.found != nil && !s.found(b) {
+			return true
+		}
+		return s.limit > 0 && s.count >= s.limit
+	}
+
+	digits := b[best].digits()
+	if s.rnd != nil {
+		s.rnd.Shuffle(len(digits), func(i, j int) { digits[i], digits[j] = digits[j], digits[i] })
+	}
+
+	for _, d := range digits {
+		cp := *b
+		if cp.assign(uint8(best), 1<<d) && cp.search(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// solve runs the solver over the candidates of g.
+func (s *solver) solve(g *Grid) {
+	b, ok := newBoard(g)
+	if ok {
+		b.search(s)
+	}
+}
+
+// grid returns a copy of g with the cells taken from the board.
+func (b *board) grid(g *Grid) *Grid {
+	res := *g
+	for i, c := range b {
+		res.cells[i/cols][i%cols] = c
+	}
+
+	return &res
+}
+
+// CountSolutions returns the number of solutions of the grid, stopping once limit solutions have been found if limit is positive. A limit of 0 counts every solution, which may take a long time for a grid with few givens.
+func (g *Grid) CountSolutions(limit int) int {
+	if g.layout != nil {
+		return g.layoutSolutions(limit, nil, nil)
+	}
+
+	s := solver{limit: limit}
+	s.solve(g)
+	return s.count
+}
+
+// Solutions calls f with each solution of the grid until f returns false or, if limit is positive, limit solutions have been found. It returns the number of solutions passed to f. The solutions keep the givens of the grid.
+func (g *Grid) Solutions(limit int, f func(solution *Grid) bool) int {
+	if g.layout != nil {
+		return g.layoutSolutions(limit, nil, f)
+	}
+
+	s := solver{limit: limit, found: func(b *board) bool {
+		return f(b.grid(g))
+	}}
+	s.solve(g)
+	return s.count
+}
+
+// SolutionsChan streams the solutions of the grid (up to limit if limit is positive) on the returned channel, which is closed after the last one. Closing done stops the search early; done may be nil if the caller reads every solution.
+func (g *Grid) SolutionsChan(limit int, done <-chan struct{}) <-chan *Grid {
+	ch := make(chan *Grid)
+	cp := *g
+
+	go func() {
+		defer close(ch)
+		cp.Solutions(limit, func(solution *Grid) bool {
+			select {
+			case ch <- solution:
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}()
+
+	return ch
+}
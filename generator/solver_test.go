@@ -0,0 +1,169 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var hardestPuzzles = []string{
+	"85...24..72......9..4.........1.7..23.5...9...4...........8..7..17..........36.4.",
+	"..53.....8......2..7..1.5..4....53...1..7...6..32...8..6.5....9..4....3......97..",
+	"12..4......5.69.1...9...5.........7.7...52.9..3......2.9.6...5.4..9..8.1..3...9.4",
+	"1.......2.9.4...5...6...7...5.9.3.......7.......85..4.7.....6...3...9.8...2.....1",
+	".......1.4.........2...........5.4.7..8...3....1.9....3..4..2...5.1........8.6...",
+}
+
+func TestSearchUnique(t *testing.T) {
+	for _, p := range hardestPuzzles {
+		g, err := ParseEncoded(p)
+		assert.NoError(t, err)
+
+		solutions := make([]*Grid, 0, 2)
+		g.Search(&solutions)
+		assert.Len(t, solutions, 1, p)
+		assert.True(t, solutions[0].solved(), p)
+		assert.Equal(t, p, solutions[0].givens())
+	}
+}
+
+func TestSearchMultiple(t *testing.T) {
+	g, err := ParseEncoded(".........9..3.5..1..18.64....81.29..7.......8..67.82....26.95..8..2.3..9..5.1.3..")
+	assert.NoError(t, err)
+
+	solutions := make([]*Grid, 0, 2)
+	g.Search(&solutions)
+	assert.Len(t, solutions, 2)
+	assert.NotEqual(t, solutions[0].Values(), solutions[1].Values())
+}
+
+func TestSearchNone(t *testing.T) {
+	g, err := ParseEncoded("11" + strings.Repeat(".", 79))
+	assert.NoError(t, err)
+
+	solutions := make([]*Grid, 0, 2)
+	g.Search(&solutions)
+	assert.Empty(t, solutions)
+}
+
+func TestSearchRespectsCandidates(t *testing.T) {
+	g, err := ParseEncoded(".........9..3.5..1..18.64....81.29..7.......8..67.82....26.95..8..2.3..9..5.1.3..")
+	assert.NoError(t, err)
+
+	solutions := make([]*Grid, 0, 2)
+	g.Search(&solutions)
+	first := solutions[0]
+
+	// Restricting the cells where the solutions differ to the values of one solution leaves just that solution.
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if first.cells[r][c] != solutions[1].cells[r][c] {
+				g.cells[r][c] = first.cells[r][c]
+			}
+		}
+	}
+	solutions = solutions[:0]
+	g.Search(&solutions)
+	assert.Len(t, solutions, 1)
+	assert.Equal(t, first.Values(), solutions[0].Values())
+}
+
+func BenchmarkSearch(b *testing.B) {
+	grids := make([]*Grid, len(hardestPuzzles))
+	for i, p := range hardestPuzzles {
+		grids[i], _ = ParseEncoded(p)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, g := range grids {
+			solutions := make([]*Grid, 0, 2)
+			g.Search(&solutions)
+		}
+	}
+}
+
+func BenchmarkSearchEmpty(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < b.N; n++ {
+		g := randomize(rnd)
+		solutions := make([]*Grid, 0, 2)
+		g.search(&solutions, rnd)
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		Generate(int64(n))
+	}
+}
+
+func TestCountSolutions(t *testing.T) {
+	g, err := ParseEncoded(".........9..3.5..1..18.64....81.29..7.......8..67.82....26.95..8..2.3..9..5.1.3..")
+	assert.NoError(t, err)
+
+	all := g.CountSolutions(0)
+	assert.True(t, all > 2)
+	assert.Equal(t, 2, g.CountSolutions(2))
+	assert.Equal(t, all, g.CountSolutions(all+1))
+
+	seen := make(map[string]bool)
+	assert.Equal(t, all, g.Solutions(0, func(s *Grid) bool {
+		assert.True(t, s.solved())
+		seen[s.Values()] = true
+		return true
+	}))
+	assert.Len(t, seen, all)
+
+	// Stopping early from the callback.
+	n := 0
+	assert.Equal(t, 2, g.Solutions(0, func(s *Grid) bool {
+		n++
+		return n < 2
+	}))
+}
+
+func TestSolutionsChan(t *testing.T) {
+	g, err := ParseEncoded(".........9..3.5..1..18.64....81.29..7.......8..67.82....26.95..8..2.3..9..5.1.3..")
+	assert.NoError(t, err)
+
+	n := 0
+	for s := range g.SolutionsChan(0, nil) {
+		assert.True(t, s.solved())
+		n++
+	}
+	assert.Equal(t, g.CountSolutions(0), n)
+
+	done := make(chan struct{})
+	ch := g.SolutionsChan(0, done)
+	<-ch
+	close(done)
+	for range ch {
+	}
+}
@@ -0,0 +1,252 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package generator
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+type (
+	// Step is one application of a solving strategy, as recorded by Grid.Steps: the grid before and after it, the descriptions the strategy gave of its changes, the cells of the pattern it found and the links of any chain it followed.
+	Step struct {
+		Strategy      string     // Strategy is the name of the strategy, as reported by Reduce.
+		Level         Level      // Level is the level of the strategy.
+		Notes         []string   // Notes are the messages that the strategy prints when Reduce is verbose, one for each change.
+		Before, After Grid       // Before and After are the grid before and after the step.
+		Pattern       []Position // Pattern holds the cells that the notes mention and that the step did not change.
+		Links         []Link     // Links are the links of the chains that the notes mention.
+	}
+
+	// Link joins a digit in two cells of a chain. A strong link means that one of the cells must hold the digit and a weak link that at most one of them can.
+	Link struct {
+		From   Position `json:"from"`
+		To     Position `json:"to"`
+		Digit  int      `json:"digit"`
+		Strong bool     `json:"strong"`
+	}
+
+	// trace records the steps of a reduction. Its methods do nothing on a nil trace, so that Reduce only pays for it when Steps asks for it.
+	trace struct {
+		steps  []Step
+		before Grid
+		notes  []string
+		args   []interface{}
+	}
+)
+
+// Steps solves a copy of the grid with all of the strategies, as Reduce does, and returns each step that it took, along with whether the grid was solved.
+func (g *Grid) Steps() ([]Step, bool) {
+	cp := *g
+	cp.trace = &trace{}
+	_, solved := cp.Reduce(true, nil, 0)
+
+	return cp.trace.steps, solved
+}
+
+// begin starts recording a step from the current state of g.
+func (t *trace) begin(g *Grid) {
+	if t == nil {
+		return
+	}
+
+	t.before = *g
+	t.before.trace = nil
+	t.notes, t.args = nil, nil
+}
+
+// note records the message and arguments of a change made by the current step.
+func (t *trace) note(format string, a []interface{}) {
+	if t == nil {
+		return
+	}
+
+	t.notes = append(t.notes, strings.TrimSuffix(fmt.Sprintf(format, a...), "\n"))
+	t.args = append(t.args, a...)
+}
+
+// end finishes the current step, which was made by the named strategy, with g as its result.
+func (t *trace) end(strategy string, level Level, g *Grid) {
+	if t == nil {
+		return
+	}
+
+	step := Step{Strategy: strategy, Level: level, Notes: t.notes, Before: t.before, After: *g}
+	step.After.trace = nil
+
+	mentioned := make(map[point]bool)
+	links := make(map[Link]bool)
+	addLink := func(l link, strong bool) {
+		mentioned[l.left], mentioned[l.right] = true, true
+		k := Link{From: l.left.position(), To: l.right.position(), Digit: l.digit, Strong: strong}
+		if !links[k] {
+			links[k] = true
+			step.Links = append(step.Links, k)
+		}
+	}
+
+	for _, a := range t.args {
+		switch a := a.(type) {
+		case point:
+			mentioned[a] = true
+		case []point:
+			for _, p := range a {
+				mentioned[p] = true
+			}
+		case []link: // The links between the cells of an XY-chain are weak: the cells cannot both hold the digit.
+			for _, l := range a {
+				addLink(l, false)
+			}
+		case []unitLink:
+			for _, l := range a {
+				addLink(l.link, l.strong)
+			}
+		}
+	}
+
+	for p := range mentioned {
+		if step.Before.cells[p.r][p.c] == step.After.cells[p.r][p.c] {
+			step.Pattern = append(step.Pattern, p.position())
+		}
+	}
+	sort.Slice(step.Pattern, func(i, j int) bool {
+		a, b := step.Pattern[i], step.Pattern[j]
+		return a.Row < b.Row || a.Row == b.Row && a.Col < b.Col
+	})
+
+	t.steps = append(t.steps, step)
+}
+
+// position converts a point to the exported Position of the same cell.
+func (p point) position() Position {
+	return Position{int(p.r), int(p.c)}
+}
+
+// Changed returns the cells whose candidates the step changed, in row order.
+func (s *Step) Changed() (res []Position) {
+	for _, p := range s.Before.Layout().points {
+		if *s.Before.pt(p) != *s.After.pt(p) {
+			res = append(res, p.position())
+		}
+	}
+
+	return
+}
+
+// Removed returns the candidates that the step removed from each cell: those of Before that are not in After.
+func (s *Step) Removed() (res []Placement) {
+	for _, p := range s.Changed() {
+		removed := s.Before.cells[p.Row][p.Col] &^ s.After.cells[p.Row][p.Col]
+		for d := 1; d <= s.Before.size(); d++ {
+			if removed&(1<<d) != 0 {
+				res = append(res, Placement{p, d})
+			}
+		}
+	}
+
+	return
+}
+
+// SVG returns the grid before the step drawn with opts and annotated with what the step found: the pattern cells are shaded, the changed cells are shaded in another colour, removed candidates are red, digits that the step placed are blue and the links of any chain are drawn as arrows, solid for strong links and dashed for weak ones.
+func (s *Step) SVG(opts SVGOptions) string {
+	var colors [maxRows][maxCols][maxSize + 1]color
+	for _, p := range s.Removed() {
+		colors[p.Row][p.Col][p.Digit] = red
+	}
+	for _, p := range s.Changed() {
+		if after := s.After.cells[p.Row][p.Col]; bitCount[after] == 1 {
+			colors[p.Row][p.Col][after.lowestSetBit()] = blue
+		}
+	}
+
+	return s.Before.svgWith(opts, true, &colors, s)
+}
+
+const walkthrough = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <style>
+        body { font-family: sans-serif; max-width: 60em; margin: auto; }
+        section { break-inside: avoid; margin-bottom: 2em; }
+        ul { font-family: monospace; }
+    </style>
+</head>
+<body>
+    <h1>{{.Title}}</h1>
+    {{.Start}}
+    {{range .Steps}}
+    <section>
+        <h2>Step {{.Number}}: {{.Strategy}} ({{.Level}})</h2>
+        <ul>{{range .Notes}}
+            <li>{{.}}</li>{{end}}
+        </ul>
+        {{.SVG}}
+    </section>
+    {{end}}
+    <p>{{.Result}}</p>
+    {{.End}}
+</body>
+</html>
+`
+
+// WriteWalkthrough writes an HTML document to w that explains how the grid is solved, step by step: the strategy of each step, its notes and its annotated SVG, followed by the final grid.
+func (g *Grid) WriteWalkthrough(w io.Writer, title string, opts SVGOptions) error {
+	steps, solved := g.Steps()
+
+	type page struct {
+		Number   int
+		Strategy string
+		Level    Level
+		Notes    []string
+		SVG      template.HTML
+	}
+	data := struct {
+		Title      string
+		Start, End template.HTML
+		Steps      []page
+		Result     string
+	}{Title: title, Start: template.HTML(g.SVGWith(opts, true, nil))}
+
+	final := *g
+	for i := range steps {
+		s := &steps[i]
+		data.Steps = append(data.Steps, page{i + 1, s.Strategy, s.Level, s.Notes, template.HTML(s.SVG(opts))})
+		final = s.After
+	}
+
+	data.Result = fmt.Sprintf("Solved in %d steps.", len(steps))
+	if !solved {
+		data.Result = fmt.Sprintf("Not solved after %d steps: no strategy applies to the remaining candidates.", len(steps))
+	}
+	data.End = template.HTML(final.SVGWith(opts, true, nil))
+
+	t := template.Must(template.New("walkthrough").Parse(walkthrough))
+	return t.Execute(w, data)
+}
@@ -0,0 +1,129 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSteps(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+	orig := *g
+
+	steps, solved := g.Steps()
+	assert.NotEmpty(t, steps)
+	assert.Equal(t, orig, *g) // Steps works on a copy.
+
+	reduced := orig
+	_, wantSolved := reduced.Reduce(true, nil, 0)
+	assert.Equal(t, wantSolved, solved)
+
+	before := orig
+	for _, s := range steps {
+		assert.Equal(t, before, s.Before)
+		assert.NotEmpty(t, s.Notes)
+		assert.NotEmpty(t, s.Changed())
+		assert.NotEmpty(t, s.Strategy)
+		assert.Nil(t, s.After.trace)
+		before = s.After
+	}
+	assert.Equal(t, reduced, before)
+}
+
+func TestStepChain(t *testing.T) {
+	g := decodeInts([]int{59, 2, 4, 1, 35, 58, 6, 7, 389, 59, 6, 38, 238, 7, 258, 4, 1, 389, 7, 18,
+		138, 9, 6, 4, 58, 2, 358, 2, 4, 6, 5, 9, 1, 3, 8, 7, 1, 3, 5, 4, 8, 7, 2, 9, 6, 8, 7, 9,
+		6, 2, 3, 1, 5, 4, 4, 18, 128, 38, 35, 9, 7, 6, 258, 3, 5, 28, 7, 1, 6, 9, 4, 28, 6, 9,
+		7, 28, 4, 258, 58, 3, 1})
+	g.trace = &trace{}
+	g.trace.begin(g)
+	assert.True(t, g.xCycles(0))
+	g.trace.end("xCycles", Hard, g)
+
+	assert.Len(t, g.trace.steps, 1)
+	s := g.trace.steps[0]
+	assert.Equal(t, []Placement{{Position{2, 2}, 8}, {Position{2, 8}, 8}, {Position{6, 2}, 8}, {Position{6, 8}, 8}}, s.Removed())
+	assert.NotEmpty(t, s.Pattern)
+	assert.NotEmpty(t, s.Links)
+
+	strong, weak := 0, 0
+	for _, l := range s.Links {
+		assert.Equal(t, 8, l.Digit)
+		if l.Strong {
+			strong++
+		} else {
+			weak++
+		}
+	}
+	assert.NotZero(t, strong)
+	assert.NotZero(t, weak)
+
+	svg := s.SVG(SVGOptions{})
+	assert.Contains(t, svg, `<marker id="sudoku-arrow"`)
+	assert.Contains(t, svg, "stroke-dasharray")
+	assert.Contains(t, svg, "fill:#fff59d")
+	assert.Equal(t, 4, strings.Count(svg, "fill:red"))
+}
+
+func TestWriteWalkthrough(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+
+	var b strings.Builder
+	assert.NoError(t, g.WriteWalkthrough(&b, "Walkthrough", SVGOptions{}))
+	steps, _ := g.Steps()
+	assert.Contains(t, b.String(), "<h1>Walkthrough</h1>")
+	assert.Equal(t, len(steps), strings.Count(b.String(), "<h2>Step "))
+	assert.Contains(t, b.String(), "Not solved after") // The puzzle needs more than the strategies.
+}
+
+func BenchmarkSteps(b *testing.B) {
+	g, _ := ParseEncoded(marshalPuzzle)
+	for n := 0; n < b.N; n++ {
+		g.Steps()
+	}
+}
+
+func BenchmarkReduce(b *testing.B) {
+	g, _ := ParseEncoded(marshalPuzzle)
+	for n := 0; n < b.N; n++ {
+		c := *g
+		c.Reduce(true, nil, 0)
+	}
+}
+
+func TestStepChangedLayout(t *testing.T) {
+	l, _ := NewLayout(12)
+	before := NewGrid(l)
+	after := *before
+	after.cells[5][11] &^= 1<<10 | 1<<12
+	s := Step{Before: *before, After: after}
+
+	assert.Equal(t, []Position{{5, 11}}, s.Changed())
+	assert.Equal(t, []Placement{{Position{5, 11}, 10}, {Position{5, 11}, 12}}, s.Removed())
+}
@@ -51,11 +51,11 @@ type (
 	}
 )
 
-func (g *Grid) findBivalueLinks(gr *group, links *[10]map[link]bool) {
+func (g *Grid) findBivalueLinks(gr *group, links *[maxSize + 1]map[link]bool) {
 	for _, ps := range gr.unit {
 		points := g.digitPoints(ps)
 
-		for d := 1; d <= 9; d++ {
+		for d := 1; d <= g.size(); d++ {
 			dp := points[d]
 
 			for p1i, p1 := range dp {
@@ -84,11 +84,11 @@ func (g *Grid) findBivalueLinks(gr *group, links *[10]map[link]bool) {
 	}
 }
 
-func (g *Grid) findStrongLinks(gr *group, strongLinks *[10]map[unitLink]bool) {
+func (g *Grid) findStrongLinks(gr *group, strongLinks *[maxSize + 1]map[unitLink]bool) {
 	for pi, ps := range gr.unit {
 		points := g.digitPoints(ps)
 
-		for d := 1; d <= 9; d++ {
+		for d := 1; d <= g.size(); d++ {
 			p := points[d]
 
 			if len(p) != 2 {
@@ -105,17 +105,17 @@ func (g *Grid) findStrongLinks(gr *group, strongLinks *[10]map[unitLink]bool) {
 	}
 }
 
-func (g *Grid) unitPairs(pairMaps *[10]map[pair]bool) {
-	g.unitPairsGroup(&box, pairMaps)
-	g.unitPairsGroup(&col, pairMaps)
-	g.unitPairsGroup(&row, pairMaps)
+func (g *Grid) unitPairs(pairMaps *[maxSize + 1]map[pair]bool) {
+	for _, gr := range g.Layout().groups {
+		g.unitPairsGroup(gr, pairMaps)
+	}
 }
 
-func (g *Grid) unitPairsGroup(gr *group, pairMaps *[10]map[pair]bool) {
+func (g *Grid) unitPairsGroup(gr *group, pairMaps *[maxSize + 1]map[pair]bool) {
 	for _, ps := range gr.unit {
 		digits := g.digitPoints(ps)
 
-		for d := uint16(1); d <= 9; d++ {
+		for d := 1; d <= g.size(); d++ {
 			points := digits[d]
 			if len(points) != 2 {
 				continue
@@ -152,15 +152,9 @@ func (p pair) reverse() pair {
 	return pair{p.right, p.left}
 }
 
-func coloredNeighbors(d int, curr point, influence *[rows][cols][10]bool) {
-	for _, u := range []*[9]point{&box.unit[boxOfPoint(curr)], &col.unit[curr.c], &row.unit[curr.r]} {
-		for _, p := range u {
-			if p == curr {
-				continue
-			}
-
-			(*influence)[p.r][p.c][d] = true
-		}
+func (g *Grid) coloredNeighbors(d int, curr point, influence *[maxRows][maxCols][maxSize + 1]bool) {
+	for _, p := range g.peers(curr) {
+		(*influence)[p.r][p.c][d] = true
 	}
 }
 
@@ -175,27 +169,20 @@ func flipColor(c color) color {
 	}
 }
 
-func neighbors(curr point) *[9][9]bool {
-	var res [9][9]bool
-	for _, u := range []*[9]point{&box.unit[boxOfPoint(curr)], &col.unit[curr.c], &row.unit[curr.r]} {
-		for _, p := range u {
-			if p == curr {
-				continue
-			}
-
-			res[p.r][p.c] = true
-		}
+// neighbors returns a table of the cells that see curr: those that share a unit or a killer cage with it, or that a pair rule such as anti-king keeps from holding the same digit.
+func (g *Grid) neighbors(curr point) *[maxRows][maxCols]bool {
+	var res [maxRows][maxCols]bool
+	for _, p := range g.peers(curr) {
+		res[p.r][p.c] = true
 	}
 
 	return &res
 }
 
-func pointCol(p point) uint8 {
-	return p.c
-}
-
-func pointRow(p point) uint8 {
-	return p.r
+// peers returns the cells that see curr, as neighbors does.
+func (g *Grid) peers(curr point) []point {
+	l := g.Layout()
+	return l.seenBy[l.index(curr)]
 }
 
 func sortLink(p unitLink) unitLink {
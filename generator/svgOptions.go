@@ -0,0 +1,207 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type (
+	// SVGOptions controls the appearance of the SVG of a grid drawn by Grid.SVGWith. Lengths are in SVG units, which are pixels at a Scale of 1. Zero values select the look of Grid.SVG.
+	SVGOptions struct {
+		Scale         float64    // Scale multiplies the size of the drawing (default 1).
+		Invert        bool       // Invert turns the grid upside down, as for the solutions of a printed booklet.
+		CellSize      int        // CellSize is the width and height of each cell (default 50).
+		Margin        int        // Margin is the space around the grid, which holds the labels if there are any (default 25).
+		Font          string     // Font is the CSS font family of the digits and labels (default sans-serif). Only letters, digits, spaces, commas, periods, hyphens, and underscores are kept, so names with spaces are written without quotes.
+		DigitSize     int        // DigitSize is the font size of solved cells (default half the cell size).
+		CandidateSize int        // CandidateSize is the font size of candidates (default 8 for the default cell size).
+		LabelSize     int        // LabelSize is the font size of the row and column labels (default half the margin).
+		ThinLine      float64    // ThinLine is the width of the lines between cells (default 1).
+		ThickLine     float64    // ThickLine is the width of the lines between boxes and around the grid (default 5).
+		Dark          bool       // Dark selects DarkPalette instead of LightPalette for the colours that Palette leaves empty.
+		Palette       SVGPalette // Palette overrides the colours of the light or dark palette with any fields that are not empty.
+		Labels        bool       // Labels adds the row letters (A - I on a 9 x 9 grid) to the left of the grid and the column numbers above it.
+		Classes       bool       // Classes gives each element a CSS class, defined in a style element, instead of an inline style, so that a page can restyle the grid.
+		ClassPrefix   string     // ClassPrefix starts the name of each class (default "sudoku-"), giving for example sudoku-given.
+
+		style map[string]string // style holds the CSS declarations of each kind of element, computed by defaults.
+	}
+
+	// SVGPalette holds the CSS colours of the parts of a grid.
+	SVGPalette struct {
+		Background string // Background fills the whole drawing.
+		Line       string // Line is the colour of the cell and box lines.
+		Given      string // Given is the colour of the original clues.
+		Digit      string // Digit is the colour of the digits placed by solving.
+		Candidate  string // Candidate is the colour of the candidates.
+		Blue, Red  string // Blue and Red are the colours of candidates coloured blue and red by a strategy.
+		Label      string // Label is the colour of the row and column labels.
+		Pattern    string // Pattern fills the cells of the pattern found by a solving step.
+		Target     string // Target fills the cells changed by a solving step.
+		Link       string // Link is the colour of the arrows of a chain.
+		Shade      string // Shade fills the cells of shaded units, such as the diagonals of X-Sudoku, and the cells related by a pair rule in its key.
+	}
+)
+
+var (
+	// LightPalette is the palette of Grid.SVG: black on white, with green clues.
+	LightPalette = SVGPalette{"white", "black", "green", "black", "black", "blue", "red", "gray", "#fff59d", "#ffcdd2", "#ef6c00", "lightgray"}
+
+	// DarkPalette is a palette for dark backgrounds.
+	DarkPalette = SVGPalette{"#121212", "#e0e0e0", "#81c784", "#e0e0e0", "#bdbdbd", "#64b5f6", "#e57373", "#9e9e9e", "#4e4a1e", "#4e2424", "#ffb74d", "#424242"}
+)
+
+// defaults fills in the zero fields of the options.
+func (o *SVGOptions) defaults() {
+	if o.Scale <= 0 {
+		o.Scale = 1
+	}
+	if o.CellSize <= 0 {
+		o.CellSize = svgCell
+	}
+	if o.Margin <= 0 {
+		o.Margin = svgMargin
+	}
+	if o.Font = fontFamily(o.Font); o.Font == "" {
+		o.Font = "sans-serif"
+	}
+	if o.DigitSize <= 0 {
+		o.DigitSize = o.CellSize * svgDigit / svgCell
+	}
+	if o.CandidateSize <= 0 {
+		o.CandidateSize = o.CellSize * 8 / svgCell
+	}
+	if o.LabelSize <= 0 {
+		o.LabelSize = o.Margin / 2
+	}
+	if o.ThinLine <= 0 {
+		o.ThinLine = 1
+	}
+	if o.ThickLine <= 0 {
+		o.ThickLine = 5
+	}
+	if o.ClassPrefix == "" {
+		o.ClassPrefix = "sudoku-"
+	}
+
+	base := LightPalette
+	if o.Dark {
+		base = DarkPalette
+	}
+	for _, c := range []struct {
+		field *string
+		value string
+	}{
+		{&o.Palette.Background, base.Background},
+		{&o.Palette.Line, base.Line},
+		{&o.Palette.Given, base.Given},
+		{&o.Palette.Digit, base.Digit},
+		{&o.Palette.Candidate, base.Candidate},
+		{&o.Palette.Blue, base.Blue},
+		{&o.Palette.Red, base.Red},
+		{&o.Palette.Label, base.Label},
+		{&o.Palette.Pattern, base.Pattern},
+		{&o.Palette.Target, base.Target},
+		{&o.Palette.Link, base.Link},
+		{&o.Palette.Shade, base.Shade},
+	} {
+		if *c.field == "" {
+			*c.field = c.value
+		}
+	}
+
+	o.style = o.styles()
+}
+
+// fontFamily drops the characters of a font family that could end a CSS declaration, a style attribute, or a style element.
+func fontFamily(s string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" ,.-_", r) {
+			return r
+		}
+		return -1
+	}, s))
+}
+
+// styles returns the CSS declarations for each kind of element of a grid.
+func (o *SVGOptions) styles() map[string]string {
+	text := func(size int, fill string) string {
+		return fmt.Sprintf("font:%dpx %s;text-anchor:middle;fill:%s", size, o.Font, fill)
+	}
+
+	return map[string]string{
+		"background": "fill:" + o.Palette.Background,
+		"thin":       fmt.Sprintf("stroke:%s;stroke-width:%g", o.Palette.Line, o.ThinLine),
+		"thick":      fmt.Sprintf("stroke:%s;stroke-width:%g;stroke-linecap:round", o.Palette.Line, o.ThickLine),
+		"given":      text(o.DigitSize, o.Palette.Given),
+		"digit":      text(o.DigitSize, o.Palette.Digit),
+		"candidate":  text(o.CandidateSize, o.Palette.Candidate),
+		"blue":       text(o.CandidateSize, o.Palette.Blue),
+		"red":        text(o.CandidateSize, o.Palette.Red),
+		"label":      text(o.LabelSize, o.Palette.Label),
+		"pattern":    "fill:" + o.Palette.Pattern,
+		"target":     "fill:" + o.Palette.Target,
+		"strong":     fmt.Sprintf("stroke:%s;stroke-width:%g;fill:none", o.Palette.Link, o.ThinLine*2),
+		"weak":       fmt.Sprintf("stroke:%s;stroke-width:%g;stroke-dasharray:4 3;fill:none", o.Palette.Link, o.ThinLine*2),
+		"arrow":      "fill:" + o.Palette.Link,
+		"shade":      "fill:" + o.Palette.Shade,
+		"cell":       fmt.Sprintf("stroke:%s;stroke-width:%g;fill:none", o.Palette.Line, o.ThinLine),
+		"cage":       fmt.Sprintf("stroke:%s;stroke-width:%g;stroke-dasharray:3,3", o.Palette.Line, o.ThinLine),
+		"sum":        fmt.Sprintf("font:%dpx %s;fill:%s", o.CandidateSize+2, o.Font, o.Palette.Digit),
+		"bar":        fmt.Sprintf("stroke:%s;stroke-width:%g", o.Palette.Line, o.ThickLine*3/5),
+		"mark":       "fill:" + o.Palette.Line,
+		"key":        fmt.Sprintf("font:%dpx %s;fill:%s", o.DigitSize*3/5, o.Font, o.Palette.Digit),
+	}
+}
+
+// attr returns the attribute that styles an element of the given kind: a class if Classes is set, or else an inline style.
+func (o *SVGOptions) attr(kind string) string {
+	if o.Classes {
+		return fmt.Sprintf(`class="%s%s"`, o.ClassPrefix, kind)
+	}
+
+	return o.style[kind]
+}
+
+// css returns the style sheet that defines the classes used when Classes is set.
+func (o *SVGOptions) css() string {
+	styles := o.style
+	kinds := make([]string, 0, len(styles))
+	for k := range styles {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+
+	var b strings.Builder
+	for _, k := range kinds {
+		fmt.Fprintf(&b, ".%s%s { %s }\n", o.ClassPrefix, k, styles[k])
+	}
+
+	return b.String()
+}
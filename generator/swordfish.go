@@ -26,7 +26,7 @@ package generator
 
 // swordfish finds and removes candidates. A swordfish is a 3 by 3 nine-cell pattern, where is in each column (or row), a candidate is only found in three different rows (or columns). The candidate can be removed from all other columns (or row) that line up with the three rows.
 func (g *Grid) swordfish(verbose uint) bool {
-	return g.swordfishGroup(&col, verbose) || g.swordfishGroup(&row, verbose)
+	return g.eachLine(func(major, _ *group) bool { return g.swordfishGroup(major, verbose) })
 }
 
 func (g *Grid) swordfishGroup(gr *group, verbose uint) (res bool) {
@@ -47,7 +47,7 @@ func (g *Grid) swordfishGroup(gr *group, verbose uint) (res bool) {
 
 				digits3 := g.digitPlaces(p3s)
 
-				for d := 1; d <= 9; d++ {
+				for d := 1; d <= g.size(); d++ {
 					d1 := digits1[d]
 					d2 := digits2[d]
 					d3 := digits3[d]
@@ -0,0 +1,196 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Transform is one of the 3,359,232 × 9! transformations that preserve the validity of a sudoku: an optional transposition followed by a permutation of the rows and of the columns that keeps bands and stacks together, and a relabelling of the digits. Cell (r, c) of the result is taken from cell (Rows[r], Cols[c]) of the (possibly transposed) grid, and digit d becomes Digits[d]; Digits[0] is always 0.
+type Transform struct {
+	Transpose  bool
+	Rows, Cols [cols]int
+	Digits     [10]int
+}
+
+// IdentityTransform returns the transformation that leaves every grid unchanged.
+func IdentityTransform() (t Transform) {
+	for i := 0; i < cols; i++ {
+		t.Rows[i], t.Cols[i] = i, i
+	}
+	for d := range t.Digits {
+		t.Digits[d] = d
+	}
+
+	return
+}
+
+// RandomTransform returns a transformation chosen uniformly at random using rnd, or the shared source if rnd is nil.
+func RandomTransform(rnd *rand.Rand) Transform {
+	t := IdentityTransform()
+	if rnd == nil {
+		t.Transpose = rand.Intn(2) == 1
+	} else {
+		t.Transpose = rnd.Intn(2) == 1
+	}
+
+	for _, lines := range []*[cols]int{&t.Rows, &t.Cols} {
+		groups := [3]int{0, 1, 2}
+		shuffle(rnd, 3, func(i, j int) { groups[i], groups[j] = groups[j], groups[i] })
+		for i, g := range groups {
+			within := [3]int{g * 3, g*3 + 1, g*3 + 2}
+			shuffle(rnd, 3, func(i, j int) { within[i], within[j] = within[j], within[i] })
+			copy(lines[i*3:], within[:])
+		}
+	}
+
+	shuffle(rnd, 9, func(i, j int) { t.Digits[i+1], t.Digits[j+1] = t.Digits[j+1], t.Digits[i+1] })
+
+	return t
+}
+
+// Valid returns an error if t does not keep bands and stacks together or does not relabel the digits one to one.
+func (t Transform) Valid() error {
+	for _, lines := range []struct {
+		name, group string
+		ls          [cols]int
+	}{{"row", "band", t.Rows}, {"column", "stack", t.Cols}} {
+		var seen [cols]bool
+		for i, l := range lines.ls {
+			if l < 0 || l >= cols || seen[l] {
+				return fmt.Errorf("%ss must be a permutation of 0 to 8", lines.name)
+			}
+			seen[l] = true
+			if first := lines.ls[i/3*3]; l/3 != first/3 {
+				return fmt.Errorf("%s %d is not in the same %s as %s %d", lines.name, l, lines.group, lines.name, first)
+			}
+		}
+	}
+
+	var seen [10]bool
+	for d, l := range t.Digits {
+		if (d == 0) != (l == 0) || l < 0 || l > 9 || seen[l] {
+			return fmt.Errorf("digits must be a permutation of 1 to 9")
+		}
+		seen[l] = true
+	}
+
+	return nil
+}
+
+// Apply returns a copy of g with its cells, candidates and givens transformed by t. It returns ErrNotStandard if g has a layout.
+func (t Transform) Apply(g *Grid) (*Grid, error) {
+	if !g.Standard() {
+		return nil, ErrNotStandard
+	}
+
+	var res Grid
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			sr, sc := t.Rows[r], t.Cols[c]
+			if t.Transpose {
+				sr, sc = sc, sr
+			}
+
+			var cl cell
+			for d := 1; d <= 9; d++ {
+				if g.cells[sr][sc]&(1<<d) != 0 {
+					cl |= 1 << t.Digits[d]
+				}
+			}
+			res.cells[r][c] = cl
+			res.orig[r][c] = g.orig[sr][sc]
+		}
+	}
+
+	return &res, nil
+}
+
+// ApplyGame returns a copy of g whose puzzle and solution are transformed by t. The level, clues and strategies are unchanged; the seed is 0 because the result cannot be regenerated from one. It returns ErrNotStandard if the puzzle has a layout.
+func (t Transform) ApplyGame(g *Game) (*Game, error) {
+	res := *g
+	res.Strategies = append([]string(nil), g.Strategies...)
+	res.Seed = 0
+
+	var err error
+	if res.Puzzle, err = t.Apply(g.Puzzle); err != nil {
+		return nil, err
+	}
+	if g.Solution != nil {
+		if res.Solution, err = t.Apply(g.Solution); err != nil {
+			return nil, err
+		}
+	}
+
+	return &res, nil
+}
+
+// Then returns the transformation that applies t and then u.
+func (t Transform) Then(u Transform) (res Transform) {
+	res.Transpose = t.Transpose != u.Transpose
+	rs, cs := t.Rows, t.Cols
+	if u.Transpose {
+		rs, cs = cs, rs
+	}
+	for i := 0; i < cols; i++ {
+		res.Rows[i], res.Cols[i] = rs[u.Rows[i]], cs[u.Cols[i]]
+	}
+	for d := range res.Digits {
+		res.Digits[d] = u.Digits[t.Digits[d]]
+	}
+
+	return
+}
+
+// Inverse returns the transformation that undoes t.
+func (t Transform) Inverse() (res Transform) {
+	res.Transpose = t.Transpose
+	for i := 0; i < cols; i++ {
+		res.Rows[t.Rows[i]], res.Cols[t.Cols[i]] = i, i
+	}
+	if t.Transpose {
+		res.Rows, res.Cols = res.Cols, res.Rows
+	}
+	for d, l := range t.Digits {
+		res.Digits[l] = d
+	}
+
+	return
+}
+
+// Isomorphic reports whether the givens of b are a transformation of the givens of a and, if so, returns a transformation that turns the givens of a into those of b. Transformations apply only to standard grids, so it returns false if either grid has a layout.
+func Isomorphic(a, b *Grid) (Transform, bool) {
+	if !a.Standard() || !b.Standard() {
+		return Transform{}, false
+	}
+
+	fa, ta := canonicalForm(a.givenDigits())
+	fb, tb := canonicalForm(b.givenDigits())
+	if fa != fb {
+		return Transform{}, false
+	}
+
+	return ta.Then(tb.Inverse()), true
+}
@@ -0,0 +1,105 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package generator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// apply returns g transformed by tr.
+func apply(t *testing.T, tr Transform, g *Grid) *Grid {
+	res, err := tr.Apply(g)
+	assert.NoError(t, err)
+	return res
+}
+
+func TestTransform(t *testing.T) {
+	var game *Game
+	for seed := int64(1); game == nil; seed++ {
+		game = Generate(seed)
+	}
+	id := IdentityTransform()
+	assert.NoError(t, id.Valid())
+	assert.Equal(t, game.Puzzle.Encode(), apply(t, id, game.Puzzle).Encode())
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		tr := RandomTransform(rnd)
+		assert.NoError(t, tr.Valid())
+
+		g, err := tr.ApplyGame(game)
+		assert.NoError(t, err)
+		assert.True(t, g.Solution.Valid())
+		assert.True(t, g.Solution.solved())
+		assert.Equal(t, game.Clues, g.Puzzle.Clues())
+
+		var solutions []*Grid
+		p := *g.Puzzle
+		p.Search(&solutions)
+		assert.Len(t, solutions, 1)
+		assert.Equal(t, g.Solution.Encode(), solutions[0].Encode())
+
+		assert.Equal(t, game.Puzzle.Encode(), apply(t, tr.Inverse(), g.Puzzle).Encode())
+		assert.Equal(t, id, tr.Then(tr.Inverse()))
+		u := RandomTransform(rnd)
+		assert.Equal(t, apply(t, u, apply(t, tr, game.Puzzle)).Encode(), apply(t, tr.Then(u), game.Puzzle).Encode())
+	}
+
+	bad := IdentityTransform()
+	bad.Rows[0], bad.Rows[3] = 3, 0
+	assert.EqualError(t, bad.Valid(), "row 1 is not in the same band as row 3")
+	bad = IdentityTransform()
+	bad.Digits[1] = 2
+	assert.Error(t, bad.Valid())
+}
+
+func TestIsomorphic(t *testing.T) {
+	g, err := ParseEncoded(marshalPuzzle)
+	assert.NoError(t, err)
+	h, err := ParseEncoded(transformedPuzzle())
+	assert.NoError(t, err)
+
+	tr, err := g.CanonicalTransform()
+	assert.NoError(t, err)
+	assert.NoError(t, tr.Valid())
+	assert.Equal(t, canonical(t, g), apply(t, tr, g).Encode())
+
+	tr, ok := Isomorphic(g, h)
+	assert.True(t, ok)
+	assert.NoError(t, tr.Valid())
+	assert.Equal(t, h.Encode(), apply(t, tr, g).Encode())
+
+	r := apply(t, RandomTransform(rand.New(rand.NewSource(2))), h)
+	tr, ok = Isomorphic(r, g)
+	assert.True(t, ok)
+	assert.Equal(t, g.Encode(), apply(t, tr, r).Encode())
+
+	h.cells[0][0] = 1 << 1
+	h.orig[0][0] = true
+	_, ok = Isomorphic(g, h)
+	assert.False(t, ok)
+}
@@ -0,0 +1,285 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"errors"
+	"math/rand"
+)
+
+const (
+	unavoidableDigits    = 4       // unavoidableDigits is the largest number of digits blanked together when looking for unavoidable sets.
+	unavoidableSolutions = 1 << 12 // unavoidableSolutions limits the number of other solutions enumerated for each combination of blanked digits.
+	digSetSize           = 12      // digSetSize is the largest unavoidable set used to speed up digging.
+	digSetDigits         = 2       // digSetDigits is the largest number of digits blanked to find the sets used when digging; more digits find more sets but cost more than they save.
+)
+
+// UnavoidableSets returns the minimal unavoidable sets of a solved grid that contain at most maxSize cells, smallest first. An unavoidable set is a set of cells whose digits can be rearranged to give another valid solution, so every puzzle with this solution must have a given in each set. The sets are found by blanking every combination of two to four digits and enumerating the other solutions, so sets that need more than four digits are not found.
+func (g *Grid) UnavoidableSets(maxSize int) ([][]Position, error) {
+	if !g.Standard() {
+		return nil, ErrNotStandard
+	}
+	if !g.solved() {
+		return nil, errors.New("unavoidable sets require a solved grid")
+	}
+
+	var res [][]Position
+	for _, s := range g.unavoidableSets(maxSize, unavoidableDigits) {
+		var ps []Position
+		for _, p := range s.points() {
+			ps = append(ps, position(p))
+		}
+		res = append(res, ps)
+	}
+
+	return res, nil
+}
+
+// Minimal returns true if the grid has a single solution and removing any one of its givens would allow more than one. It returns ErrNotStandard if the grid has a layout.
+func (g *Grid) Minimal() (bool, error) {
+	if !g.Standard() {
+		return false, ErrNotStandard
+	}
+
+	var solution *Grid
+	if g.Solutions(2, func(s *Grid) bool {
+		solution = s
+		return true
+	}) != 1 {
+		return false, nil
+	}
+
+	givens := g.givenSet()
+	sets := solution.unavoidableSets(digSetSize, digSetDigits)
+
+	for _, p := range givens.points() {
+		if lastHit(sets, givens, p) { // The given is needed to hit one of the sets.
+			continue
+		}
+
+		h := *g
+		*h.pt(p) = all
+		h.orig[p.r][p.c] = false
+		if h.CountSolutions(2) == 1 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Sparsest digs puzzles from a solved grid in attempts different random orders, taken from seed, and returns the one with the fewest givens. It stops early if a puzzle has as few givens as the number of disjoint unavoidable sets, since no puzzle for this solution can have fewer.
+func (g *Grid) Sparsest(attempts int, seed int64) (*Grid, error) {
+	if !g.Standard() {
+		return nil, ErrNotStandard
+	}
+	if !g.solved() {
+		return nil, errors.New("digging a puzzle requires a solved grid")
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	sets := g.unavoidableSets(digSetSize, unavoidableDigits)
+	bound := disjointSets(sets)
+
+	var best *Grid
+	for a := 0; a < attempts; a++ {
+		puzzle := *g
+		points := puzzle.allPoints()
+		rnd.Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] })
+		puzzle.dig(points, sets)
+		puzzle.markGivens()
+
+		if best == nil || puzzle.Clues() < best.Clues() {
+			best = &puzzle
+			if int(best.Clues()) <= bound {
+				break
+			}
+		}
+	}
+
+	return best, nil
+}
+
+// unavoidableSets finds the minimal unavoidable sets with at most maxSize cells by blanking every combination of two to maxDigits digits in a solved grid and collecting the cells that differ in each other solution.
+func (g *Grid) unavoidableSets(maxSize, maxDigits int) []uint128 {
+	var digitCells [10]uint128
+	for r := zero; r < rows; r++ {
+		for c := zero; c < cols; c++ {
+			digitCells[g.cells[r][c].lowestSetBit()].set(point{r, c})
+		}
+	}
+
+	var sets []differenceSet
+	for m := 1; m < 1<<9; m++ {
+		digits := cell(m << 1)
+		if n := bitCount[digits]; n < 2 || n > maxDigits {
+			continue
+		} else if n == 2 {
+			ds := digits.digits()
+			for _, u := range g.digitPairSets(ds[0], ds[1]) {
+				if u.count() <= maxSize {
+					sets = append(sets, differenceSet{cells: u})
+				}
+			}
+			continue
+		}
+
+		// The blanked cells can only hold the blanked digits, since every other digit is already placed in each row.
+		h := *g
+		for _, d := range digits.digits() {
+			digitCells[d].process(func(r, c uint8) {
+				h.cells[r][c] = digits
+			})
+		}
+
+		h.Solutions(unavoidableSolutions, func(s *Grid) bool {
+			if d := g.differences(s); !d.empty() && d.count() <= maxSize {
+				sets = append(sets, differenceSet{cells: d})
+			}
+			return true
+		})
+	}
+
+	min := minimalSets(sets)
+	res := make([]uint128, len(min))
+	for i, s := range min {
+		res[i] = s.cells
+	}
+
+	return res
+}
+
+// digitPairSets returns the unavoidable sets of a solved grid that exchange two digits. Exchanging the digits in some of their cells gives another solution exactly when every box, column, and row has both or neither of its two cells exchanged, so the minimal sets are the connected components of the graph that joins the two cells in each unit.
+func (g *Grid) digitPairSets(a, b int) []uint128 {
+	var parent [rows * cols]uint8
+	for i := range parent {
+		parent[i] = uint8(i)
+	}
+
+	var find func(i uint8) uint8
+	find = func(i uint8) uint8 {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for _, u := range unitCells {
+		var ia, ib uint8
+		for _, i := range u {
+			switch g.cells[i/cols][i%cols] {
+			case 1 << a:
+				ia = i
+			case 1 << b:
+				ib = i
+			}
+		}
+		parent[find(ia)] = find(ib)
+	}
+
+	var components [rows * cols]uint128
+	for i := range parent {
+		if d := g.cells[i/cols][i%cols]; d == 1<<a || d == 1<<b {
+			components[find(uint8(i))].set(point{uint8(i / cols), uint8(i % cols)})
+		}
+	}
+
+	var res []uint128
+	for _, c := range components {
+		if !c.empty() {
+			res = append(res, c)
+		}
+	}
+
+	return res
+}
+
+// dig removes givens from a solved grid in the order of points, putting each one back if removing it allows a second solution. A given that is the last one left in an unavoidable set must stay, so it is kept without a search.
+func (g *Grid) dig(points []pointCell, sets []uint128) {
+	var givens uint128
+	for i := range points {
+		givens.set(points[i].point)
+	}
+
+	for _, curr := range points {
+		if lastHit(sets, givens, curr.point) {
+			continue
+		}
+
+		*g.pt(curr.point) = all      // Clear the cell.
+		if g.CountSolutions(2) > 1 { // No longer unique.
+			*g.pt(curr.point) = curr.cell // Put the value back.
+			continue
+		}
+
+		givens.unset(curr.point)
+	}
+}
+
+// givenSet returns the cells that are givens.
+func (g *Grid) givenSet() (res uint128) {
+	for r := zero; r < rows; r++ {
+		for c := zero; c < cols; c++ {
+			if g.orig[r][c] {
+				res.set(point{r, c})
+			}
+		}
+	}
+
+	return
+}
+
+// markGivens makes every solved cell a given and clears the others.
+func (g *Grid) markGivens() {
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			g.orig[r][c] = bitCount[g.cells[r][c]] == 1
+		}
+	}
+}
+
+// disjointSets greedily counts the sets (assumed smallest first) that share no cells, which is a lower bound on the number of givens needed to hit every set.
+func disjointSets(sets []uint128) (res int) {
+	var used uint128
+	for _, s := range sets {
+		if s.and(used).empty() {
+			used = used.or(s)
+			res++
+		}
+	}
+
+	return
+}
+
+// lastHit returns true if p is the only given in one of the sets.
+func lastHit(sets []uint128, givens uint128, p point) bool {
+	for _, s := range sets {
+		if s.has(p) && s.and(givens).count() == 1 {
+			return true
+		}
+	}
+
+	return false
+}
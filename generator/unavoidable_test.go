@@ -0,0 +1,140 @@
+/*
+ * MIT LICENSE
+ *
+ * Copyright © 2020, G.Ralph Kuntz, MD.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnavoidableSets(t *testing.T) {
+	g, err := ParseEncoded(hardestPuzzles[0])
+	assert.NoError(t, err)
+	var solution *Grid
+	g.Solutions(1, func(s *Grid) bool {
+		solution = s
+		return true
+	})
+
+	sets, err := solution.UnavoidableSets(12)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, sets)
+
+	for i, s := range sets {
+		assert.True(t, len(s) >= 4 && len(s) <= 12)
+		if i > 0 {
+			assert.True(t, len(sets[i-1]) <= len(s))
+		}
+
+		// Blanking the cells of an unavoidable set allows more than one solution.
+		h := *solution
+		for _, p := range s {
+			h.cells[p.Row][p.Col] = all
+		}
+		assert.True(t, h.CountSolutions(2) > 1)
+	}
+
+	// The puzzle must have a given in every set.
+	for _, s := range sets {
+		hit := false
+		for _, p := range s {
+			hit = hit || g.orig[p.Row][p.Col]
+		}
+		assert.True(t, hit)
+	}
+
+	_, err = g.UnavoidableSets(12)
+	assert.Error(t, err)
+}
+
+func TestDigitPairSets(t *testing.T) {
+	g := Generate(1).Solution
+	for a := 1; a <= 9; a++ {
+		for b := a + 1; b <= 9; b++ {
+			digits := cell(1<<a | 1<<b)
+			h := *g
+			for r := 0; r < rows; r++ {
+				for c := 0; c < cols; c++ {
+					if g.cells[r][c]&digits != 0 {
+						h.cells[r][c] = digits
+					}
+				}
+			}
+
+			// The minimal sets among all the solutions that exchange the two digits are the components.
+			var sets []differenceSet
+			h.Solutions(0, func(s *Grid) bool {
+				if d := g.differences(s); !d.empty() {
+					sets = append(sets, differenceSet{cells: d})
+				}
+				return true
+			})
+			assert.Equal(t, len(minimalSets(sets)), len(g.digitPairSets(a, b)))
+		}
+	}
+}
+
+func TestMinimal(t *testing.T) {
+	game := Generate(1)
+	minimal, err := game.Puzzle.Minimal()
+	assert.NoError(t, err)
+	assert.True(t, minimal)
+
+	g := *game.Puzzle
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if !g.orig[r][c] {
+				g.cells[r][c] = game.Solution.cells[r][c]
+				g.orig[r][c] = true
+				minimal, err := g.Minimal()
+				assert.NoError(t, err)
+				assert.False(t, minimal)
+				return
+			}
+		}
+	}
+}
+
+func TestSparsest(t *testing.T) {
+	game := Generate(2)
+	p, err := game.Solution.Sparsest(5, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, p.CountSolutions(0))
+	minimal, err := p.Minimal()
+	assert.NoError(t, err)
+	assert.True(t, minimal)
+	p.Solutions(1, func(s *Grid) bool {
+		assert.Equal(t, game.Solution.Values(), s.Values())
+		return true
+	})
+}
+
+func BenchmarkUnavoidableSets(b *testing.B) {
+	g := Generate(1).Solution
+	for n := 0; n < b.N; n++ {
+		g.UnavoidableSets(12)
+	}
+}
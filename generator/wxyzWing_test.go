@@ -30,6 +30,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// TestWXYZWing removes the 9 from (3, 1) and the 1 from (7, 2). The earlier implementation looked up the influence of the second wing at (r, r) instead of (r, c), so it removed only the 9; TestWXYZWingColumn covers the 1 on its own.
 func TestWXYZWing(t *testing.T) {
 	g := decodeInts([]int{1689, 169, 189, 1589, 2, 4, 7, 3, 158, 5, 4, 189, 3, 7, 89, 2, 6, 18, 2, 3,
 		7, 1568, 15, 568, 159, 189, 4, 7, 12569, 1259, 59, 3, 259, 8, 4, 156, 69, 2569, 3, 4, 8, 1,
@@ -39,5 +40,19 @@ func TestWXYZWing(t *testing.T) {
 	assert.Equal(t, []int{1689, 169, 189, 1589, 2, 4, 7, 3, 158, 5, 4, 189, 3, 7, 89, 2, 6, 18, 2,
 		3, 7, 1568, 15, 568, 159, 189, 4, 7, 1256, 1259, 59, 3, 259, 8, 4, 156, 69, 2569, 3, 4, 8,
 		1, 59, 279, 567, 19, 8, 4, 579, 6, 2579, 159, 12, 3, 3, 12, 128, 1678, 14, 678, 46, 5, 9,
-		148, 7, 158, 568, 9, 3, 46, 18, 2, 1489, 159, 6, 2, 145, 58, 3, 178, 178}, g.encodeInts())
+		148, 7, 58, 568, 9, 3, 46, 18, 2, 1489, 159, 6, 2, 145, 58, 3, 178, 178}, g.encodeInts())
+}
+
+// TestWXYZWingColumn checks a group whose pivot (6, 2) and wings (0, 2) and (1, 2) share a column, with the disjoint wing at (6, 1). The unrestricted 1 is removed from (7, 2), which sees all four cells; the only solution has 5 there.
+func TestWXYZWingColumn(t *testing.T) {
+	g := decodeInts([]int{1689, 169, 189, 1589, 2, 4, 7, 3, 158, 5, 4, 189, 3, 7, 89, 2, 6, 18, 2, 3,
+		7, 1568, 15, 568, 159, 189, 4, 7, 1256, 1259, 59, 3, 259, 8, 4, 156, 69, 2569, 3, 4, 8, 1,
+		59, 279, 567, 19, 8, 4, 579, 6, 2579, 159, 12, 3, 3, 12, 128, 1678, 14, 678, 46, 5, 9, 148,
+		7, 158, 568, 9, 3, 46, 18, 2, 1489, 159, 6, 2, 145, 58, 3, 178, 178})
+	assert.True(t, g.wxyzWing(0))
+	assert.Equal(t, []int{1689, 169, 189, 1589, 2, 4, 7, 3, 158, 5, 4, 189, 3, 7, 89, 2, 6, 18, 2,
+		3, 7, 1568, 15, 568, 159, 189, 4, 7, 1256, 1259, 59, 3, 259, 8, 4, 156, 69, 2569, 3, 4, 8,
+		1, 59, 279, 567, 19, 8, 4, 579, 6, 2579, 159, 12, 3, 3, 12, 128, 1678, 14, 678, 46, 5, 9,
+		148, 7, 58, 568, 9, 3, 46, 18, 2, 1489, 159, 6, 2, 145, 58, 3, 178, 178}, g.encodeInts())
+	assert.False(t, g.wxyzWing(0))
 }
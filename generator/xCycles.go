@@ -36,32 +36,34 @@ const (
 	weakLoop
 )
 
+var xCyclesBudget = 1 << 14 // xCyclesBudget is the maximum number of chains that findCycle extends before settling for the longest loop found so far. The links of multi-grid layouts form too many chains to search them all.
+
 func (g *Grid) xCycles(verbose uint) (res bool) {
 	// Find all strong links. A pair of points form a strong link if they contain the only two instances of a digit within a unit (box, column, or row).
-	var strongLinks [10]map[unitLink]bool
-	g.findStrongLinks(&box, &strongLinks)
-	g.findStrongLinks(&col, &strongLinks)
-	g.findStrongLinks(&row, &strongLinks)
+	var strongLinks [maxSize + 1]map[unitLink]bool
+	for _, gr := range g.Layout().groups {
+		g.findStrongLinks(gr, &strongLinks)
+	}
 
 	// Find all weak links. A pair of points form a weak link if they contain the two instances of a digit within a unit (box, column, or row). There can be other instances of the digit in the same unit.
-	var weakLinks [10]map[unitLink]bool
-	g.findXCycleWeakLinks(&box, &weakLinks)
-	g.findXCycleWeakLinks(&col, &weakLinks)
-	g.findXCycleWeakLinks(&row, &weakLinks)
+	var weakLinks [maxSize + 1]map[unitLink]bool
+	for _, gr := range g.Layout().groups {
+		g.findXCycleWeakLinks(gr, &weakLinks)
+	}
 
-	for d := 1; d <= 9; d++ { // Process nice chains.
+	for d := 1; d <= g.size(); d++ { // Process nice chains.
 		niceChain := findCycle(d, niceLoop, strongLinks[d], weakLinks[d])
 
-		var overlap [rows][cols]bool
+		var overlap [maxRows][maxCols]bool
 		for _, c := range niceChain {
 			if c.strong {
 				continue
 			} // Only consider weak links.
 
-			nl := neighbors(c.left)
-			nr := neighbors(c.right)
-			for r := zero; r < rows; r++ {
-				for c := zero; c < cols; c++ {
+			nl := g.neighbors(c.left)
+			nr := g.neighbors(c.right)
+			for r := zero; r < g.height(); r++ {
+				for c := zero; c < g.width(); c++ {
 					overlap[r][c] = nl[r][c] && nr[r][c]
 				}
 			}
@@ -69,11 +71,11 @@ func (g *Grid) xCycles(verbose uint) (res bool) {
 			overlap[c.right.r][c.right.c] = false
 
 			// In each cell that is seen by both ends of a weak chain link, the digit can be removed.
-			for r := zero; r < rows; r++ {
-				for c := zero; c < cols; c++ {
+			for r := zero; r < g.height(); r++ {
+				for c := zero; c < g.width(); c++ {
 					if overlap[r][c] {
 						if g.pt(point{r, c}).andNot(1 << d) {
-							g.cellChange(&res, verbose, "xCycles: nice chain removes %d from %s\n", d, point{r, c})
+							g.cellChange(&res, verbose, "xCycles: nice chain removes %d from %s (chain: %v)\n", d, point{r, c}, niceChain)
 						}
 					}
 				}
@@ -85,7 +87,7 @@ func (g *Grid) xCycles(verbose uint) (res bool) {
 		return
 	}
 
-	for d := 1; d <= 9; d++ { // Process strong chains.
+	for d := 1; d <= g.size(); d++ { // Process strong chains.
 		strongChain := findCycle(d, strongLoop, strongLinks[d], weakLinks[d])
 
 		// Find the strong discontinuity (two strong links in a row) and fix the digit at the intersection to the current digit.
@@ -95,13 +97,13 @@ func (g *Grid) xCycles(verbose uint) (res bool) {
 			last := strongChain[length-1]
 			if first.strong && last.strong { // If the first and last links are strong, the discontinuity is the last point in the chain (last.right).
 				if g.pt(last.right).setTo(1 << d) {
-					g.cellChange(&res, verbose, "xCycles: strong chain sets %s to %d\n", last.right, d)
+					g.cellChange(&res, verbose, "xCycles: strong chain sets %s to %d (chain: %v)\n", last.right, d, strongChain)
 				}
 			} else { // Search the chain for the discontinuity.
 				for i := 0; i < length-1; i++ {
 					if strongChain[i].strong && strongChain[i+1].strong {
 						if g.pt(strongChain[i].right).setTo(1 << d) {
-							g.cellChange(&res, verbose, "xCycles: strong chain sets %s to %d\n", strongChain[i].right, d)
+							g.cellChange(&res, verbose, "xCycles: strong chain sets %s to %d (chain: %v)\n", strongChain[i].right, d, strongChain)
 						}
 						break // Once we find the discontinuity, we can stop looking because there can be only one ("Highlander").
 					}
@@ -114,7 +116,7 @@ func (g *Grid) xCycles(verbose uint) (res bool) {
 		return
 	}
 
-	for d := 1; d <= 9; d++ { // Process weak chains.
+	for d := 1; d <= g.size(); d++ { // Process weak chains.
 		weakChain := findCycle(d, weakLoop, strongLinks[d], weakLinks[d])
 
 		// Find the weak discontinuity (two weak links in a row) and remove the current digit at the intersection as a candidate.
@@ -124,13 +126,13 @@ func (g *Grid) xCycles(verbose uint) (res bool) {
 			last := weakChain[length-1]
 			if !first.strong && !last.strong { // If the first and last links are weak, the discontinuity is the last point in the chain (last.right).
 				if g.pt(last.right).andNot(1 << d) {
-					g.cellChange(&res, verbose, "xCycles: weak chain removes %d from %s\n", d, last.right)
+					g.cellChange(&res, verbose, "xCycles: weak chain removes %d from %s (chain: %v)\n", d, last.right, weakChain)
 				}
 			} else { // Search the chain for the discontinuity.
 				for i := 0; i < length-1; i++ {
 					if !weakChain[i].strong && !weakChain[i+1].strong {
 						if g.pt(weakChain[i].right).andNot(1 << d) {
-							g.cellChange(&res, verbose, "xCycles: weak chain removes %d from %s\n", d, weakChain[i].right)
+							g.cellChange(&res, verbose, "xCycles: weak chain removes %d from %s (chain: %v)\n", d, weakChain[i].right, weakChain)
 						}
 						break // Once we find the discontinuity, we can stop looking because there can be only one ("Highlander").
 					}
@@ -184,11 +186,11 @@ func (g *Grid) checkUnit(d int, p1, p2 point) bool {
 	return false
 }
 
-func (g *Grid) findXCycleWeakLinks(gr *group, weakLinks *[10]map[unitLink]bool) {
+func (g *Grid) findXCycleWeakLinks(gr *group, weakLinks *[maxSize + 1]map[unitLink]bool) {
 	for pi, ps := range gr.unit {
 		points := g.digitPoints(ps)
 
-		for d := 1; d <= 9; d++ {
+		for d := 1; d <= g.size(); d++ {
 			p := points[d]
 
 			if len(p) < 3 {
@@ -283,11 +285,15 @@ func chainValid(checkComplete bool, kind loopKind, chain []unitLink) bool {
 }
 
 func findCycle(digit int, kind loopKind, strongLinks, weakLinks map[unitLink]bool) (res []unitLink) {
+	strongFrom := linksFrom(strongLinks)
+	weakFrom := linksFrom(weakLinks)
+
 	// Try each strong link as the start of the chain and keep the longest chain we can form.
+	nodes := 0
 	for s := range strongLinks {
 		chain := []unitLink{s}
 		best := []unitLink{}
-		findCycleRecursive(digit, kind, chain, &best, strongLinks, weakLinks)
+		findCycleRecursive(digit, kind, chain, &best, strongFrom, weakFrom, &nodes)
 
 		if len(best) > len(res) {
 			res = res[:0]
@@ -300,7 +306,24 @@ func findCycle(digit int, kind loopKind, strongLinks, weakLinks map[unitLink]boo
 	return
 }
 
-func findCycleRecursive(digit int, kind loopKind, chain []unitLink, best *[]unitLink, strongLinks, weakLinks map[unitLink]bool) {
+// linksFrom indexes links by the point they leave, holding each link in both directions.
+func linksFrom(links map[unitLink]bool) map[point][]unitLink {
+	res := make(map[point][]unitLink)
+	for l := range links {
+		reversed := unitLink{link{pair{l.right, l.left}, l.digit}, l.unit, l.strong}
+		res[l.left] = append(res[l.left], l)
+		res[l.right] = append(res[l.right], reversed)
+	}
+
+	return res
+}
+
+func findCycleRecursive(digit int, kind loopKind, chain []unitLink, best *[]unitLink, strongFrom, weakFrom map[point][]unitLink, nodes *int) {
+	if *nodes >= xCyclesBudget {
+		return
+	}
+	*nodes++
+
 	// If the right side of the last item in the chain links back to the head, we are done. TODO: keep searching for a longer chain.
 	if chainValid(true, kind, chain) && chainLoops(chain) {
 		if len(chain) > len(*best) {
@@ -330,61 +353,43 @@ func findCycleRecursive(digit int, kind loopKind, chain []unitLink, best *[]unit
 	}
 
 	if strongAllowed {
-	strongOuter:
-		for s := range strongLinks {
-			reversed := unitLink{link{pair{s.right, s.left}, s.digit}, s.unit, s.strong}
-
-			for _, c := range chain {
-				if s.pair == c.pair || reversed.pair == c.pair { // Already in the chain, skip.
-					continue strongOuter
-				}
-			}
-
-			if last.unit == s.unit {
-				continue
-			}
-
-			if last.right == s.left {
-				candidates = append(candidates, s)
-			}
-			if last.right == reversed.left {
-				candidates = append(candidates, reversed)
-			}
-		}
+		candidates = appendNextLinks(candidates, chain, strongFrom[last.right])
 	}
-
 	if weakAllowed {
-	weakOuter:
-		for w := range weakLinks {
-			reversed := unitLink{link{pair{w.right, w.left}, w.digit}, w.unit, w.strong}
-
-			for _, c := range chain {
-				if w.pair == c.pair || reversed.pair == c.pair { // Already in the chain, skip.
-					continue weakOuter
-				}
-			}
-
-			if last.unit == w.unit {
-				continue
-			}
-
-			if last.right == w.left {
-				candidates = append(candidates, w)
-			}
-			if last.right == reversed.left {
-				candidates = append(candidates, reversed)
-			}
-		}
+		candidates = appendNextLinks(candidates, chain, weakFrom[last.right])
 	}
 
 	for _, c := range candidates {
 		chain = append(chain, c)
 
 		if chainValid(false, kind, chain) {
-			findCycleRecursive(digit, kind, chain, best, strongLinks, weakLinks)
+			findCycleRecursive(digit, kind, chain, best, strongFrom, weakFrom, nodes)
 		}
 		chain = chain[:len(chain)-1]
 	}
 
 	return
 }
+
+// appendNextLinks appends the links that can extend the chain, those that are not already in it and are in a different unit from its last link, to candidates.
+func appendNextLinks(candidates, chain []unitLink, links []unitLink) []unitLink {
+	last := chain[len(chain)-1]
+
+outer:
+	for _, l := range links {
+		reversed := pair{l.right, l.left}
+		for _, c := range chain {
+			if l.pair == c.pair || reversed == c.pair { // Already in the chain, skip.
+				continue outer
+			}
+		}
+
+		if last.unit == l.unit {
+			continue
+		}
+
+		candidates = append(candidates, l)
+	}
+
+	return candidates
+}
@@ -66,6 +66,17 @@ func TestXCyclesWeakLoop(t *testing.T) {
 		5, 129, 8, 4, 7, 6, 12, 19, 3, 12, 4, 29, 138, 5, 13, 1278, 1789, 6}, g.encodeInts())
 }
 
+func TestXCyclesBudget(t *testing.T) {
+	defer func(budget int) { xCyclesBudget = budget }(xCyclesBudget)
+	xCyclesBudget = 1
+
+	g := decodeInts([]int{59, 2, 4, 1, 35, 58, 6, 7, 389, 59, 6, 38, 238, 7, 258, 4, 1, 389, 7, 18,
+		138, 9, 6, 4, 58, 2, 358, 2, 4, 6, 5, 9, 1, 3, 8, 7, 1, 3, 5, 4, 8, 7, 2, 9, 6, 8, 7, 9,
+		6, 2, 3, 1, 5, 4, 4, 18, 128, 38, 35, 9, 7, 6, 258, 3, 5, 28, 7, 1, 6, 9, 4, 28, 6, 9,
+		7, 28, 4, 258, 58, 3, 1})
+	assert.False(t, g.xCycles(0)) // The nice loop of TestXCyclesNiceLoop is not reached.
+}
+
 func BenchmarkXCycles(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		g := decodeInts([]int{59, 2, 4, 1, 35, 58, 6, 7, 389, 59, 6, 38, 238, 7, 258, 4, 1, 389, 7, 18,
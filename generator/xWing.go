@@ -26,15 +26,15 @@ package generator
 
 // xWing removes candidates. If in 2 columns, say 0 and 7, all instances of a particular digit, say 4, appear in the same two rows, say 4 and 6, then 1 of the 4's must be in (0, 4) or (0, 6) and the other in (7, 4) or (7, 6). Therefore all of the other 4's in those two rows can be removed. The same logic applies if rows and columns are swapped. It returns true if it changes any cells.
 func (g *Grid) xWing(verbose uint) bool {
-	return g.xWingGroup(&col, &row, verbose) || g.xWingGroup(&row, &col, verbose)
+	return g.eachLine(func(major, minor *group) bool { return g.xWingGroup(major, minor, verbose) })
 }
 
 func (g *Grid) xWingGroup(majorGroup, minorGroup *group, verbose uint) (res bool) {
-	var digits [9][10]cell
+	var digits [maxSize][maxSize + 1]cell
 	for ui, u := range majorGroup.unit {
 		for pi, p := range u {
 			cell := *g.pt(p)
-			for d := 1; d <= 9; d++ {
+			for d := 1; d <= g.size(); d++ {
 				if cell&(1<<d) != 0 {
 					digits[ui][d] |= 1 << pi
 				}
@@ -42,16 +42,16 @@ func (g *Grid) xWingGroup(majorGroup, minorGroup *group, verbose uint) (res bool
 		}
 	}
 
-	for d := 1; d <= 9; d++ {
-		for c1i := 0; c1i < 9; c1i++ {
-			for c2i := 0; c2i < 9; c2i++ {
+	for d := 1; d <= g.size(); d++ {
+		for c1i := 0; c1i < len(majorGroup.unit); c1i++ {
+			for c2i := 0; c2i < len(majorGroup.unit); c2i++ {
 				if c1i == c2i {
 					continue
 				}
 
 				proto := digits[c1i][d]
 				if bitCount[proto] == 2 && proto == digits[c2i][d] {
-					for minor := 1; minor <= 9; minor++ {
+					for minor := 1; minor < len(minorGroup.unit); minor++ {
 						if proto&(1<<minor) != 0 {
 							for mi, m := range minorGroup.unit[minor] {
 								if mi == c1i || mi == c2i {
@@ -26,20 +26,20 @@ package generator
 
 // xyChains removes candidates by following a chain of bivalued cells. If a candidate is shared by both ends of the chain, that candidate can be removed from any cells that can see both ends ofr the chain.
 func (g *Grid) xyChains(verbose uint) (res bool) {
-	var links [10]map[link]bool
-	g.findBivalueLinks(&box, &links)
-	g.findBivalueLinks(&col, &links)
-	g.findBivalueLinks(&row, &links)
+	var links [maxSize + 1]map[link]bool
+	for _, gr := range g.Layout().groups {
+		g.findBivalueLinks(gr, &links)
+	}
 
 	linkEnds := make(map[point][]link)
-	for d := 1; d <= 9; d++ {
+	for d := 1; d <= g.size(); d++ {
 		for l := range links[d] {
 			linkEnds[l.left] = append(linkEnds[l.left], l)
 			linkEnds[l.right] = append(linkEnds[l.right], l)
 		}
 	}
 
-	for d := 1; d <= 9; d++ {
+	for d := 1; d <= g.size(); d++ {
 		for l := range links[d] {
 			if l.left.r != 0 || l.left.c != 0 {
 				continue
@@ -54,42 +54,33 @@ func (g *Grid) xyChains(verbose uint) (res bool) {
 	return
 }
 
-func (g *Grid) followChain(res *bool, verbose uint, chain []link, links [10]map[link]bool, linkEnds map[point][]link) {
+func (g *Grid) followChain(res *bool, verbose uint, chain []link, links [maxSize + 1]map[link]bool, linkEnds map[point][]link) {
 	firstLink := chain[0]
 	lastLink := chain[len(chain)-1]
 
-	if boxOfPoint(firstLink.left) != boxOfPoint(lastLink.right) &&
-		firstLink.left.c != lastLink.right.c &&
-		firstLink.left.r != lastLink.left.r {
-		front := *g.pt(firstLink.left) &^ (1 << firstLink.digit)
-		back := *g.pt(lastLink.right) &^ (1 << lastLink.digit)
-		if front == back {
-
-			// Find the overlap between the left of front and the right of back.
-			firstInfluence := influence[firstLink.left.r][firstLink.left.c]
-			lastInfluence := influence[lastLink.right.r][lastLink.right.c]
-			overlap := uint128{
-				firstInfluence.ms & lastInfluence.ms,
-				firstInfluence.ls & lastInfluence.ls,
-			}
+	front := *g.pt(firstLink.left) &^ (1 << firstLink.digit)
+	back := *g.pt(lastLink.right) &^ (1 << lastLink.digit)
+	if front == back {
+		// Find the overlap between the left of front and the right of back, leaving out all points in the chain.
+		inChain := make(map[point]bool)
+		for _, l := range chain {
+			inChain[l.left], inChain[l.right] = true, true
+		}
 
-			// Remove all points in the chain from the overlap.
-			for _, l := range chain {
-				overlap.unset(l.left).unset(l.right)
+		for _, p := range g.peers(firstLink.left) {
+			if inChain[p] || !g.sees(lastLink.right, p) {
+				continue
 			}
 
-			overlap.process(func(r, c uint8) {
-				p := point{r, c}
-				if g.pt(p).andNot(front) {
-					g.cellChange(res, verbose, "xyChains: remove %s from (%d, %d) because it is seen by %s and %s (chain: %v)\n", front, r, c, firstLink.left, lastLink.right, chain)
+			if g.pt(p).andNot(front) {
+				g.cellChange(res, verbose, "xyChains: remove %s from (%d, %d) because it is seen by %s and %s (chain: %v)\n", front, p.r, p.c, firstLink.left, lastLink.right, chain)
 
-					// Once a candidate digit is removed, that point can no longer be a part of any chain since it will not be bivalued.
-					for _, l := range linkEnds[p] {
-						delete(links[l.digit], l)
-					}
-					delete(linkEnds, p)
+				// Once a candidate digit is removed, that point can no longer be a part of any chain since it will not be bivalued.
+				for _, l := range linkEnds[p] {
+					delete(links[l.digit], l)
 				}
-			})
+				delete(linkEnds, p)
+			}
 		}
 	}
 
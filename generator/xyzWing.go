@@ -26,7 +26,7 @@ package generator
 
 // xyzWing removes candidates in a manor similar to yWing. The pivot point contains XYZ. If it can see two other cells XZ and YZ, then any cells that can be seen by all three can be cleared of Z.
 func (g *Grid) xyzWing(verbose uint) (res bool) {
-	for _, u := range box.unit {
+	for _, u := range g.Layout().groups[0].unit {
 		for _, p := range u { // Traverse all cells, using box units for convenience.
 			cell := *g.pt(p)
 
@@ -34,7 +34,7 @@ func (g *Grid) xyzWing(verbose uint) (res bool) {
 				continue
 			}
 
-			n := neighbors(p)
+			n := g.neighbors(p)
 
 			candidates := g.findYWingCandidates(p, 2)
 			if len(candidates) < 2 {
@@ -43,7 +43,7 @@ func (g *Grid) xyzWing(verbose uint) (res bool) {
 
 			for c1i, p1 := range candidates {
 				cell1 := *g.pt(p1)
-				n1 := neighbors(p1)
+				n1 := g.neighbors(p1)
 
 				for c2i, p2 := range candidates {
 					if c1i == c2i {
@@ -56,19 +56,19 @@ func (g *Grid) xyzWing(verbose uint) (res bool) {
 						continue
 					}
 
-					n2 := neighbors(p2)
+					n2 := g.neighbors(p2)
 
-					var overlap [9][9]bool
-					for r := 0; r < rows; r++ {
-						for c := 0; c < cols; c++ {
+					var overlap [maxRows][maxCols]bool
+					for r := zero; r < g.height(); r++ {
+						for c := zero; c < g.width(); c++ {
 							overlap[r][c] = n[r][c] && n1[r][c] && n2[r][c]
 						}
 					}
 
 					overlap[p.r][p.c] = false
 
-					for r := 0; r < rows; r++ {
-						for c := 0; c < cols; c++ {
+					for r := zero; r < g.height(); r++ {
+						for c := zero; c < g.width(); c++ {
 							if overlap[r][c] {
 								bits := cell1 & cell2 & cell
 								if (&g.cells[r][c]).andNot(bits) {
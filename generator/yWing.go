@@ -26,7 +26,7 @@ package generator
 
 // yWing removes candidates. If a cell has two candidates (AB) and in a neighboring unit (box, row, or column) of AB is another cell containing AC and in a second neighboring unit of AB is a cell containing BC, then any cell that can be "seen" by AC and BC (in both neighborhoods of AC and BC) that contain C can have C removed. It returns true if it changes any cells.
 func (g *Grid) yWing(verbose uint) (res bool) {
-	for _, u := range box.unit {
+	for _, u := range g.Layout().groups[0].unit {
 		for _, p := range u { // Traverse all cells, using box units for convenience.
 			cell := *g.pt(p)
 
@@ -38,7 +38,7 @@ func (g *Grid) yWing(verbose uint) (res bool) {
 
 			for c1i, p1 := range candidates {
 				cell1 := *g.pt(p1)
-				n1 := neighbors(p1)
+				n1 := g.neighbors(p1)
 
 				for c2i, p2 := range candidates {
 					if c1i == c2i {
@@ -51,19 +51,19 @@ func (g *Grid) yWing(verbose uint) (res bool) {
 						continue
 					}
 
-					n2 := neighbors(p2)
+					n2 := g.neighbors(p2)
 
-					var overlap [9][9]bool
-					for r := 0; r < rows; r++ {
-						for c := 0; c < cols; c++ {
+					var overlap [maxRows][maxCols]bool
+					for r := zero; r < g.height(); r++ {
+						for c := zero; c < g.width(); c++ {
 							overlap[r][c] = n1[r][c] && n2[r][c]
 						}
 					}
 
 					overlap[p.r][p.c] = false
 
-					for r := 0; r < rows; r++ {
-						for c := 0; c < cols; c++ {
+					for r := zero; r < g.height(); r++ {
+						for c := zero; c < g.width(); c++ {
 							if overlap[r][c] {
 								bits := (cell1 | cell2) &^ cell
 								if (&g.cells[r][c]).andNot(bits) {
@@ -81,38 +81,15 @@ func (g *Grid) yWing(verbose uint) (res bool) {
 }
 
 func (g *Grid) findYWingCandidates(curr point, overlap int) (res []point) {
-	m := make(map[point]bool)
-	for p := range g.findYWingCandidatesUnit(&box.unit[boxOfPoint(curr)], curr, overlap) {
-		m[p] = true
-	}
-	for p := range g.findYWingCandidatesUnit(&col.unit[curr.c], curr, overlap) {
-		m[p] = true
-	}
-	for p := range g.findYWingCandidatesUnit(&row.unit[curr.r], curr, overlap) {
-		m[p] = true
-	}
-
-	for p := range m {
-		res = append(res, p)
-	}
-	return
-}
-
-func (g *Grid) findYWingCandidatesUnit(u *[9]point, curr point, overlap int) map[point]bool {
-	res := make(map[point]bool)
 	cell := *g.pt(curr)
-	for _, p := range u {
-		if p == curr {
-			continue
-		}
-
+	for _, p := range g.peers(curr) {
 		candidate := *g.pt(p)
 		if bitCount[candidate] != 2 || bitCount[cell&candidate] != overlap {
 			continue
 		}
 
-		res[p] = true
+		res = append(res, p)
 	}
 
-	return res
+	return
 }
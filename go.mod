@@ -1,5 +1,6 @@
 module dogdaze.org/sudoku
 
+// serve -grpc needs Go 1.24 or later (see cmd/sudoku/grpc.go); the rest builds with Go 1.14.
 go 1.14
 
 require (